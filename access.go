@@ -0,0 +1,87 @@
+package fixhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// A PermissionError is returned when GitHub refuses a request because the
+// access token lacks a permission. This is how fine-grained personal access
+// tokens and GitHub Apps fail when they aren't granted a repository,
+// or are granted too little of it. Client methods wrap it; use errors.As.
+type PermissionError struct {
+	// Permissions are those GitHub says would do for the request,
+	// such as "contents=write", if it says.
+	Permissions string
+	Message     string // GitHub's
+}
+
+func (e *PermissionError) Error() string {
+	s := "the access token lacks permission: " + e.Message
+	if e.Permissions != "" {
+		s += fmt.Sprintf(" (it needs %s on the repository)", e.Permissions)
+	}
+	return s
+}
+
+// An SSOError is returned when GitHub refuses a request because the repository
+// belongs to an organization that uses SAML single sign-on, and the access
+// token hasn't been authorized for it. Client methods wrap it; use errors.As.
+type SSOError struct {
+	// URL is where the token's owner can authorize it for the organization,
+	// if GitHub says.
+	URL string
+}
+
+func (e *SSOError) Error() string {
+	s := "the organization requires SAML single sign-on, and the access token isn't authorized for it"
+	if e.URL != "" {
+		s += "; authorize it at " + e.URL
+	}
+	return s
+}
+
+// accessTransport is an http.RoundTripper that turns GitHub's refusals
+// for want of permission into PermissionErrors and SSOErrors.
+type accessTransport struct {
+	base http.RoundTripper
+}
+
+func (t accessTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+	if _, limited := rateLimitReset(resp); limited {
+		return resp, nil
+	}
+	// The header is "required; url=https://github.com/orgs/...".
+	if sso := resp.Header.Get("X-GitHub-SSO"); strings.HasPrefix(sso, "required") {
+		resp.Body.Close()
+		e := &SSOError{}
+		for _, part := range strings.Split(sso, ";") {
+			if u, ok := strings.CutPrefix(strings.TrimSpace(part), "url="); ok {
+				e.URL = u
+			}
+		}
+		return nil, e
+	}
+	perms := resp.Header.Get("X-Accepted-GitHub-Permissions")
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(b, &body)
+	if perms == "" && !strings.HasPrefix(body.Message, "Resource not accessible by") {
+		resp.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+		return resp, nil
+	}
+	return nil, &PermissionError{Permissions: perms, Message: body.Message}
+}
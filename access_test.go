@@ -0,0 +1,59 @@
+package fixhub
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/faker/granted":
+			w.Write([]byte(`{"default_branch": "main"}`))
+		case "/repos/faker/sso":
+			w.Header().Set("X-GitHub-SSO", "required; url=https://github.com/orgs/faker/sso?authorization_request=x")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Resource protected by organization SAML enforcement."}`))
+		case "/repos/faker/ungranted":
+			w.Header().Set("X-Accepted-GitHub-Permissions", "metadata=read")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Resource not accessible by personal access token"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Repository access blocked"}`))
+		}
+	}))
+	defer srv.Close()
+
+	for _, repo := range []string{"granted", "ungranted", "sso", "blocked"} {
+		c := NewClientWithHTTPClient("faker", repo, nil)
+		if err := c.SetBaseURL(srv.URL + "/"); err != nil {
+			t.Fatal(err)
+		}
+		_, err := c.DefaultBranch()
+		var pe *PermissionError
+		isPerm := errors.As(err, &pe)
+		switch repo {
+		case "granted":
+			if err != nil {
+				t.Errorf("%s: %v", repo, err)
+			}
+		case "ungranted":
+			if !isPerm || pe.Permissions != "metadata=read" || !strings.Contains(err.Error(), "needs metadata=read") {
+				t.Errorf("%s: err = %v, want a PermissionError needing metadata=read", repo, err)
+			}
+		case "sso":
+			var se *SSOError
+			if !errors.As(err, &se) || se.URL != "https://github.com/orgs/faker/sso?authorization_request=x" {
+				t.Errorf("%s: err = %v, want an SSOError with the authorization URL", repo, err)
+			}
+		case "blocked":
+			if err == nil || isPerm {
+				t.Errorf("%s: err = %v, want GitHub's error, not a PermissionError", repo, err)
+			}
+		}
+	}
+}
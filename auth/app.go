@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// appSource is a Source of installation tokens of a GitHub App.
+// Each token lasts an hour; a new one is made shortly before it expires.
+type appSource struct {
+	id, installation int64
+	key              *rsa.PrivateKey
+	apiURL           string
+	hc               *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newAppSource(c Credentials) (*appSource, error) {
+	if c.AppID == 0 || c.AppInstallation == 0 || c.AppKeyFile == "" {
+		return nil, errors.New("a GitHub App needs an ID, an installation ID and a private key file")
+	}
+	b, err := readPrivateFile(c.AppKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("reading GitHub App key from %s: %v", c.AppKeyFile, err)
+	}
+	s := &appSource{
+		id:           c.AppID,
+		installation: c.AppInstallation,
+		key:          key,
+		apiURL:       c.APIURL,
+		hc:           c.HTTPClient,
+	}
+	if s.apiURL == "" {
+		s.apiURL = "https://api.github.com/"
+	}
+	if !strings.HasSuffix(s.apiURL, "/") {
+		s.apiURL += "/"
+	}
+	if s.hc == nil {
+		s.hc = http.DefaultClient
+	}
+	return s, nil
+}
+
+// parseKey parses a PEM-encoded RSA private key, as GitHub issues for Apps.
+func parseKey(b []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return key, nil
+}
+
+func (s *appSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Until(s.expires) > 5*time.Minute {
+		return s.token, nil
+	}
+	jwt, err := s.jwt(time.Now())
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%sapp/installations/%d/access_tokens", s.apiURL, s.installation), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := s.hc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("making GitHub App installation token: %v", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("making GitHub App installation token: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("making GitHub App installation token: %s: %s", resp.Status, abbrev(b))
+	}
+	var it struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(b, &it); err != nil {
+		return "", fmt.Errorf("parsing GitHub App installation token: %v", err)
+	}
+	s.token, s.expires = it.Token, it.ExpiresAt
+	return s.token, nil
+}
+
+// jwt returns a JSON Web Token, signed with the App's key, that authenticates
+// as the App for long enough to make an installation token.
+func (s *appSource) jwt(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(struct {
+		IssuedAt  int64 `json:"iat"`
+		ExpiresAt int64 `json:"exp"`
+		Issuer    int64 `json:"iss"`
+	}{
+		IssuedAt:  now.Add(-time.Minute).Unix(), // allowing for clock skew
+		ExpiresAt: now.Add(9 * time.Minute).Unix(),
+		Issuer:    s.id,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// abbrev returns b for an error message, shortened if it is long.
+func abbrev(b []byte) string {
+	s := strings.TrimSpace(string(b))
+	if len(s) > 200 {
+		s = s[:200] + "..."
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+// Package auth finds the GitHub credentials that fixhub's programs use.
+//
+// A token may be given directly, in $GITHUB_TOKEN, in the operating system's
+// credential store, or in a file that no one else may read,
+// or be an installation token of a GitHub App.
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// A Source provides GitHub access tokens.
+type Source interface {
+	// Token returns the current token, or "" if requests are to be unauthenticated.
+	Token() (string, error)
+}
+
+// Static is a Source of a token that never changes.
+type Static string
+
+func (s Static) Token() (string, error) { return string(s), nil }
+
+// Credentials say where to find the token to use. The first of these that
+// is set is used: Token, the GitHub App, $GITHUB_TOKEN, the credential store
+// if Keyring is set, then File.
+type Credentials struct {
+	Token   string // such as from a -token flag
+	Keyring bool   // whether to look in the credential store; see KeyringGet
+	File    string // a file containing a token; it is fine for it not to exist
+
+	// AppID, AppInstallation and AppKeyFile identify a GitHub App
+	// installation to act as. AppKeyFile holds the App's private key in PEM.
+	AppID           int64
+	AppInstallation int64
+	AppKeyFile      string
+
+	// APIURL is the base URL of the GitHub API; the default is https://api.github.com/.
+	APIURL string
+	// HTTPClient makes the requests for GitHub App tokens.
+	// If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// Source returns the source of tokens that c describes.
+// If c describes none, it returns Static("").
+func (c Credentials) Source() (Source, error) {
+	if c.Token != "" {
+		return Static(c.Token), nil
+	}
+	if c.AppID != 0 || c.AppInstallation != 0 || c.AppKeyFile != "" {
+		return newAppSource(c)
+	}
+	if t := os.Getenv("GITHUB_TOKEN"); t != "" {
+		return Static(t), nil
+	}
+	if c.Keyring {
+		// A credential store that can't be used, such as without a desktop
+		// session, is no different from one without a token.
+		if t, err := KeyringGet(); err == nil {
+			return Static(t), nil
+		}
+	}
+	if c.File == "" {
+		return Static(""), nil
+	}
+	t, err := ReadTokenFile(c.File)
+	if err != nil {
+		return nil, err
+	}
+	return Static(t), nil
+}
+
+// ReadTokenFile returns the token in the named file, or "" if it doesn't exist.
+// As a security check, the file must not be accessible to anyone but its owner.
+func ReadTokenFile(name string) (string, error) {
+	b, err := readPrivateFile(name)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(b)), err
+}
+
+// readPrivateFile reads the named file, if no group or world permission bits are set on it.
+func readPrivateFile(name string) ([]byte, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&0077 != 0 {
+		return nil, fmt.Errorf("%s is too accessible; run `chmod go= %s` to fix", name, name)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// Transport is an http.RoundTripper that authenticates requests with tokens from Source.
+type Transport struct {
+	Source Source
+	Base   http.RoundTripper // if nil, http.DefaultTransport is used
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	tok, err := t.Source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == "" {
+		return base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	tests := []struct {
+		c    Credentials
+		env  string
+		want string
+	}{
+		{Credentials{Token: "flag", File: file}, "env", "flag"},
+		{Credentials{File: file}, "env", "env"},
+		{Credentials{File: file}, "", "from-file"},
+		{Credentials{File: filepath.Join(dir, "missing")}, "", ""},
+		{Credentials{}, "", ""},
+	}
+	for _, test := range tests {
+		os.Setenv("GITHUB_TOKEN", test.env)
+		src, err := test.c.Source()
+		if err != nil {
+			t.Errorf("%+v.Source(): %v", test.c, err)
+			continue
+		}
+		if got, _ := src.Token(); got != test.want {
+			t.Errorf("%+v with $GITHUB_TOKEN=%q: token %q, want %q", test.c, test.env, got, test.want)
+		}
+	}
+
+	os.Setenv("GITHUB_TOKEN", "")
+	if err := os.Chmod(file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Credentials{File: file}).Source(); err == nil || !strings.Contains(err.Error(), "too accessible") {
+		t.Errorf("Source() with a world-readable file: err = %v, want it to be too accessible", err)
+	}
+}
+
+func TestAppSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := ioutil.WriteFile(keyFile, pemKey, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method != "POST" || r.URL.Path != "/app/installations/7/access_tokens" {
+			t.Errorf("request to %s %s", r.Method, r.URL.Path)
+		}
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		i := strings.LastIndex(jwt, ".")
+		sig, err := base64.RawURLEncoding.DecodeString(jwt[i+1:])
+		if err != nil {
+			t.Errorf("bad JWT signature: %v", err)
+		}
+		sum := sha256.Sum256([]byte(jwt[:i]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+			t.Errorf("JWT signature doesn't verify: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_installation", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	src, err := Credentials{AppID: 42, AppInstallation: 7, AppKeyFile: keyFile, APIURL: srv.URL}.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		tok, err := src.Token()
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		if tok != "ghs_installation" {
+			t.Errorf("Token() = %q, want ghs_installation", tok)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("made %d installation tokens, want 1 to be reused", calls)
+	}
+}
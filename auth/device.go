@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A DeviceFlow gets a token by GitHub's device authorization flow:
+// the user visits a page on GitHub, perhaps on another device, and enters
+// a code there to let the OAuth App with ClientID act for them.
+// The App must have the device flow enabled.
+type DeviceFlow struct {
+	ClientID string
+	Scopes   []string // such as "public_repo"
+
+	// Prompt is called with the page to visit and the code to enter there.
+	Prompt func(verificationURI, userCode string)
+
+	// BaseURL is GitHub's; the default is https://github.com/.
+	BaseURL string
+	// HTTPClient makes the requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// Login carries out the flow, returning the token once the user has allowed it.
+func (d DeviceFlow) Login() (string, error) {
+	base := d.BaseURL
+	if base == "" {
+		base = "https://github.com/"
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	var code struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+	}
+	err := d.post(base+"login/device/code", url.Values{
+		"client_id": {d.ClientID},
+		"scope":     {strings.Join(d.Scopes, " ")},
+	}, &code)
+	if err != nil {
+		return "", fmt.Errorf("starting device login: %v", err)
+	}
+	d.Prompt(code.VerificationURI, code.UserCode)
+
+	interval := time.Duration(code.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		var tok struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+			Interval    int    `json:"interval"`
+		}
+		err := d.post(base+"login/oauth/access_token", url.Values{
+			"client_id":   {d.ClientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &tok)
+		if err != nil {
+			return "", fmt.Errorf("waiting for device login: %v", err)
+		}
+		switch tok.Error {
+		case "":
+			return tok.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval = time.Duration(tok.Interval) * time.Second
+		default:
+			if tok.Description != "" {
+				return "", errors.New(tok.Description)
+			}
+			return "", errors.New(tok.Error)
+		}
+	}
+	return "", errors.New("the code expired before it was entered")
+}
+
+// post posts form to u and decodes the JSON response into v.
+func (d DeviceFlow) post(u string, form url.Values, v interface{}) error {
+	req, err := http.NewRequest("POST", u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	hc := d.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, abbrev(b))
+	}
+	return json.Unmarshal(b, v)
+}
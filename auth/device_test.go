@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeviceFlow(t *testing.T) {
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "Iv1.abc" {
+			t.Errorf("%s: client_id = %q", r.URL.Path, r.FormValue("client_id"))
+		}
+		switch r.URL.Path {
+		case "/login/device/code":
+			if got := r.FormValue("scope"); got != "public_repo" {
+				t.Errorf("scope = %q, want public_repo", got)
+			}
+			w.Write([]byte(`{"device_code": "dc", "user_code": "ABCD-1234", "verification_uri": "https://github.com/login/device", "expires_in": 900, "interval": 0}`))
+		case "/login/oauth/access_token":
+			if r.FormValue("device_code") != "dc" {
+				t.Errorf("device_code = %q, want dc", r.FormValue("device_code"))
+			}
+			polls++
+			switch polls {
+			case 1:
+				w.Write([]byte(`{"error": "authorization_pending"}`))
+			case 2:
+				w.Write([]byte(`{"error": "slow_down", "interval": 0}`))
+			default:
+				w.Write([]byte(`{"access_token": "gho_token", "token_type": "bearer"}`))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var prompted string
+	tok, err := DeviceFlow{
+		ClientID: "Iv1.abc",
+		Scopes:   []string{"public_repo"},
+		Prompt:   func(uri, code string) { prompted = code },
+		BaseURL:  srv.URL,
+	}.Login()
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if tok != "gho_token" || prompted != "ABCD-1234" || polls != 3 {
+		t.Errorf("Login() = %q after prompting with %q and %d polls; want gho_token, ABCD-1234 and 3", tok, prompted, polls)
+	}
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Kind is a kind of GitHub token, known by its prefix.
+type Kind string
+
+const (
+	Classic         Kind = "classic personal access token"
+	FineGrained     Kind = "fine-grained personal access token"
+	OAuth           Kind = "OAuth token"
+	AppInstallation Kind = "GitHub App installation token"
+	AppUser         Kind = "GitHub App user token"
+	Unknown         Kind = "token"
+)
+
+// KindOf returns the kind of token.
+func KindOf(token string) Kind {
+	switch {
+	case strings.HasPrefix(token, "ghp_"):
+		return Classic
+	case strings.HasPrefix(token, "github_pat_"):
+		return FineGrained
+	case strings.HasPrefix(token, "gho_"):
+		return OAuth
+	case strings.HasPrefix(token, "ghs_"):
+		return AppInstallation
+	case strings.HasPrefix(token, "ghu_"):
+		return AppUser
+	}
+	return Unknown
+}
+
+// TokenInfo describes a token.
+type TokenInfo struct {
+	Kind Kind
+	// Scopes are what a classic or OAuth token may do.
+	// Other kinds have permissions for each repository instead, which GitHub doesn't list.
+	Scopes  []string
+	Expires time.Time // zero if it doesn't
+}
+
+// Inspect asks GitHub about token, using its rate limit endpoint,
+// which doesn't count against the rate limit.
+// apiURL is the base URL of the GitHub API; the default is https://api.github.com/.
+func Inspect(hc *http.Client, apiURL, token string) (TokenInfo, error) {
+	if apiURL == "" {
+		apiURL = "https://api.github.com/"
+	}
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	req, err := http.NewRequest("GET", strings.TrimSuffix(apiURL, "/")+"/rate_limit", nil)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := hc.Do(req)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("inspecting token: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return TokenInfo{}, fmt.Errorf("GitHub rejected the %s", KindOf(token))
+	}
+
+	ti := TokenInfo{Kind: KindOf(token)}
+	if h, ok := resp.Header["X-Oauth-Scopes"]; ok && len(h) > 0 {
+		ti.Scopes = []string{} // known to have none, as distinct from not knowing
+		for _, s := range strings.Split(h[0], ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				ti.Scopes = append(ti.Scopes, s)
+			}
+		}
+		sort.Strings(ti.Scopes)
+	}
+	if exp := resp.Header.Get("Github-Authentication-Token-Expiration"); exp != "" {
+		// Such as "2026-01-02 15:04:05 UTC".
+		if t, err := time.Parse("2006-01-02 15:04:05 MST", exp); err == nil {
+			ti.Expires = t
+		}
+	}
+	return ti, nil
+}
+
+// neededScopes are the scopes of classic tokens that fixhub's programs may use:
+// to read and change repositories, public or private, and to set commit statuses.
+var neededScopes = map[string]bool{
+	"repo":        true,
+	"public_repo": true,
+	"repo:status": true,
+	"read:org":    true,
+	"read:user":   true,
+	"user:email":  true,
+}
+
+// ExcessScopes returns the token's scopes that fixhub never needs,
+// such as to delete repositories or administer organizations.
+// A token with such scopes does more harm if it leaks; a fine-grained
+// personal access token, granted only the repositories it is needed for,
+// does the least.
+func (ti TokenInfo) ExcessScopes() []string {
+	var excess []string
+	for _, s := range ti.Scopes {
+		if !neededScopes[s] {
+			excess = append(excess, s)
+		}
+	}
+	return excess
+}
+
+// Advice returns a warning about the token, or "" if there is nothing to say.
+func (ti TokenInfo) Advice() string {
+	var msgs []string
+	if excess := ti.ExcessScopes(); len(excess) > 0 {
+		msgs = append(msgs, fmt.Sprintf("The %s has scopes that fixhub doesn't need: %s. "+
+			"Consider a fine-grained personal access token granted only the repositories to check, "+
+			"with read access to contents and metadata, and write access to contents and pull requests to fix them.",
+			ti.Kind, strings.Join(excess, ", ")))
+	}
+	if !ti.Expires.IsZero() && time.Until(ti.Expires) < 7*24*time.Hour {
+		msgs = append(msgs, fmt.Sprintf("The %s expires on %s.", ti.Kind, ti.Expires.Format("2006-01-02")))
+	}
+	return strings.Join(msgs, "\n")
+}
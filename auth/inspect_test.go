@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer ghp_broad":
+			w.Header().Set("X-OAuth-Scopes", "repo, delete_repo, admin:org")
+		case "Bearer ghp_narrow":
+			w.Header().Set("X-OAuth-Scopes", "public_repo")
+			w.Header().Set("GitHub-Authentication-Token-Expiration", "2000-01-02 03:04:05 UTC")
+		case "Bearer github_pat_x":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	ti, err := Inspect(nil, srv.URL, "ghp_broad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti.Kind != Classic || !reflect.DeepEqual(ti.ExcessScopes(), []string{"admin:org", "delete_repo"}) {
+		t.Errorf("broad token: kind %q, excess scopes %q", ti.Kind, ti.ExcessScopes())
+	}
+	if !strings.Contains(ti.Advice(), "fine-grained") {
+		t.Errorf("broad token: advice %q doesn't suggest a fine-grained token", ti.Advice())
+	}
+
+	ti, err = Inspect(nil, srv.URL, "ghp_narrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ti.ExcessScopes()) != 0 || ti.Expires.Year() != 2000 || !strings.Contains(ti.Advice(), "expires on 2000-01-02") {
+		t.Errorf("narrow token: %+v, advice %q", ti, ti.Advice())
+	}
+
+	ti, err = Inspect(nil, srv.URL, "github_pat_x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti.Kind != FineGrained || ti.Scopes != nil || ti.Advice() != "" {
+		t.Errorf("fine-grained token: %+v, advice %q", ti, ti.Advice())
+	}
+
+	if _, err := Inspect(nil, srv.URL, "bogus"); err == nil {
+		t.Errorf("Inspect with a rejected token succeeded")
+	}
+}
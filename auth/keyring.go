@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The token kept in the operating system's credential store, by KeyringSet,
+// is filed under this service and account.
+const (
+	keyringService = "fixhub"
+	keyringAccount = "github.com"
+)
+
+var (
+	// ErrNoKeyring is returned when there is no credential store to use.
+	ErrNoKeyring = errors.New("no credential store is available")
+	// ErrNotInKeyring is returned when the credential store has no token.
+	ErrNotInKeyring = errors.New("no token in the credential store")
+)
+
+// KeyringGet returns the token in the operating system's credential store:
+// the Keychain on macOS, the Credential Manager on Windows,
+// and the Secret Service (such as GNOME Keyring) elsewhere, through secret-tool.
+func KeyringGet() (string, error) { return keyringGet() }
+
+// KeyringSet stores token in the operating system's credential store.
+func KeyringSet(token string) error {
+	if token == "" {
+		return errors.New("no token to store")
+	}
+	return keyringSet(token)
+}
+
+// KeyringDelete removes the token from the operating system's credential store.
+// It is not an error if there is none.
+func KeyringDelete() error {
+	if err := keyringDelete(); err != ErrNotInKeyring {
+		return err
+	}
+	return nil
+}
+
+// run runs a credential store's command line tool, giving it stdin,
+// and returns its output. Its complaints are included in the error.
+func run(stdin string, name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", ErrNoKeyring
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	out, err := cmd.Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && len(strings.TrimSpace(string(ee.Stderr))) > 0 {
+			return "", fmt.Errorf("%s: %s (%w)", name, strings.TrimSpace(string(ee.Stderr)), err)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// silentFailure reports whether err is from a tool that failed without complaint.
+func silentFailure(err error) bool {
+	var ee *exec.ExitError
+	return errors.As(err, &ee) && len(strings.TrimSpace(string(ee.Stderr))) == 0
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// On macOS, tokens are kept in the login Keychain, using security(1).
+
+func keyringGet() (string, error) {
+	t, err := run("", "security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	if notFound(err) {
+		return "", ErrNotInKeyring
+	}
+	return t, err
+}
+
+func keyringSet(token string) error {
+	// -U updates an existing item. The token is an argument, as security
+	// can't read it from stdin, but only briefly.
+	_, err := run("", "security", "add-generic-password", "-U", "-s", keyringService, "-a", keyringAccount, "-w", token)
+	return err
+}
+
+func keyringDelete() error {
+	_, err := run("", "security", "delete-generic-password", "-s", keyringService, "-a", keyringAccount)
+	if notFound(err) {
+		return ErrNotInKeyring
+	}
+	return err
+}
+
+// notFound reports whether err is security's exit status for a missing item.
+func notFound(err error) bool {
+	var ee *exec.ExitError
+	return errors.As(err, &ee) && ee.ExitCode() == 44
+}
@@ -0,0 +1,31 @@
+//go:build !darwin && !windows
+
+package auth
+
+// Elsewhere, tokens are kept by the Secret Service, such as GNOME Keyring
+// or KWallet, using libsecret's secret-tool.
+
+var secretAttrs = []string{"service", keyringService, "account", keyringAccount}
+
+func keyringGet() (string, error) {
+	t, err := run("", "secret-tool", append([]string{"lookup"}, secretAttrs...)...)
+	// secret-tool fails silently when there is no such secret.
+	if silentFailure(err) || err == nil && t == "" {
+		return "", ErrNotInKeyring
+	}
+	return t, err
+}
+
+func keyringSet(token string) error {
+	args := append([]string{"store", "--label=fixhub GitHub token"}, secretAttrs...)
+	_, err := run(token, "secret-tool", args...)
+	return err
+}
+
+func keyringDelete() error {
+	_, err := run("", "secret-tool", append([]string{"clear"}, secretAttrs...)...)
+	if silentFailure(err) {
+		return ErrNotInKeyring
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+//go:build !darwin && !windows
+
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A fake secret-tool that keeps one secret in a file.
+const fakeSecretTool = `#!/bin/sh
+f="$(dirname "$0")/secret"
+case "$1" in
+lookup) [ -f "$f" ] && cat "$f" || exit 1 ;;
+store) cat > "$f" ;;
+clear) [ -f "$f" ] && rm "$f" || exit 1 ;;
+esac
+`
+
+func TestKeyring(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "secret-tool"), []byte(fakeSecretTool), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GITHUB_TOKEN", "")
+
+	if _, err := KeyringGet(); err != ErrNotInKeyring {
+		t.Errorf("KeyringGet() before storing: err = %v, want ErrNotInKeyring", err)
+	}
+	if err := KeyringSet("stored"); err != nil {
+		t.Fatalf("KeyringSet: %v", err)
+	}
+	src, err := Credentials{Keyring: true, File: filepath.Join(dir, "missing")}.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, _ := src.Token(); tok != "stored" {
+		t.Errorf("token = %q, want the one stored", tok)
+	}
+	if err := KeyringDelete(); err != nil {
+		t.Errorf("KeyringDelete: %v", err)
+	}
+	if err := KeyringDelete(); err != nil {
+		t.Errorf("KeyringDelete with nothing stored: %v", err)
+	}
+
+	os.Setenv("PATH", "")
+	if _, err := KeyringGet(); err != ErrNoKeyring {
+		t.Errorf("KeyringGet() without secret-tool: err = %v, want ErrNoKeyring", err)
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// On Windows, tokens are kept by the Credential Manager, as generic credentials.
+
+var (
+	advapi32      = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead  = advapi32.NewProc("CredReadW")
+	procCredWrite = advapi32.NewProc("CredWriteW")
+	procCredDel   = advapi32.NewProc("CredDeleteW")
+	procCredFree  = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential is a CREDENTIALW.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func credTarget() *uint16 {
+	p, _ := syscall.UTF16PtrFromString(keyringService + ":" + keyringAccount)
+	return p
+}
+
+func keyringGet() (string, error) {
+	if err := procCredRead.Find(); err != nil {
+		return "", ErrNoKeyring
+	}
+	var c *credential
+	r, _, err := procCredRead.Call(uintptr(unsafe.Pointer(credTarget())), credTypeGeneric, 0, uintptr(unsafe.Pointer(&c)))
+	if r == 0 {
+		if err == errorNotFound {
+			return "", ErrNotInKeyring
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(c)))
+	return string(unsafe.Slice(c.CredentialBlob, c.CredentialBlobSize)), nil
+}
+
+func keyringSet(token string) error {
+	if err := procCredWrite.Find(); err != nil {
+		return ErrNoKeyring
+	}
+	blob := []byte(token)
+	user, _ := syscall.UTF16PtrFromString(keyringAccount)
+	c := credential{
+		Type:               credTypeGeneric,
+		TargetName:         credTarget(),
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if r, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&c)), 0); r == 0 {
+		return err
+	}
+	return nil
+}
+
+func keyringDelete() error {
+	if err := procCredDel.Find(); err != nil {
+		return ErrNoKeyring
+	}
+	if r, _, err := procCredDel.Call(uintptr(unsafe.Pointer(credTarget())), credTypeGeneric, 0); r == 0 {
+		if err == errorNotFound {
+			return ErrNotInKeyring
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package fixhub
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// A BlobCache holds the contents of blobs, keyed by their SHA-1 ID.
+// Blobs never change, so cached contents never go stale.
+// Its methods may be called concurrently.
+type BlobCache interface {
+	Get(sha1 string) (content []byte, ok bool)
+	Put(sha1 string, content []byte)
+}
+
+// DirCache is a BlobCache that keeps blobs as files in a directory,
+// so that they are reused by later runs of a program.
+// Failures to write to the directory are ignored.
+type DirCache string
+
+func (d DirCache) path(sha1 string) (string, bool) {
+	if _, err := hex.DecodeString(sha1); err != nil || len(sha1) < 3 {
+		return "", false
+	}
+	return filepath.Join(string(d), sha1[:2], sha1[2:]), true
+}
+
+// Get implements BlobCache.
+func (d DirCache) Get(sha1 string) ([]byte, bool) {
+	path, ok := d.path(sha1)
+	if !ok {
+		return nil, false
+	}
+	content, err := ioutil.ReadFile(path)
+	return content, err == nil
+}
+
+// Put implements BlobCache.
+func (d DirCache) Put(sha1 string, content []byte) {
+	path, ok := d.path(sha1)
+	if !ok {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	// Write to a temporary file first, so a concurrent Get
+	// never sees part of a blob.
+	f, err := ioutil.TempFile(filepath.Dir(path), "tmp")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
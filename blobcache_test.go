@@ -0,0 +1,43 @@
+package fixhub
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBlobCache(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+	c.Enabled = []ProblemType{Gofmt}
+	c.BlobCache = DirCache(t.TempDir())
+	var fetches int32
+	c.Hooks.OnFetch = func(kind string, d time.Duration) {
+		if kind == "blob" {
+			atomic.AddInt32(&fetches, 1)
+		}
+	}
+
+	first, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if fetches == 0 {
+		t.Fatalf("first Run fetched no blobs")
+	}
+	fetches = 0
+	second, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if fetches != 0 {
+		t.Errorf("second Run fetched %d blobs, want all from the cache", fetches)
+	}
+	if len(first.Problems) != len(second.Problems) {
+		t.Errorf("second Run found %d problems, want %d as before", len(second.Problems), len(first.Problems))
+	}
+
+	if _, ok := DirCache(t.TempDir()).Get("../../etc/passwd"); ok {
+		t.Errorf("DirCache.Get accepted a key that is not a SHA-1")
+	}
+}
@@ -7,25 +7,32 @@ package fixhub
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"go/build"
 	"go/format"
+	"go/parser"
 	"go/scanner"
+	"go/token"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
-	"code.google.com/p/goauth2/oauth"
 	"github.com/golang/lint"
 	"github.com/google/go-github/github"
+	"golang.org/x/mod/modfile"
 )
 
 const (
@@ -38,38 +45,198 @@ type Client struct {
 	gc          *github.Client
 	owner, repo string
 
+	rate struct {
+		sync.Mutex
+		limit RateLimit
+	}
+	rateWait sync.Mutex // held while waiting for the rate limit to reset
+
 	FetchParallelism int    // max fetches to do at once in an operation
 	ScratchDir       string // where we can scribble files; defaults to os.TempDir()
 
+	// FileTimeout, if positive, limits how long the per-file checks of a file may take.
+	// Files that take longer are reported as skipped.
+	FileTimeout time.Duration
+
+	// CheckerTimeout, if positive, limits how long each checker may spend
+	// on a file or package. A checker that takes longer, or that panics,
+	// is abandoned and reported as an Internal problem, and the others carry on.
+	CheckerTimeout time.Duration
+
+	// Timeout, if positive, limits how long Run spends checking files.
+	// Files not checked in time are reported as skipped,
+	// and the checks that need whole packages are not run.
+	Timeout time.Duration
+
+	// ForkTimeout, if positive, limits how long Fork waits for a fork to be made.
+	// Otherwise it waits up to a minute.
+	ForkTimeout time.Duration
+
 	// VetBinary is the path to vet.
 	// If this is the empty string we try to find it under GOROOT.
 	VetBinary string
+
+	// Sandbox makes Run check the repository's Go modules with full type information.
+	// It copies their Go files into ScratchDir, downloads their dependencies,
+	// then vets and type-checks each module as a whole, instead of vetting files one by one.
+	// Dependencies are downloaded from ModuleProxy; if that is not set,
+	// only those already in ModCache are used.
+	Sandbox bool
+
+	// GoBinary is the path to the go command, for Sandbox.
+	// If this is the empty string we try to find it under GOROOT, then in PATH.
+	GoBinary string
+
+	// ModCache is the module cache shared by sandboxes, so that dependencies
+	// are only downloaded once. If this is the empty string, one in ScratchDir is used.
+	ModCache string
+
+	// Dir, if non-empty, restricts Check to files under this
+	// slash-separated directory of the repository.
+	Dir string
+
+	// MinGoVersion, if set, is the oldest Go version (e.g. "1.21")
+	// that a go.mod file's go directive may name.
+	MinGoVersion string
+
+	// ModuleProxy, if set, is a Go module proxy (e.g. "https://proxy.golang.org")
+	// used to find out whether required module versions have been retracted,
+	// and by Sandbox to download dependencies.
+	ModuleProxy string
+
+	// MinDocCoverage is the fraction of its exported identifiers that a package
+	// should document. If zero, a default of one half is used.
+	// The repository may override it in its ConfigFile.
+	MinDocCoverage float64
+
+	// Platforms lists the GOOS/GOARCH combinations (e.g. "linux/amd64")
+	// whose sets of files are linted together, taking build constraints into account.
+	// If empty, DefaultPlatforms is used. The repository may override it in its ConfigFile.
+	Platforms []string
+
+	// DisabledLintCategories lists golint categories (e.g. "comments", "naming")
+	// whose problems are not reported. The repository may disable more in its ConfigFile.
+	DisabledLintCategories []string
+
+	// Enabled, if non-empty, restricts Check to these types of check.
+	// Otherwise all types of check run except for Unused.
+	// Disabled lists types of check for Check to skip.
+	// Syntax errors are always reported.
+	Enabled, Disabled []ProblemType
+
+	// Progress, if non-nil, is called by Check after each file is checked,
+	// with the number of files checked so far and the total number to check.
+	// Calls are serialized.
+	Progress func(checked, total int)
+
+	// Logger, if non-nil, receives diagnostics about checks,
+	// such as why a check could not be run.
+	Logger Logger
+
+	// Hooks receives instrumentation events.
+	Hooks Hooks
+
+	// BlobCache, if non-nil, is consulted by GetBlob before fetching a blob,
+	// and remembers what it fetches.
+	BlobCache BlobCache
+
+	// WaitForRateLimit, if non-nil, is called when the GitHub API refuses a request
+	// because the rate limit is exhausted, with when the limit resets.
+	// It should wait until then and return true, upon which the request is retried,
+	// or return false to fail the request. Calls are serialized.
+	WaitForRateLimit func(reset time.Time) bool
+
+	// ProtectedPaths lists patterns of paths that fixes must never change,
+	// as for ProtectedPath. Commit refuses to change such files, and CommitFixes
+	// leaves them out. If empty, DefaultProtectedPaths is used.
+	// The repository may protect more in its ConfigFile.
+	ProtectedPaths []string
+
+	// KeepSources makes Run return the contents of the files with problems,
+	// such as for showing problems in context.
+	KeepSources bool
 }
 
-// NewClient returns a new client.
+// NewClient returns a new client that makes its requests through DefaultTransport.
 // If accessToken is empty then the client will be unauthenticated.
 func NewClient(owner, repo, accessToken string) (*Client, error) {
-	// Either load the personal access token (and set httpClient accordingly),
-	// or leave httpClient as nil to get an unauthenticated client.
-	var httpClient *http.Client
-	if accessToken != "" {
-		httpClient = (&oauth.Transport{
-			Token: &oauth.Token{
-				AccessToken: accessToken,
-			},
-		}).Client()
-	}
-
-	gc := github.NewClient(httpClient)
-	gc.UserAgent = "fixhub"
-
-	return &Client{
-		gc:    gc,
+	return NewClientWithHTTPClient(owner, repo, NewHTTPClient(nil, accessToken)), nil
+}
+
+// NewClientWithHTTPClient returns a new client that makes its requests using hc,
+// which may be nil to use http.DefaultClient.
+// This permits the use of custom transports, such as those in package fixhubtest,
+// or a client from NewHTTPClient with a base transport of the caller's choosing.
+func NewClientWithHTTPClient(owner, repo string, hc *http.Client) *Client {
+	c := &Client{
 		owner: owner,
 		repo:  repo,
 
 		FetchParallelism: 10,
-	}, nil
+	}
+
+	// Copy hc so as to wait out rate limits without changing the caller's client.
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	rc := *hc
+	rt := &rateLimitTransport{c: c, base: hc.Transport}
+	if rt.base == nil {
+		rt.base = http.DefaultTransport
+	}
+	rc.Transport = accessTransport{rt}
+	c.gc = github.NewClient(&rc)
+	c.gc.UserAgent = UserAgent
+	return c
+}
+
+// GitHub returns the GitHub API client that c uses, so that embedders can make
+// their own calls with the same authentication, rate limit handling and base URL.
+// Calls made through it are not reported to c's Hooks.
+func (c *Client) GitHub() *github.Client {
+	return c.gc
+}
+
+// SetBaseURL sets the base URL of the GitHub API that the client talks to.
+// It is chiefly useful for pointing a client at a fixhubtest.Server.
+func (c *Client) SetBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("parsing base URL: %v", err)
+	}
+	c.gc.BaseURL = u
+	return nil
+}
+
+// Runs reports whether Check will run the given type of check,
+// taking Enabled and Disabled into account.
+func (c *Client) Runs(t ProblemType) bool {
+	if t == Syntax {
+		return true
+	}
+	for _, d := range c.Disabled {
+		if d == t {
+			return false
+		}
+	}
+	if len(c.Enabled) == 0 {
+		return !optIn[t]
+	}
+	for _, e := range c.Enabled {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) runsAny(ts ...ProblemType) bool {
+	for _, t := range ts {
+		if c.Runs(t) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Client) tempDir() string {
@@ -80,44 +247,316 @@ func (c *Client) tempDir() string {
 }
 
 // ResolveRef resolves the given ref into the SHA-1 commit ID.
+// The ref may be a SHA-1 ID, a branch or tag name, or a qualified name
+// such as "heads/main", "tags/v1.2.3" or "refs/tags/v1.2.3".
 func (c *Client) ResolveRef(ref string) (sha1 string, err error) {
-	commit, _, err := c.gc.Repositories.GetCommit(c.owner, c.repo, ref)
+	ref = strings.TrimPrefix(ref, "refs/")
+	start := time.Now()
+	commit, resp, err := c.gc.Repositories.GetCommit(c.owner, c.repo, ref)
+	c.fetched("commit", start, resp, err)
+	if err != nil && resp != nil {
+		switch resp.StatusCode {
+		case http.StatusConflict:
+			return "", fmt.Errorf("%s/%s: %w", c.owner, c.repo, ErrEmptyRepository)
+		case http.StatusNotFound, http.StatusUnprocessableEntity:
+			// A 404 may instead mean there's no such repository,
+			// which fetching the default branch will say.
+			def, err := c.DefaultBranch()
+			if err != nil {
+				return "", err
+			}
+			if def == ref || def == strings.TrimPrefix(ref, "heads/") {
+				def = ""
+			}
+			return "", &RefError{Ref: ref, DefaultBranch: def}
+		}
+	}
 	if err != nil {
 		return "", err
 	}
 	return *commit.SHA, nil
 }
 
+// LatestRelease returns the tag of the repository's latest release,
+// as a ref such as "tags/v1.2.3" for passing to Run.
+func (c *Client) LatestRelease() (string, error) {
+	start := time.Now()
+	rel, resp, err := c.gc.Repositories.GetLatestRelease(c.owner, c.repo)
+	c.fetched("release", start, resp, err)
+	if err != nil {
+		return "", fmt.Errorf("fetching latest release: %w", err)
+	}
+	if rel.TagName == nil {
+		return "", fmt.Errorf("latest release has no tag")
+	}
+	return "tags/" + *rel.TagName, nil
+}
+
 // GetTree fetches the github tree by SHA-1 commit ID.
 func (c *Client) GetTree(sha1 string) (*github.Tree, error) {
-	tree, _, err := c.gc.Git.GetTree(c.owner, c.repo, sha1, true)
+	start := time.Now()
+	tree, resp, err := c.gc.Git.GetTree(c.owner, c.repo, sha1, true)
+	c.fetched("tree", start, resp, err)
 	return tree, err
 }
 
+// fullTree fetches the complete recursive tree of a commit.
+// GitHub truncates recursive listings of large trees, so if that happens
+// the tree is walked one directory at a time instead, skipping directories
+// outside c.Dir. Listings that are still incomplete are described by the
+// returned warnings.
+func (c *Client) fullTree(sha1 string) (tree *github.Tree, warnings []string, err error) {
+	tree, err = c.GetTree(sha1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tree.Truncated == nil || !*tree.Truncated {
+		return tree, nil, nil
+	}
+
+	dir := strings.Trim(c.Dir, "/")
+	relevant := func(p string) bool {
+		return dir == "" || p == dir || strings.HasPrefix(p, dir+"/") || strings.HasPrefix(dir, p+"/")
+	}
+	full := &github.Tree{SHA: tree.SHA}
+	var walk func(prefix, sha1 string) error
+	walk = func(prefix, sha1 string) error {
+		start := time.Now()
+		t, resp, err := c.gc.Git.GetTree(c.owner, c.repo, sha1, false)
+		c.fetched("tree", start, resp, err)
+		if err != nil {
+			return fmt.Errorf("fetching subtree %q: %v", prefix, err)
+		}
+		if t.Truncated != nil && *t.Truncated {
+			name := prefix
+			if name == "" {
+				name = "the top-level directory"
+			}
+			warnings = append(warnings, fmt.Sprintf("GitHub truncated the listing of %s; some of its files were not checked", name))
+		}
+		for _, ent := range t.Entries {
+			if ent.SHA == nil || ent.Path == nil {
+				continue
+			}
+			ent.Path = github.String(path.Join(prefix, *ent.Path))
+			full.Entries = append(full.Entries, ent)
+			if ent.Type != nil && *ent.Type == "tree" && relevant(*ent.Path) {
+				if err := walk(*ent.Path, *ent.SHA); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk("", sha1); err != nil {
+		return nil, nil, err
+	}
+	return full, warnings, nil
+}
+
 // GetBlob fetches the repository blob by SHA-1 ID.
 func (c *Client) GetBlob(sha1 string) ([]byte, error) {
-	blob, _, err := c.gc.Git.GetBlob(c.owner, c.repo, sha1)
+	if c.BlobCache != nil {
+		if content, ok := c.BlobCache.Get(sha1); ok {
+			return content, nil
+		}
+	}
+	start := time.Now()
+	blob, resp, err := c.gc.Git.GetBlob(c.owner, c.repo, sha1)
+	c.fetched("blob", start, resp, err)
 	if err != nil {
 		return nil, err
 	}
-	content := *blob.Content
-	switch *blob.Encoding {
-	case "base64":
-		return base64.StdEncoding.DecodeString(content)
-	default:
+	if *blob.Encoding != "base64" {
 		return nil, fmt.Errorf("unknown blob encoding %q", *blob.Encoding)
 	}
+	content, err := base64.StdEncoding.DecodeString(*blob.Content)
+	if err != nil {
+		return nil, err
+	}
+	if c.BlobCache != nil {
+		c.BlobCache.Put(sha1, content)
+	}
+	return content, nil
+}
+
+// GetFileAtRev fetches the file at path, relative to the top of the repository,
+// as of the named revision. It returns the file's content and blob SHA-1 ID.
+func (c *Client) GetFileAtRev(path, rev string) (content []byte, sha1 string, err error) {
+	ref, err := c.ResolveRef(rev)
+	if err != nil {
+		return nil, "", fmt.Errorf("resolving %q: %v", rev, err)
+	}
+	// Look up one directory at a time, rather than listing the whole tree.
+	sha1 = ref
+	elems := strings.Split(strings.Trim(path, "/"), "/")
+	for i, elem := range elems {
+		start := time.Now()
+		t, resp, err := c.gc.Git.GetTree(c.owner, c.repo, sha1, false)
+		c.fetched("tree", start, resp, err)
+		if err != nil {
+			return nil, "", fmt.Errorf("fetching tree for %q: %v", strings.Join(elems[:i], "/"), err)
+		}
+		want := "tree"
+		if i == len(elems)-1 {
+			want = "blob"
+		}
+		sha1 = ""
+		for _, ent := range t.Entries {
+			if ent.Path != nil && *ent.Path == elem && ent.Type != nil && *ent.Type == want && ent.SHA != nil {
+				sha1 = *ent.SHA
+				break
+			}
+		}
+		if sha1 == "" {
+			return nil, "", fmt.Errorf("no file %q at %s", path, rev)
+		}
+	}
+	content, err = c.GetBlob(sha1)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching %q: %v", path, err)
+	}
+	return content, sha1, nil
 }
 
 // A Problem is something that was found wrong.
 type Problem struct {
 	File string
-	Line int    // line number, starting at 1
-	Text string // the prose that describes the problem
+	Line int // line number, starting at 1
+	Col  int // column number in bytes, starting at 1, or 0 if unknown
+
+	// EndLine and EndCol, if non-zero, give the position just after the
+	// source that the problem is about, for problems that span more than a point.
+	EndLine, EndCol int
+
+	Type ProblemType // the kind of check that found the problem
+	Code string      // the checker's name for the rule, if any (e.g. golint's "naming")
+	Text string      // the prose that describes the problem
+	URL  string      // documentation that explains the problem, if any
+
+	// LineText is the source line the problem is on, with runs of spaces
+	// collapsed, if known. It lets Fingerprint follow a problem as its file
+	// changes around it.
+	LineText string
+
+	Severity Severity
+	Fixable  bool          // whether Fix can fix the problem
+	Fix      *SuggestedFix // edits that fix the problem, for some fixable problems
+}
+
+// Severity is how important a Problem is.
+type Severity int
+
+const (
+	Warning Severity = iota // most problems
+	Error                   // the code is broken, such as a syntax error
+	Info                    // worth knowing, but not necessarily wrong
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	case Info:
+		return "info"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ProblemType identifies the check that found a Problem.
+type ProblemType string
+
+const (
+	Syntax ProblemType = "syntax" // the file could not be parsed
+	Gofmt  ProblemType = "gofmt"  // the file is not gofmt'd
+	Lint   ProblemType = "lint"   // golint
+	Vet    ProblemType = "vet"    // go vet
+	GoMod  ProblemType = "gomod"  // go.mod hygiene
+
+	FieldAlignment ProblemType = "fieldalignment" // struct fields that waste space on padding
+	Deprecated     ProblemType = "deprecated"     // uses of deprecated identifiers
+	Unconvert      ProblemType = "unconvert"      // unnecessary type conversions
+	IneffAssign    ProblemType = "ineffassign"    // assignments that are never used
+
+	DocCoverage ProblemType = "doccoverage" // packages that document too few of their exported identifiers
+	NoTests     ProblemType = "notests"     // packages without any tests
+	Encoding    ProblemType = "encoding"    // byte order marks and CRLF line endings
+
+	Types ProblemType = "types" // type errors, found only with Client.Sandbox
+
+	// Internal reports that a checker failed, by panicking or taking longer than
+	// Client.CheckerTimeout. Its Code is the checker. It is always reported.
+	Internal ProblemType = "internal"
+
+	// Unused reports exported identifiers that the repository never uses.
+	// Those are normal for libraries, so this check only runs if it is listed in Client.Enabled.
+	Unused ProblemType = "unused"
+)
+
+// docURLs are the documentation for each type of problem,
+// for those whose checker does not give something more specific.
+var docURLs = map[ProblemType]string{
+	Syntax:         "https://go.dev/ref/spec",
+	Gofmt:          "https://pkg.go.dev/cmd/gofmt",
+	Lint:           "https://go.dev/wiki/CodeReviewComments",
+	Vet:            "https://pkg.go.dev/cmd/vet",
+	GoMod:          "https://go.dev/ref/mod#go-mod-file",
+	FieldAlignment: "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/fieldalignment",
+	Deprecated:     "https://go.dev/wiki/Deprecated",
+	Unconvert:      "https://github.com/mdempsky/unconvert",
+	IneffAssign:    "https://github.com/gordonklaus/ineffassign",
+	DocCoverage:    "https://go.dev/doc/comment",
+	NoTests:        "https://pkg.go.dev/testing",
+	Encoding:       "https://go.dev/ref/spec#Source_code_representation",
+	Types:          "https://go.dev/ref/spec",
+	Unused:         "https://staticcheck.dev/docs/checks/#U1000",
+}
+
+// repoChecks are the problem types whose checks need all of the repository's packages.
+var repoChecks = []ProblemType{Deprecated, Unused, DocCoverage, NoTests}
+
+// optIn lists the problem types that only run if they are in Client.Enabled.
+var optIn = map[ProblemType]bool{Unused: true}
+
+// ProblemTypes lists all the known problem types.
+var ProblemTypes = []ProblemType{Syntax, Gofmt, Lint, Vet, GoMod, FieldAlignment, Deprecated, Unconvert, IneffAssign, DocCoverage, NoTests, Encoding, Types, Unused}
+
+// ParseProblemTypes parses a comma-separated list of problem types,
+// such as "gofmt,vet". An empty string yields an empty list.
+func ParseProblemTypes(s string) ([]ProblemType, error) {
+	var ts []ProblemType
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		found := false
+		for _, t := range ProblemTypes {
+			if string(t) == f {
+				ts = append(ts, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown problem type %q", f)
+		}
+	}
+	return ts, nil
+}
+
+// at returns p with its position set to span the source from start to end.
+func (p Problem) at(fset *token.FileSet, start, end token.Pos) Problem {
+	s, e := fset.Position(start), fset.Position(end)
+	p.Line, p.Col = s.Line, s.Column
+	p.EndLine, p.EndCol = e.Line, e.Column
+	return p
 }
 
 func (p Problem) String() string {
-	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Text)
+	return fmt.Sprint(p)
 }
 
 // Problems is a slice of Problem.
@@ -138,29 +577,95 @@ func (ps Problems) Less(i, j int) bool {
 
 // Check runs checks on the Go source files at the named revision.
 func (c *Client) Check(rev string) (Problems, error) {
+	res, err := c.Run(rev)
+	if err != nil {
+		return nil, err
+	}
+	return res.Problems, nil
+}
+
+// CheckResult is the outcome of checking a revision.
+type CheckResult struct {
+	SHA      string // the commit that was checked
+	Checked  int    // the number of files checked
+	Problems Problems
+	Skipped  []Skipped // files that were not checked, sorted by file
+	Warnings []string  // conditions that may have made the check incomplete
+
+	// Sources holds the contents of the files with problems, keyed by path,
+	// if Client.KeepSources is set.
+	Sources map[string][]byte
+}
+
+// Skipped records a file that was not checked, and why.
+type Skipped struct {
+	File   string
+	Reason string
+}
+
+// lfsPointerPrefix is how Git LFS pointer files start.
+// See https://github.com/git-lfs/git-lfs/blob/main/docs/spec.md.
+var lfsPointerPrefix = []byte("version https://git-lfs.github.com/spec/v1\n")
+
+// Run runs checks on the Go source files at the named revision,
+// like Check, but also reports what was skipped.
+func (c *Client) Run(rev string) (*CheckResult, error) {
+	return c.run(rev, nil)
+}
+
+// CheckFiles runs checks on just the named files at the named revision.
+// Paths are relative to the top of the repository, not Client.Dir.
+// Checks that need to see whole packages or modules (GoMod, Deprecated, Unused,
+// DocCoverage and NoTests) are not run, since they would be misled by
+// the files that are left out. Named files that are not in the revision
+// are reported as skipped.
+func (c *Client) CheckFiles(rev string, paths []string) (*CheckResult, error) {
+	only := make(map[string]bool)
+	for _, path := range paths {
+		only[path] = true
+	}
+	return c.run(rev, only)
+}
+
+// run implements Run and CheckFiles. If only is not nil,
+// it checks only the files it lists.
+func (c *Client) run(rev string, only map[string]bool) (*CheckResult, error) {
+	var deadline time.Time
+	if c.Timeout > 0 {
+		deadline = time.Now().Add(c.Timeout)
+	}
 	ref, err := c.ResolveRef(rev) // TODO: skip this if it looks like a SHA-1 hash
+	var re *RefError
+	if errors.Is(err, ErrEmptyRepository) || errors.As(err, &re) {
+		return nil, err // these say enough already
+	} else if err != nil {
+		return nil, fmt.Errorf("resolving %q: %w", rev, err)
+	}
+	c.debug("checking revision", "owner", c.owner, "repo", c.repo, "rev", rev, "sha1", ref)
+	tree, warnings, err := c.fullTree(ref)
 	if err != nil {
-		return nil, fmt.Errorf("resolving %q: %v", rev, err)
+		return nil, fmt.Errorf("fetching tree %q (%s): %w", rev, ref, err)
 	}
-	tree, err := c.GetTree(ref)
+	cfg, err := c.loadConfig(tree)
 	if err != nil {
-		return nil, fmt.Errorf("fetching tree %q (%s): %v", rev, ref, err)
+		return nil, err
 	}
 
 	// Look for vet.
 	vet := c.VetBinary
-	if vet == "" {
+	if vet == "" && c.Runs(Vet) {
 		vet = filepath.Join(build.ToolDir, "vet")
 		if _, err := os.Stat(vet); err != nil {
-			// don't care what the error is; silently ignore vet
-			log.Printf("XXX: vet stat: %v", err)
+			c.warn("vet not found; skipping vet checks", "path", vet, "err", err)
 			vet = ""
 		}
 	}
+	if c.Sandbox && only == nil {
+		vet = "" // the sandbox vets whole modules instead
+	}
 
 	var (
-		linter = new(lint.Linter)
-		sem    = make(chan int, c.FetchParallelism)
+		sem = make(chan int, c.FetchParallelism)
 
 		wg       sync.WaitGroup
 		problems struct {
@@ -173,92 +678,612 @@ func (c *Client) Check(rev string) (Problems, error) {
 		problems.list = append(problems.list, p)
 		problems.Unlock()
 	}
-	addScannerError := func(path string, err *scanner.Error) {
-		addProblem(Problem{
-			File: path,
-			Line: err.Pos.Line,
-			Text: err.Msg,
-		})
+
+	var (
+		files    []github.TreeEntry
+		links    []github.TreeEntry        // symlinks to .go files
+		modFiles = make(map[string]string) // dir -> SHA-1 of go.mod
+		sumFiles = make(map[string]string) // dir -> SHA-1 of go.sum
+	)
+	var skipped struct {
+		sync.Mutex
+		list []Skipped
+	}
+	skip := func(file, format string, a ...interface{}) {
+		skipped.Lock()
+		skipped.list = append(skipped.list, Skipped{file, fmt.Sprintf(format, a...)})
+		skipped.Unlock()
 	}
 
+	found := make(map[string]bool) // files in only that are in the tree
 	for _, ent := range tree.Entries {
-		if ent.SHA == nil || ent.Path == nil || ent.Size == nil {
+		if ent.SHA == nil || ent.Path == nil {
+			continue
+		}
+		path := *ent.Path
+		if only != nil {
+			if !only[path] {
+				continue
+			}
+			found[path] = true
+		} else if dir := strings.Trim(c.Dir, "/"); dir != "" && !strings.HasPrefix(path, dir+"/") {
+			continue
+		}
+		if ent.Type != nil && *ent.Type == "commit" {
+			skip(path, "submodule at commit %s", *ent.SHA)
+			continue
+		}
+		if ent.Mode != nil && *ent.Mode == symlinkMode {
+			if strings.HasSuffix(path, ".go") {
+				links = append(links, ent)
+			}
+			continue
+		}
+		if ent.Size == nil {
+			continue
+		}
+		size := *ent.Size
+		if dir, ok := modFileDir(path, "go.mod"); ok {
+			modFiles[dir] = *ent.SHA
+			continue
+		}
+		if dir, ok := modFileDir(path, "go.sum"); ok {
+			sumFiles[dir] = *ent.SHA
 			continue
 		}
-		sha1, path, size := *ent.SHA, *ent.Path, *ent.Size
 		if !strings.HasSuffix(path, ".go") {
 			continue
 		}
-		if strings.HasSuffix(path, ".pb.go") {
+		if generated(path) {
+			skip(path, "generated protocol buffer code")
 			continue
 		}
 		if size > sizeLimit {
-			//log.Printf("Skipping %s because it is too big: %d > %d", path, size, sizeLimit)
+			skip(path, "too big (%d bytes)", size)
 			continue
 		}
-		//log.Printf("+ %s (%d bytes)", path, size)
+		c.debug("checking file", "path", path, "size", size)
+		files = append(files, ent)
+	}
+	for path := range only {
+		if !found[path] {
+			skip(path, "not found at %s", ref)
+		}
+	}
+	files = append(files, c.followLinks(tree, links, files, skip)...)
+
+	var imports struct {
+		sync.Mutex
+		m map[string][]string // file path -> import paths
+	}
+	imports.m = make(map[string][]string)
+	addImport := func(file, ip string) {
+		imports.Lock()
+		imports.m[file] = append(imports.m[file], ip)
+		imports.Unlock()
+	}
+
+	// Lint needs to see all of a package's files together.
+	var packages struct {
+		sync.Mutex
+		m map[string]map[string][]byte // directory and package name -> file path -> content
+	}
+	packages.m = make(map[string]map[string][]byte)
+	addPackageFile := func(pkg, file string, src []byte) {
+		key := filepath.ToSlash(filepath.Dir(file)) + " " + pkg
+		packages.Lock()
+		if packages.m[key] == nil {
+			packages.m[key] = make(map[string][]byte)
+		}
+		packages.m[key][file] = src
+		packages.Unlock()
+	}
+
+	var sources struct {
+		sync.Mutex
+		m map[string][]byte // file path -> content, for fingerprints and KeepSources
+	}
+	sources.m = make(map[string][]byte)
+
+	var checked struct {
+		sync.Mutex
+		n int
+	}
+
+	var progress struct {
+		sync.Mutex
+		checked int
+	}
+	fileDone := func() {
+		if c.Progress == nil {
+			return
+		}
+		progress.Lock()
+		progress.checked++
+		c.Progress(progress.checked, len(files))
+		progress.Unlock()
+	}
+
+	for _, ent := range files {
+		sha1, path := *ent.SHA, *ent.Path
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-
-			// TODO: figure out how to do error reporting in here
+			defer fileDone()
 
 			sem <- 1
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				<-sem
+				skip(path, "not checked before the timeout of %v", c.Timeout)
+				return
+			}
 			src, err := c.GetBlob(sha1)
 			<-sem
 			if err != nil {
-				//log.Printf("Getting blob for %s: %v", path, err)
+				skip(path, "fetching failed: %v", err)
 				return
 			}
+			if bytes.HasPrefix(src, lfsPointerPrefix) {
+				skip(path, "Git LFS pointer")
+				return
+			}
+			defer c.checkedFile(path, time.Now())
+			sources.Lock()
+			sources.m[path] = src
+			sources.Unlock()
 
-			formatted, err := format.Source(src)
-			if err != nil {
-				switch err := err.(type) {
-				case scanner.ErrorList:
-					for _, err := range err {
-						addScannerError(path, err)
-					}
-				case *scanner.Error:
-					addScannerError(path, err)
-				default:
-					addProblem(Problem{
-						File: path,
-						Text: err.Error(),
-					})
-				}
-				return // no more to do if we have syntax errors
+			// Check in another goroutine, so a slow file can be abandoned.
+			done := make(chan *fileResult, 1)
+			go func() { done <- c.checkFile(path, src, cfg, vet) }()
+			var timeout <-chan time.Time
+			if d := c.fileTimeout(deadline); d > 0 {
+				t := time.NewTimer(d)
+				defer t.Stop()
+				timeout = t.C
+			}
+			var fr *fileResult
+			select {
+			case fr = <-done:
+			case <-timeout:
+				skip(path, "checking took too long")
+				return
+			}
+			checked.Lock()
+			checked.n++
+			checked.Unlock()
+			for _, p := range fr.problems {
+				addProblem(p)
 			}
-			if !bytes.Equal(src, formatted) {
-				addProblem(Problem{
-					File: path,
-					Text: "This file needs formatting with gofmt.",
-				})
+			for _, ip := range fr.imports {
+				addImport(path, ip)
 			}
+			if fr.pkg != "" && (c.Runs(Lint) || c.runsAny(repoChecks...)) {
+				addPackageFile(fr.pkg, path, src)
+			}
+		}()
+	}
+	wg.Wait()
 
-			if ps, err := linter.Lint(path, src); err == nil {
-				for _, p := range ps {
-					if p.Confidence < 0.8 { // TODO: flag
-						continue
+	outOfTime := !deadline.IsZero() && time.Now().After(deadline)
+	if outOfTime {
+		warnings = append(warnings, fmt.Sprintf("checking ran out of time after %v, so package-level checks were not run", c.Timeout))
+	}
+
+	if c.Runs(Lint) && !outOfTime {
+		platforms := DefaultPlatforms
+		if len(c.Platforms) > 0 {
+			platforms = c.Platforms
+		}
+		if len(cfg.Platforms) > 0 {
+			platforms = cfg.Platforms
+		}
+		for _, files := range packages.m {
+			files := files
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Files for different platforms may conflict,
+				// so lint each platform's set of files separately.
+				// Problems in files shared by several platforms
+				// would otherwise be reported several times.
+				var first string // the package's first file, for Internal problems
+				for file := range files {
+					if first == "" || file < first {
+						first = file
+					}
+				}
+				seen := make(map[Problem]bool)
+				for _, set := range platformFileSets(files, platforms) {
+					set := set
+					for _, p := range c.runChecker("lint", first, func() Problems { return c.lint(set, cfg) }) {
+						if !seen[p] {
+							seen[p] = true
+							// Some fixes depend on the package's other files.
+							p.Fixable = p.Fixable && fixableIn(files, only == nil, p)
+							if p.Fixable {
+								p.Fix = suggestFix(files[p.File], p)
+							}
+							addProblem(p)
+						}
 					}
-					addProblem(Problem{
-						File: path,
-						Line: p.Position.Line,
-						Text: p.Text,
-					})
 				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	if c.runsAny(repoChecks...) && only == nil && !outOfTime {
+		fset := token.NewFileSet()
+		pkgs := parsePackages(fset, c.modulePath(modFiles), packages.m)
+		if c.Runs(Deprecated) {
+			problems.list = append(problems.list, c.runChecker("deprecated", "", func() Problems { return checkDeprecated(fset, pkgs) })...)
+		}
+		if c.Runs(Unused) {
+			problems.list = append(problems.list, c.runChecker("unused", "", func() Problems { return checkUnused(fset, pkgs, cfg) })...)
+		}
+		if c.Runs(DocCoverage) {
+			min := defaultMinDocCoverage
+			if c.MinDocCoverage > 0 {
+				min = c.MinDocCoverage
+			}
+			if cfg.Doc.MinCoverage > 0 {
+				min = cfg.Doc.MinCoverage
+			}
+			problems.list = append(problems.list, c.runChecker("doccoverage", "", func() Problems { return checkDocCoverage(pkgs, min) })...)
+		}
+		if c.Runs(NoTests) {
+			problems.list = append(problems.list, c.runChecker("notests", "", func() Problems { return checkNoTests(pkgs) })...)
+		}
+	}
+
+	if c.Sandbox && c.runsAny(Vet, Types) && only == nil && !outOfTime {
+		ctx := context.Background()
+		if !deadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+		files := make(map[string][]byte)
+		for path, src := range sources.m {
+			files[path] = src
+		}
+		var modDirs []string
+		for dir, sha1 := range modFiles {
+			modDirs = append(modDirs, dir)
+			if err := c.addModFile(files, dir, "go.mod", sha1); err != nil {
+				return nil, err
+			}
+		}
+		for dir, sha1 := range sumFiles {
+			if err := c.addModFile(files, dir, "go.sum", sha1); err != nil {
+				return nil, err
 			}
+		}
+		ps, ws, err := c.checkSandbox(ctx, files, modDirs, c.modulePath(modFiles))
+		if err != nil {
+			c.warn("checking in sandbox", "err", err)
+			warnings = append(warnings, fmt.Sprintf("checking with full type information failed: %v", err))
+		}
+		problems.list = append(problems.list, ps...)
+		warnings = append(warnings, ws...)
+	}
 
-			if ps, err := c.vet(vet, path, src); err == nil {
-				for _, p := range ps {
-					addProblem(p)
+	if c.Runs(GoMod) && only == nil && !outOfTime {
+		for dir, sha1 := range modFiles {
+			ps, err := c.checkModule(dir, sha1, sumFiles[dir], imports.m, skipped.list)
+			if err != nil {
+				return nil, err
+			}
+			problems.list = append(problems.list, ps...)
+		}
+	}
+	sort.Sort(Problems(problems.list))
+	for i := range problems.list {
+		p := &problems.list[i]
+		if p.URL == "" {
+			p.URL = docURLs[p.Type]
+		}
+		if p.LineText == "" {
+			p.LineText = lineText(sources.m[p.File], p.Line)
+		}
+	}
+	if c.Hooks.OnProblem != nil {
+		for _, p := range problems.list {
+			c.Hooks.OnProblem(p)
+		}
+	}
+	sort.Slice(skipped.list, func(i, j int) bool { return skipped.list[i].File < skipped.list[j].File })
+	if rl, ok := c.RateLimit(); ok && rl.Remaining == 0 && len(skipped.list) > 0 {
+		warnings = append(warnings, fmt.Sprintf("the GitHub API rate limit of %d requests an hour ran out, so files were skipped; it resets at %s", rl.Limit, rl.Reset.Format("15:04")))
+	}
+	res := &CheckResult{
+		SHA:      ref,
+		Checked:  checked.n,
+		Problems: problems.list,
+		Skipped:  skipped.list,
+		Warnings: warnings,
+	}
+	if c.KeepSources {
+		res.Sources = make(map[string][]byte)
+		for _, p := range res.Problems {
+			if src, ok := sources.m[p.File]; ok {
+				res.Sources[p.File] = src
+			}
+		}
+	}
+	return res, nil
+}
+
+// symlinkMode is the mode of tree entries for symbolic links,
+// whose blobs hold the paths they link to.
+const symlinkMode = "120000"
+
+// followLinks returns entries for checking the files that symlinks point to,
+// under the symlinks' own paths. Links are followed once, and only to
+// files in the tree that aren't in files already; others are skipped.
+func (c *Client) followLinks(tree *github.Tree, links, files []github.TreeEntry, skip func(file, format string, a ...interface{})) []github.TreeEntry {
+	if len(links) == 0 {
+		return nil
+	}
+	inTree := make(map[string]github.TreeEntry)
+	for _, ent := range tree.Entries {
+		if ent.Path != nil && ent.SHA != nil {
+			inTree[*ent.Path] = ent
+		}
+	}
+	checking := make(map[string]bool)
+	for _, ent := range files {
+		checking[*ent.Path] = true
+	}
+	var followed []github.TreeEntry
+	for _, ent := range links {
+		link := *ent.Path
+		b, err := c.GetBlob(*ent.SHA)
+		if err != nil {
+			skip(link, "fetching symlink failed: %v", err)
+			continue
+		}
+		target := path.Join(path.Dir(link), string(b))
+		t, ok := inTree[target]
+		switch {
+		case path.IsAbs(string(b)) || target == ".." || strings.HasPrefix(target, "../"):
+			skip(link, "symlink to %s, outside the repository", b)
+		case !ok:
+			skip(link, "symlink to %s, which is not in the tree", target)
+		case t.Mode != nil && *t.Mode == symlinkMode:
+			skip(link, "symlink to %s, another symlink", target)
+		case t.Type == nil || *t.Type != "blob" || t.Size == nil:
+			skip(link, "symlink to %s, which is not a file", target)
+		case checking[target]:
+			skip(link, "symlink to %s, which is checked itself", target)
+		case *t.Size > sizeLimit:
+			skip(link, "symlink to %s, which is too big (%d bytes)", target, *t.Size)
+		default:
+			c.debug("checking file", "path", link, "target", target, "size", *t.Size)
+			checking[target] = true
+			followed = append(followed, github.TreeEntry{Path: ent.Path, SHA: t.SHA, Size: t.Size})
+		}
+	}
+	return followed
+}
+
+// modulePath returns the path of the repository's top-level module,
+// given the SHA-1s of its go.mod files by directory.
+// Without a top-level go.mod, it is the path of the repository on GitHub.
+func (c *Client) modulePath(modFiles map[string]string) string {
+	modPath := "github.com/" + c.owner + "/" + c.repo
+	if sha1, ok := modFiles[""]; ok {
+		if mod, err := c.GetBlob(sha1); err != nil {
+			c.warn("fetching go.mod", "err", err)
+		} else if mp := modfile.ModulePath(mod); mp != "" {
+			modPath = mp
+		}
+	}
+	return modPath
+}
+
+// addModFile adds the module file name (go.mod or go.sum) in the directory dir,
+// which has the given SHA-1, to files.
+func (c *Client) addModFile(files map[string][]byte, dir, name, sha1 string) error {
+	path := name
+	if dir != "" {
+		path = dir + "/" + name
+	}
+	src, err := c.GetBlob(sha1)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %v", path, err)
+	}
+	files[path] = src
+	return nil
+}
+
+// fileResult is the outcome of the per-file checks of a Go source file.
+type fileResult struct {
+	problems Problems
+	pkg      string   // package name, if the file parsed
+	imports  []string // import paths, if GoMod runs
+}
+
+// checkFile runs the per-file checks on the file at path with content src.
+// vet is the path to vet, or empty if it is not to be run.
+func (c *Client) checkFile(path string, src []byte, cfg *Config, vet string) *fileResult {
+	fr := new(fileResult)
+	add := func(p Problem) { fr.problems = append(fr.problems, p) }
+	addScannerError := func(err *scanner.Error) {
+		add(Problem{
+			File:     path,
+			Line:     err.Pos.Line,
+			Col:      err.Pos.Column,
+			Type:     Syntax,
+			Text:     err.Msg,
+			Severity: Error,
+		})
+	}
+
+	if c.Runs(Encoding) {
+		for _, p := range c.runChecker("encoding", path, func() Problems { return checkEncoding(path, src) }) {
+			add(p)
+		}
+	}
+
+	formatted, err := format.Source(src)
+	if err != nil {
+		switch err := err.(type) {
+		case scanner.ErrorList:
+			for _, err := range err {
+				addScannerError(err)
+			}
+		case *scanner.Error:
+			addScannerError(err)
+		default:
+			add(Problem{
+				File:     path,
+				Type:     Syntax,
+				Text:     err.Error(),
+				Severity: Error,
+			})
+		}
+		return fr // no more to do if we have syntax errors
+	}
+	if f, err := parser.ParseFile(token.NewFileSet(), path, src, parser.ImportsOnly); err == nil {
+		if c.Runs(GoMod) {
+			for _, imp := range f.Imports {
+				if ip, err := strconv.Unquote(imp.Path.Value); err == nil {
+					fr.imports = append(fr.imports, ip)
 				}
 			}
+		}
+		fr.pkg = f.Name.Name
+	}
+	if c.Runs(Gofmt) && !bytes.Equal(src, formatted) {
+		add(Problem{
+			File:    path,
+			Type:    Gofmt,
+			Text:    "This file needs formatting with gofmt.",
+			Fixable: true,
+			Fix:     suggest("Format with gofmt.", src, formatted, nil),
+		})
+	}
+
+	if c.Runs(FieldAlignment) {
+		for _, p := range c.runChecker("fieldalignment", path, func() Problems { return checkFieldAlignment(path, src, cfg.canReorder) }) {
+			add(p)
+		}
+	}
+
+	if c.Runs(Unconvert) {
+		for _, p := range c.runChecker("unconvert", path, func() Problems { return checkUnconvert(path, src) }) {
+			add(p)
+		}
+	}
+	if c.Runs(IneffAssign) {
+		for _, p := range c.runChecker("ineffassign", path, func() Problems { return checkIneffAssign(path, src) }) {
+			add(p)
+		}
+	}
+
+	if c.Runs(Vet) && vet != "" {
+		ps := c.runChecker("vet", path, func() Problems {
+			ps, err := c.vet(vet, path, src)
+			if err != nil {
+				c.warn("vet failed", "path", path, "err", err)
+			}
+			return ps
+		})
+		for _, p := range ps {
+			add(p)
+		}
+	}
+	return fr
+}
+
+// runChecker runs a checker, named name, on file, recovering if it panics
+// and abandoning it after CheckerTimeout. Either failure is returned as
+// an Internal problem. file is "" for checkers of the whole repository.
+func (c *Client) runChecker(name, file string, check func() Problems) Problems {
+	failed := func(format string, a ...interface{}) Problems {
+		return Problems{{
+			File: file,
+			Type: Internal,
+			Code: name,
+			Text: fmt.Sprintf(format, a...),
+		}}
+	}
+	done := make(chan Problems, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c.warn("checker panicked", "checker", name, "path", file, "panic", r, "stack", string(debug.Stack()))
+				done <- failed("%s failed: %v", name, r)
+			}
 		}()
+		done <- check()
+	}()
+	if c.CheckerTimeout <= 0 {
+		return <-done
+	}
+	t := time.NewTimer(c.CheckerTimeout)
+	defer t.Stop()
+	select {
+	case ps := <-done:
+		return ps
+	case <-t.C:
+		c.warn("checker took too long", "checker", name, "path", file, "timeout", c.CheckerTimeout)
+		return failed("%s took longer than %v, so was abandoned", name, c.CheckerTimeout)
 	}
-	wg.Wait()
-	sort.Sort(Problems(problems.list))
-	return problems.list, nil
+}
+
+// fileTimeout returns how long to wait for the per-file checks of a file,
+// or 0 to wait as long as they take.
+func (c *Client) fileTimeout(deadline time.Time) time.Duration {
+	d := c.FileTimeout
+	if !deadline.IsZero() {
+		if left := time.Until(deadline); d <= 0 || left < d {
+			d = left
+		}
+		if d <= 0 {
+			d = time.Nanosecond // already out of time
+		}
+	}
+	return d
+}
+
+// lint runs golint on the files of a single package.
+func (c *Client) lint(files map[string][]byte, cfg *Config) Problems {
+	disabled := make(map[string]bool)
+	for _, cat := range c.DisabledLintCategories {
+		disabled[cat] = true
+	}
+	for _, cat := range cfg.Lint.Disable {
+		disabled[cat] = true
+	}
+
+	ps, err := new(lint.Linter).LintFiles(files)
+	if err != nil {
+		return nil
+	}
+	var problems Problems
+	for _, p := range ps {
+		if p.Confidence < 0.8 { // TODO: flag
+			continue
+		}
+		if disabled[p.Category] {
+			continue
+		}
+		prob := Problem{
+			File: p.Position.Filename,
+			Line: p.Position.Line,
+			Col:  p.Position.Column,
+			Type: Lint,
+			Code: p.Category,
+			Text: p.Text,
+			URL:  p.Link,
+		}
+		prob.Fixable = fixerFor(prob) != nil
+		problems = append(problems, prob)
+	}
+	return problems
 }
 
 func (c *Client) vet(vet, filename string, content []byte) (Problems, error) {
@@ -295,6 +1320,8 @@ func (c *Client) vet(vet, filename string, content []byte) (Problems, error) {
 		line := scan.Text()
 		// line looks like
 		//	x.go:301: unreachable code
+		// or, with a column,
+		//	x.go:301:2: unreachable code
 		parts := strings.SplitN(line, ":", 3)
 		if len(parts) != 3 || parts[0] != src {
 			continue
@@ -303,11 +1330,18 @@ func (c *Client) vet(vet, filename string, content []byte) (Problems, error) {
 		if err != nil {
 			continue // probably not a line number
 		}
-		text := strings.TrimSpace(parts[2])
+		text, col := parts[2], 0
+		if c, rest, ok := strings.Cut(text, ":"); ok {
+			if n, err := strconv.Atoi(c); err == nil {
+				text, col = rest, n
+			}
+		}
 		ps = append(ps, Problem{
 			File: filename,
 			Line: ln,
-			Text: text,
+			Col:  col,
+			Type: Vet,
+			Text: strings.TrimSpace(text),
 		})
 	}
 	return ps, nil
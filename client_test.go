@@ -1,26 +1,21 @@
 package fixhub
 
 import (
-	"crypto/sha1"
-	"encoding/base64"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"net/http/httptest"
-	"net/url"
-	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/dsymonds/fixhub/fixhubtest"
 	"github.com/google/go-github/github"
 )
 
 func TestBasic(t *testing.T) {
 	c, cleanup := newFakeClient(t)
 	defer cleanup()
+	c.Enabled = []ProblemType{Gofmt, Lint, Vet} // the checks this test knows about
 
 	ps, err := c.Check("master")
 	if err != nil {
@@ -32,6 +27,9 @@ func TestBasic(t *testing.T) {
 	if got, want := ps[0].Text, "This file needs formatting with gofmt."; got != want {
 		t.Errorf("ps[0].Text = %q, want %q", ps[0].Text, want)
 	}
+	if got, want := ps[0].Type, Gofmt; got != want {
+		t.Errorf("ps[0].Type = %q, want %q", got, want)
+	}
 	if got, want := ps[1].File, "p1.go"; got != want {
 		t.Errorf("Problem found in %q, want %q", got, want)
 	}
@@ -44,6 +42,9 @@ func TestBasic(t *testing.T) {
 	if got, want := ps[3].Line, 3; got != want {
 		t.Errorf("Problem found at line %d, want %d", got, want)
 	}
+	if got, want := ps[3].Type, Syntax; got != want {
+		t.Errorf("ps[3].Type = %q, want %q", got, want)
+	}
 	// p3.go test: govet
 	if !strings.Contains(ps[4].Text, "printf verb") {
 		t.Errorf("ps[4].Text=%q, want it to mention %q", ps[4].Text, "printf verb")
@@ -51,120 +52,496 @@ func TestBasic(t *testing.T) {
 	if got, want := ps[4].Line, 6; got != want {
 		t.Errorf("Problem found at line %d, want %d", got, want)
 	}
+	if got, want := ps[4].Type, Vet; got != want {
+		t.Errorf("ps[4].Type = %q, want %q", got, want)
+	}
 }
 
 func newFakeClient(t *testing.T) (client *Client, cleanup func()) {
 	const owner, proj = "faker", "proj"
 
-	f, err := newFakeGitHub(filepath.Join("testdata", owner, proj))
-	if err != nil {
-		t.Fatalf("newFakeGitHub: %v", err)
+	srv := fixhubtest.NewServer()
+	if _, err := srv.AddRepoFromDir(owner, proj, filepath.Join("testdata", owner, proj)); err != nil {
+		srv.Close()
+		t.Fatalf("AddRepoFromDir: %v", err)
 	}
 
 	c, err := NewClient(owner, proj, "")
 	if err != nil {
+		srv.Close()
 		t.Fatalf("NewClient: %v", err)
 	}
-
-	srv := httptest.NewServer(f)
-	c.gc.BaseURL, err = url.Parse(srv.URL + "/gh/")
-	if err != nil {
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
 		srv.Close()
-		t.Fatalf("Bad httptest address %q: %v", srv.URL, err)
+		t.Fatalf("SetBaseURL: %v", err)
 	}
 	return c, srv.Close
 }
 
-type fakeGitHub struct {
-	baseDir string
-
-	master string // SHA-1
+func TestSkipped(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
 
-	files map[string]string // path -> SHA-1
-	blobs map[string][]byte // SHA-1 -> content
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []Skipped{{File: "lfs.go", Reason: "Git LFS pointer"}}
+	if !reflect.DeepEqual(res.Skipped, want) {
+		t.Errorf("Skipped = %+v, want %+v", res.Skipped, want)
+	}
 }
 
-func newFakeGitHub(baseDir string) (*fakeGitHub, error) {
-	f := &fakeGitHub{
-		baseDir: baseDir,
-		master:  "deadbeef",
-		files:   make(map[string]string),
-		blobs:   make(map[string][]byte),
+func TestTruncatedTree(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.TruncateTrees = 3
+	ok := []byte("package p\n")
+	srv.AddRepo("faker", "big", map[string][]byte{
+		"a/a.go":         ok,
+		"b/1.go":         ok,
+		"b/2.go":         ok,
+		"b/3.go":         ok,
+		"b/4.go":         ok,
+		"c/deep/ugly.go": []byte("package  deep\n"),
+	})
+
+	c, err := NewClient("faker", "big", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	c.Enabled = []ProblemType{Gofmt}
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Problems) != 1 || res.Problems[0].File != "c/deep/ugly.go" {
+		t.Errorf("Problems = %v, want just a gofmt problem in c/deep/ugly.go", res.Problems)
+	}
+	want := []string{"GitHub truncated the listing of b; some of its files were not checked"}
+	if !reflect.DeepEqual(res.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", res.Warnings, want)
 	}
+}
 
-	err := filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+func TestSymlinks(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "links", map[string][]byte{
+		"a/ugly.go": []byte("package  a\n"),
+	})
+	for link, target := range map[string]string{
+		"b/ugly.go":  "../a/ugly.go",
+		"b/again.go": "ugly.go",
+		"gone.go":    "nope.go",
+		"out.go":     "../elsewhere/x.go",
+	} {
+		if _, err := srv.AddSymlink("faker", "links", link, target); err != nil {
+			t.Fatalf("AddSymlink: %v", err)
 		}
-		if !info.Mode().IsRegular() {
-			return nil
+	}
+
+	c, err := NewClient("faker", "links", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	c.Enabled = []ProblemType{Gofmt}
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Problems) != 1 || res.Problems[0].File != "a/ugly.go" {
+		t.Errorf("Problems = %v, want just a gofmt problem in a/ugly.go", res.Problems)
+	}
+	want := []Skipped{
+		{File: "b/again.go", Reason: "symlink to b/ugly.go, another symlink"},
+		{File: "b/ugly.go", Reason: "symlink to a/ugly.go, which is checked itself"},
+		{File: "gone.go", Reason: "symlink to nope.go, which is not in the tree"},
+		{File: "out.go", Reason: "symlink to ../elsewhere/x.go, outside the repository"},
+	}
+	if !reflect.DeepEqual(res.Skipped, want) {
+		t.Errorf("Skipped = %+v, want %+v", res.Skipped, want)
+	}
+
+	// Asked for on its own, a link is checked as the file it links to.
+	res, err = c.CheckFiles("master", []string{"b/ugly.go"})
+	if err != nil {
+		t.Fatalf("CheckFiles: %v", err)
+	}
+	if len(res.Problems) != 1 || res.Problems[0].File != "b/ugly.go" {
+		t.Errorf("CheckFiles(b/ugly.go): Problems = %v, want just a gofmt problem in b/ugly.go", res.Problems)
+	}
+}
+
+type recordLogger []string
+
+func (l *recordLogger) Debug(msg string, args ...interface{}) { *l = append(*l, "DEBUG "+msg) }
+func (l *recordLogger) Warn(msg string, args ...interface{})  { *l = append(*l, "WARN "+msg) }
+
+func TestLogger(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+	c.Enabled = []ProblemType{Gofmt}
+	var l recordLogger
+	c.Logger = &l
+
+	if _, err := c.Check("master"); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(l) == 0 || l[0] != "DEBUG checking revision" {
+		t.Errorf("logged %q, want it to start with the revision being checked", l)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+	c.Enabled = []ProblemType{Gofmt}
+
+	var (
+		mu        sync.Mutex
+		fetches   = make(map[string]int)
+		checked   int
+		problems  int
+		apiErrors int
+	)
+	c.Hooks = Hooks{
+		OnFetch: func(kind string, d time.Duration) {
+			mu.Lock()
+			fetches[kind]++
+			mu.Unlock()
+		},
+		OnAPIError: func(kind string, err error) {
+			mu.Lock()
+			apiErrors++
+			mu.Unlock()
+		},
+		OnCheckFile: func(file string, d time.Duration) {
+			mu.Lock()
+			checked++
+			mu.Unlock()
+		},
+		OnProblem: func(p Problem) { problems++ },
+	}
+	ps, err := c.Check("master")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if fetches["commit"] != 1 || fetches["tree"] != 1 || fetches["blob"] == 0 {
+		t.Errorf("fetches = %v, want one commit, one tree and some blobs", fetches)
+	}
+	if apiErrors != 0 {
+		t.Errorf("got %d API errors, want none", apiErrors)
+	}
+	if checked == 0 || checked >= fetches["blob"] {
+		t.Errorf("checked %d files from %d blobs; want some, but not the LFS pointer", checked, fetches["blob"])
+	}
+	if problems != len(ps) {
+		t.Errorf("OnProblem called %d times, want %d", problems, len(ps))
+	}
+}
+
+func TestKeepSources(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+	c.Enabled = []ProblemType{Gofmt}
+	c.KeepSources = true
+
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Problems) == 0 {
+		t.Fatalf("found no problems")
+	}
+	for _, p := range res.Problems {
+		if _, ok := res.Sources[p.File]; !ok {
+			t.Errorf("no source kept for %s, which has a problem", p.File)
 		}
-		rel, _ := filepath.Rel(baseDir, path) // can't fail
-		data, err := ioutil.ReadFile(path)
-		if err != nil {
-			return err
+	}
+	if len(res.Sources) > len(res.Problems) {
+		t.Errorf("kept %d sources for %d problems; want only files with problems", len(res.Sources), len(res.Problems))
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+	c.Enabled = []ProblemType{Gofmt, Lint}
+
+	res, err := c.CheckFiles("master", []string{"p1.go", "missing.go"})
+	if err != nil {
+		t.Fatalf("CheckFiles: %v", err)
+	}
+	if len(res.Problems) == 0 {
+		t.Errorf("found no problems in p1.go")
+	}
+	for _, p := range res.Problems {
+		if p.File != "p1.go" {
+			t.Errorf("found problem in %s, which was not asked for: %v", p.File, p)
 		}
-		sha1 := fmt.Sprintf("%02x", sha1.Sum(data))
-		f.files[rel] = sha1
-		f.blobs[sha1] = data
-		return nil
+	}
+	if len(res.Skipped) != 1 || res.Skipped[0].File != "missing.go" {
+		t.Errorf("Skipped = %+v, want just missing.go", res.Skipped)
+	}
+}
+
+func TestLintPackage(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "pkg", map[string][]byte{
+		// New returns a type declared in another file of the package,
+		// so linting new.go on its own can't tell that it is unexported.
+		"a/new.go":   []byte("// Package a makes things.\npackage a\n\n// New returns a new thing.\nfunc New() *thing { return &thing{} }\n"),
+		"a/thing.go": []byte("package a\n\ntype thing struct{}\n"),
+		// A package of its own, whose files are linted apart from a's.
+		"b/b.go": []byte("// Package b makes nothing.\npackage b\n\ntype thing struct{}\n\n// New returns nothing.\nfunc New() *Thing { return nil }\n\n// Thing is nothing.\ntype Thing struct{}\n"),
+	})
+	c, err := NewClient("faker", "pkg", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	c.Enabled = []ProblemType{Lint}
+
+	ps, err := c.Check("master")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(ps) != 1 {
+		t.Fatalf("got %d problems, want 1:\n%v", len(ps), ps)
+	}
+	if p := ps[0]; p.File != "a/new.go" || p.Line != 5 || p.Code != "unexported-type-in-api" || !strings.Contains(p.Text, "unexported type *a.thing") {
+		t.Errorf("got %v (%s), want a/new.go:5 saying New returns unexported type *a.thing", p, p.Code)
+	}
+}
+
+func TestGetFileAtRev(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	src := []byte("package deep\n")
+	srv.AddRepo("faker", "nested", map[string][]byte{
+		"top.go":      []byte("package top\n"),
+		"a/b/deep.go": src,
 	})
-	return f, err
-}
-
-func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "GET only", http.StatusMethodNotAllowed)
-		return
-	}
-	path := strings.TrimPrefix(r.URL.Path, "/gh/repos/faker/proj")
-	if path == r.URL.Path {
-		// didn't have prefix
-		http.Error(w, "bad path", http.StatusForbidden)
-		return
-	}
-
-	switch path {
-	case "/commits/master":
-		writeJSON(w, &github.RepositoryCommit{SHA: &f.master})
-		return
-	case "/git/trees/" + f.master: // assume "?recursive=1":
-		t := &github.Tree{SHA: &f.master}
-		for path, sha1 := range f.files {
-			t.Entries = append(t.Entries, github.TreeEntry{
-				SHA:  github.String(sha1),
-				Path: github.String(path),
-				Size: github.Int(len(f.blobs[sha1])),
-			})
+	c, err := NewClient("faker", "nested", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	got, sha1, err := c.GetFileAtRev("a/b/deep.go", "master")
+	if err != nil {
+		t.Fatalf("GetFileAtRev: %v", err)
+	}
+	if string(got) != string(src) {
+		t.Errorf("content = %q, want %q", got, src)
+	}
+	if blob, err := c.GetBlob(sha1); err != nil || string(blob) != string(src) {
+		t.Errorf("GetBlob(%q) = %q, %v; want %q", sha1, blob, err, src)
+	}
+	for _, path := range []string{"a/missing.go", "a/b", "top.go/x"} {
+		if _, _, err := c.GetFileAtRev(path, "master"); err == nil {
+			t.Errorf("GetFileAtRev(%q) succeeded, want error", path)
 		}
-		writeJSON(w, t)
-		return
 	}
+}
+
+func TestProblemURL(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+	c.Enabled = []ProblemType{Gofmt, Syntax}
+
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Problems) == 0 {
+		t.Fatalf("found no problems")
+	}
+	for _, p := range res.Problems {
+		if p.URL != docURLs[p.Type] {
+			t.Errorf("%v: URL = %q, want %q", p, p.URL, docURLs[p.Type])
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+	c.Timeout = time.Nanosecond
+
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Problems) != 0 {
+		t.Errorf("found problems %v, want none after timing out", res.Problems)
+	}
+	if len(res.Skipped) == 0 || !strings.Contains(res.Skipped[0].Reason, "timeout") {
+		t.Errorf("Skipped = %+v, want files skipped for the timeout", res.Skipped)
+	}
+	if len(res.Warnings) != 1 {
+		t.Errorf("Warnings = %q, want one about running out of time", res.Warnings)
+	}
+}
+
+func TestRunChecker(t *testing.T) {
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	c.CheckerTimeout = 10 * time.Millisecond
+	want := Problems{{File: "a.go", Type: Lint, Text: "fine"}}
+
+	if ps := c.runChecker("lint", "a.go", func() Problems { return want }); !reflect.DeepEqual(ps, want) {
+		t.Errorf("runChecker of a working checker = %v, want %v", ps, want)
+	}
+	ps := c.runChecker("lint", "a.go", func() Problems { panic("oops") })
+	if len(ps) != 1 || ps[0].Type != Internal || ps[0].Code != "lint" || !strings.Contains(ps[0].Text, "oops") {
+		t.Errorf("runChecker of a panicking checker = %v, want an Internal problem about the panic", ps)
+	}
+	block := make(chan bool)
+	defer close(block)
+	ps = c.runChecker("vet", "a.go", func() Problems { <-block; return want })
+	if len(ps) != 1 || ps[0].Type != Internal || ps[0].Code != "vet" || !strings.Contains(ps[0].Text, "abandoned") {
+		t.Errorf("runChecker of a hung checker = %v, want an Internal problem about the timeout", ps)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+
+	if _, ok := c.RateLimit(); ok {
+		t.Errorf("RateLimit reported before any API calls")
+	}
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	rl, ok := c.RateLimit()
+	if !ok || rl.Limit != 5000 || rl.Remaining >= rl.Limit || rl.Reset.IsZero() {
+		t.Errorf("RateLimit() = %+v, %v; want some of a limit of 5000 used", rl, ok)
+	}
+	if res.Checked == 0 {
+		t.Errorf("Checked = 0, want the number of files checked")
+	}
+}
 
-	if sha1 := strings.TrimPrefix(path, "/git/blobs/"); sha1 != path {
-		data := f.blobs[sha1]
-		if data == nil {
-			http.Error(w, "no such blob "+sha1, 404)
-			return
+func TestGitHub(t *testing.T) {
+	c, cleanup := newFakeClient(t)
+	defer cleanup()
+
+	var responses int
+	c.Hooks.OnResponse = func(kind string, resp *github.Response) {
+		if kind == "commit" {
+			responses++
+		}
+		if resp.Limit == 0 {
+			t.Errorf("%s response has no rate limit", kind)
 		}
-		writeJSON(w, &github.Blob{
-			Content:  github.String(base64.StdEncoding.EncodeToString(data)),
-			Encoding: github.String("base64"),
-			Size:     github.Int(len(data)),
-		})
-		return
+	}
+	sha, err := c.ResolveRef("master")
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	if responses != 1 {
+		t.Errorf("OnResponse called for %d commit responses, want 1", responses)
 	}
 
-	log.Printf("r: %v", r)
-	w.WriteHeader(http.StatusTeapot)
+	// The underlying client talks to the same server.
+	rc, _, err := c.GitHub().Repositories.GetCommit("faker", "proj", sha)
+	if err != nil {
+		t.Fatalf("GetCommit: %v", err)
+	}
+	if rc.SHA == nil || *rc.SHA != sha {
+		t.Errorf("GetCommit returned %v, want %s", rc.SHA, sha)
+	}
 }
 
-func writeJSON(w http.ResponseWriter, obj interface{}) {
-	b, err := json.Marshal(obj)
+func TestWaitForRateLimit(t *testing.T) {
+	const owner, proj = "faker", "proj"
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	if _, err := srv.AddRepoFromDir(owner, proj, filepath.Join("testdata", owner, proj)); err != nil {
+		t.Fatalf("AddRepoFromDir: %v", err)
+	}
+	srv.RateLimit = 3
+	c := NewClientWithHTTPClient(owner, proj, nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Skipped) == 0 || len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "rate limit") {
+		t.Errorf("Run with exhausted rate limit: Skipped = %+v, Warnings = %q; want files skipped and a warning", res.Skipped, res.Warnings)
+	}
+
+	waits := 0
+	c.WaitForRateLimit = func(reset time.Time) bool {
+		if reset.Before(time.Now()) {
+			t.Errorf("WaitForRateLimit called with past reset time %v", reset)
+		}
+		waits++
+		srv.ResetRateLimit()
+		return true
+	}
+	want, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if waits == 0 {
+		t.Errorf("WaitForRateLimit was not called")
+	}
+	if len(want.Warnings) != 0 {
+		t.Errorf("Run waiting for rate limit: Warnings = %q, want none", want.Warnings)
+	}
+	srv.RateLimit = 0
+	c.WaitForRateLimit = nil
+	if got, err := c.Run("master"); err != nil {
+		t.Fatalf("Run: %v", err)
+	} else if len(got.Problems) != len(want.Problems) || len(got.Skipped) != len(want.Skipped) {
+		t.Errorf("Run waiting for rate limit found %d problems and skipped %d files; without a limit, %d and %d",
+			len(want.Problems), len(want.Skipped), len(got.Problems), len(got.Skipped))
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	head := srv.AddRepo("faker", "proj", map[string][]byte{"main.go": []byte("package main\n")})
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	if tag, err := c.LatestRelease(); err == nil {
+		t.Errorf("LatestRelease with no releases = %q, want error", tag)
+	}
+	if err := srv.AddRelease("faker", "proj", "master", "v1.0"); err != nil {
+		t.Fatalf("AddRelease: %v", err)
+	}
+	tag, err := c.LatestRelease()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("internal JSON error: %v", err), 500)
-		return
+		t.Fatalf("LatestRelease: %v", err)
+	}
+	if tag != "tags/v1.0" {
+		t.Errorf("LatestRelease = %q, want %q", tag, "tags/v1.0")
+	}
+	for _, ref := range []string{tag, "v1.0", "refs/tags/v1.0"} {
+		if sha1, err := c.ResolveRef(ref); err != nil || sha1 != head {
+			t.Errorf("ResolveRef(%q) = %q, %v; want %q", ref, sha1, err, head)
+		}
 	}
-	w.Header().Set("Content-Type", "application/vnd.github.v3+json")
-	w.Write(b)
 }
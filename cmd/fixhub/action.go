@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/dsymonds/fixhub"
+	"github.com/dsymonds/fixhub/format"
+)
+
+// actionEvent holds what runAction needs from the payload of the event
+// that triggered a GitHub Actions workflow.
+type actionEvent struct {
+	PullRequest *struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Head   struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+}
+
+// runAction checks the repository that a GitHub Actions workflow is running for,
+// as described by the environment that Actions sets up. For a pull request event,
+// it checks the lines that the pull request adds; otherwise it checks the whole
+// repository at the commit that triggered the workflow. Problems are written as
+// workflow commands, so that they appear as annotations, and the step fails
+// if there are any.
+func runAction() {
+	owner, repo, ok := strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/")
+	if !ok {
+		log.Fatal("action must be run by GitHub Actions, which sets $GITHUB_REPOSITORY")
+	}
+	var ev actionEvent
+	if path := os.Getenv("GITHUB_EVENT_PATH"); path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Reading event: %v", err)
+		}
+		if err := json.Unmarshal(b, &ev); err != nil {
+			log.Fatalf("Parsing event: %v", err)
+		}
+	}
+
+	client := newClient(owner, repo, loadAccessToken())
+	var res *fixhub.CheckResult
+	var err error
+	if pr := ev.PullRequest; pr != nil {
+		res, err = client.CheckPullRequest(fixhub.PullRequest{Number: pr.Number, Title: pr.Title, Head: pr.Head.SHA})
+	} else {
+		sha := os.Getenv("GITHUB_SHA")
+		if sha == "" {
+			sha = *rev
+		}
+		if sha == "" {
+			if sha, err = client.DefaultBranch(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		res, err = client.Run(sha)
+	}
+	if err != nil {
+		log.Fatalf("Checking: %v", err)
+	}
+	var actions format.GitHubActions
+	for _, w := range res.Warnings {
+		actions.Warning(os.Stdout, w)
+	}
+	actions.Encode(os.Stdout, res.Problems)
+	if out := os.Getenv("GITHUB_OUTPUT"); out != "" {
+		f, err := os.OpenFile(out, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err != nil {
+			log.Fatalf("Setting outputs: %v", err)
+		}
+		fmt.Fprintf(f, "problems=%d\nsha=%s\n", len(res.Problems), res.SHA)
+		if err := f.Close(); err != nil {
+			log.Fatalf("Setting outputs: %v", err)
+		}
+	}
+	if len(res.Problems) > 0 {
+		fmt.Printf("fixhub found %d problems in %s/%s at %.7s\n", len(res.Problems), owner, repo, res.SHA)
+		os.Exit(1)
+	}
+}
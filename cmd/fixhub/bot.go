@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// runBot reviews the open pull requests of the repositories named in args,
+// looking for new pushes to them every -bot_interval.
+func runBot(args []string) {
+	if len(args) == 0 {
+		flag.Usage()
+		log.Fatal("bot needs at least one owner/repo")
+	}
+	if loadAccessToken() == "" {
+		log.Fatal("bot needs a GitHub access token with which to comment on pull requests")
+	}
+	var repos []fixhub.RepoPath
+	for _, arg := range args {
+		rp, err := fixhub.ParseRepoPath(arg)
+		if err != nil || rp.Rev != "" || rp.Dir != "" {
+			log.Fatalf("Bad repository %q; want owner/repo", arg)
+		}
+		repos = append(repos, rp)
+	}
+
+	reviewed := make(map[string]string) // "owner/repo#N" -> SHA-1 of head last reviewed
+	for {
+		// Clients are made each time around, as a GitHub App's tokens expire.
+		accessToken := loadAccessToken()
+		for i, rp := range repos {
+			client := newClient(rp.Owner, rp.Repo, accessToken)
+			prs, err := client.PullRequests()
+			if err != nil {
+				log.Printf("%s: %v", args[i], err)
+				continue
+			}
+			for _, pr := range prs {
+				key := fmt.Sprintf("%s#%d", args[i], pr.Number)
+				if reviewed[key] == pr.Head {
+					continue
+				}
+				res, posted, fixed, err := client.ReviewPullRequest(pr)
+				if err != nil {
+					log.Printf("Reviewing %s: %v", key, err)
+					continue
+				}
+				reviewed[key] = pr.Head
+				if !*quiet {
+					log.Printf("Reviewed %s at %.7s: %d problems, %d new comments, %d marked fixed", key, pr.Head, len(res.Problems), posted, fixed)
+				}
+			}
+		}
+		time.Sleep(*botInterval)
+	}
+}
@@ -4,69 +4,388 @@ Fixhub tries to fix a GitHub repository containing Go source files.
 package main
 
 import (
-	"bytes"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/dsymonds/fixhub"
+	"github.com/dsymonds/fixhub/auth"
+	"github.com/dsymonds/fixhub/format"
 )
 
 var (
 	personalAccessTokenFile = flag.String("personal_access_token_file", filepath.Join(os.Getenv("HOME"), ".fixhub-token"), "a file to load a GitHub personal access token from")
-	rev                     = flag.String("rev", "master", "revision of the repo to check")
+	token                   = flag.String("token", "", "a GitHub personal access token; overrides $GITHUB_TOKEN and -personal_access_token_file")
+	keyring                 = flag.Bool("keyring", true, "look for a token in the operating system's credential store, where fixhub auth login puts it, after $GITHUB_TOKEN and before -personal_access_token_file")
+	appID                   = flag.Int64("app_id", 0, "the ID of a GitHub App to act as instead of using a personal access token; needs -app_installation_id and -app_key_file")
+	appInstallation         = flag.Int64("app_installation_id", 0, "the ID of the GitHub App's installation for the repo's owner")
+	appKeyFile              = flag.String("app_key_file", "", "a file containing the GitHub App's private key, in PEM")
+	rev                     = flag.String("rev", "", "revision of the repo to check (e.g. a branch, a SHA-1 or tags/v1.2.3); the default branch if empty")
+	latestRelease           = flag.Bool("latest_release", false, "check the tag of the repo's latest release instead of -rev")
+	reviewdog               = flag.Bool("reviewdog", false, "write problems in reviewdog's rdjson format; the same as -format=reviewdog")
+	junitFile               = flag.String("junit", "", "if set, a file to write problems to as JUnit XML")
+	formatFlag              = flag.String("format", "", "output format: one of "+strings.Join(format.Names(), ", ")+", or a text/template to format each problem with (e.g. {{.File}}:{{.Line}}: {{.Text}})")
+	enable                  = flag.String("enable", "", "comma-separated list of checks to run (default all but unused); any of "+checkNames())
+	disable                 = flag.String("disable", "", "comma-separated list of checks to skip")
+	disableLint             = flag.String("disable_lint", "", "comma-separated list of golint categories to ignore (e.g. comments,naming)")
+	platforms               = flag.String("platforms", "", "comma-separated GOOS/GOARCH combinations to lint with (default "+strings.Join(fixhub.DefaultPlatforms, ",")+")")
+	minGoVersion            = flag.String("min_go_version", "", "if set, report go.mod files whose go directive is older than this (e.g. 1.21)")
+	minDocCoverage          = flag.Float64("min_doc_coverage", 0, "if positive, the fraction of exported identifiers each package should document")
+	moduleProxy             = flag.String("module_proxy", "", "if set, a Go module proxy to check required versions for retractions, and to download dependencies from with -sandbox (e.g. https://proxy.golang.org)")
+	sandbox                 = flag.Bool("sandbox", false, "copy the repo's modules to a scratch directory and download their dependencies, to vet and type-check them with full type information")
+	modCache                = flag.String("mod_cache", "", "with -sandbox, the module cache to keep dependencies in (default one in the system temporary directory)")
+	concurrency             = flag.Int("concurrency", 10, "how many files to fetch from GitHub at once")
+	fileTimeout             = flag.Duration("file_timeout", 0, "if positive, how long to spend checking each file before skipping it")
+	checkerTimeout          = flag.Duration("checker_timeout", 0, "if positive, how long each checker may spend on a file or package before it is abandoned")
+	timeout                 = flag.Duration("timeout", 0, "if positive, how long to spend checking files before skipping the rest")
+	waitForReset            = flag.Bool("wait_for_ratelimit", false, "when the GitHub API rate limit is used up, wait for it to reset rather than skip files")
+	cacheDir                = flag.String("cache_dir", "", "if set, a directory in which to keep fetched files for reuse by later runs")
+	watchInterval           = flag.Duration("watch", 0, "if positive, re-check at this interval and report changes")
+	commitStatus            = flag.Bool("commit_status", false, "set a \"fixhub\" commit status on the checked commit, saying whether there are problems")
+	fixhubdURL              = flag.String("fixhubd_url", "", "if set, the URL of a fixhubd server whose page of the problems commit statuses link to")
+	botInterval             = flag.Duration("bot_interval", 5*time.Minute, "with bot, how often to look for pushes to pull requests")
+	summary                 = flag.Bool("summary", false, "write the files, packages and types of problem with the most problems, instead of the problems")
+	codes                   = flag.Bool("codes", false, "with plain output, follow each problem with its check and rule code (e.g. [lint/naming])")
+	explain                 = flag.Bool("explain", false, "follow each problem with a link to documentation that explains it")
+	patch                   = flag.Bool("patch", false, "write a diff that fixes what can be fixed, for git apply, instead of the problems")
+	fix                     = flag.Bool("fix", false, "commit fixes of everything that can be fixed to the -rev branch, in a single commit")
+	protect                 = flag.String("protect", "", "comma-separated patterns of paths that fixes must not change (default "+strings.Join(fixhub.DefaultProtectedPaths, ",")+")")
+	revert                  = flag.String("revert", "", "if set, the SHA-1 of a commit of fixes made by fixhub to revert on the -rev branch")
+	deleteBranch            = flag.Bool("delete_branch", false, "delete the -rev branch, if all its commits not on the default branch are fixes made by fixhub or reverts of them")
+	deleteFork              = flag.Bool("delete_fork", false, "delete your fork of the repo, if its branches are all merged or in closed pull requests")
+	interactive             = flag.Bool("i", false, "interactively choose fixes, and commit them to the -rev branch")
+	raw                     = flag.Bool("raw", false, "write plain file:line: text output even to a terminal")
+	quiet                   = flag.Bool("q", false, "quiet; only write problems")
+	verbose                 = flag.Bool("v", false, "verbose; report progress even when stderr is not a terminal, and log debugging detail")
 )
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, "usage: fixhub [options] owner/repo")
+		fmt.Fprintln(os.Stderr, "usage: fixhub [options] owner/repo[@rev][/dir]")
+		fmt.Fprintln(os.Stderr, "       fixhub [options] bot owner/repo...")
+		fmt.Fprintln(os.Stderr, "       fixhub [options] action")
+		fmt.Fprintln(os.Stderr, "       fixhub auth login|logout")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
 
+	switch flag.Arg(0) {
+	case "bot":
+		runBot(flag.Args()[1:])
+		return
+	case "action":
+		runAction()
+		return
+	case "auth":
+		runAuth(flag.Args()[1:])
+		return
+	}
+
 	if flag.NArg() != 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
-	parts := strings.Split(flag.Arg(0), "/")
-	if len(parts) != 2 {
-		flag.Usage()
-		os.Exit(1)
+	rp, err := fixhub.ParseRepoPath(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Bad repository: %v", err)
+	}
+	owner, repo, dir := rp.Owner, rp.Repo, rp.Dir
+	if rp.Rev != "" {
+		if *rev != "" && *rev != rp.Rev {
+			log.Fatalf("Revision %q conflicts with -rev=%q", rp.Rev, *rev)
+		}
+		*rev = rp.Rev
+	}
+
+	if *reviewdog {
+		*formatFlag = "reviewdog"
+	}
+	var (
+		enc  format.Encoder
+		tmpl *template.Template
+	)
+	if f, ok := format.Lookup(*formatFlag); ok {
+		enc = f
+		if f.Name == "text" {
+			enc = format.Text{Codes: *codes, Explain: *explain}
+		}
+	} else if *formatFlag != "" {
+		tmpl, err = template.New("format").Parse(*formatFlag)
+		if err != nil {
+			log.Fatalf("Bad -format: %v", err)
+		}
+	}
+
+	accessToken := loadAccessToken()
+	if accessToken != "" && !*quiet {
+		checkToken(accessToken)
+	}
+	client := newClient(owner, repo, accessToken)
+	client.Dir = dir
+	if !*quiet && (*verbose || isTerminal(os.Stderr)) {
+		client.Progress = progressPrinter(os.Stderr)
+	}
+	client.KeepSources = *interactive || *patch
+
+	countAPICalls(client)
+	if *rev == "" && !*latestRelease {
+		if *rev, err = client.DefaultBranch(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *fix {
+		commitFixes(client, *rev)
+		return
+	}
+	if *deleteFork {
+		deleted, why, err := client.DeleteForkIfClean()
+		if err != nil {
+			log.Fatalf("Deleting fork: %v", err)
+		}
+		if !deleted {
+			log.Fatalf("Not deleting fork: %s", why)
+		}
+		fmt.Printf("Deleted your fork of %s/%s.\n", owner, repo)
+		return
+	}
+	if *revert != "" {
+		sha1, err := client.Revert(*rev, *revert)
+		if err != nil {
+			log.Fatalf("Reverting: %v", err)
+		}
+		fmt.Printf("Reverted %.7s; committed to %s as %s.\n", *revert, *rev, sha1)
+		return
+	}
+	if *deleteBranch {
+		deleted, why, err := client.DeleteFixBranch(*rev)
+		if err != nil {
+			log.Fatalf("Deleting branch: %v", err)
+		}
+		if !deleted {
+			log.Fatalf("Not deleting %s: %s", *rev, why)
+		}
+		fmt.Printf("Deleted %s from %s/%s.\n", *rev, owner, repo)
+		return
+	}
+	start := time.Now()
+	if *latestRelease {
+		if *rev, err = client.LatestRelease(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *commitStatus {
+		sha, err := client.ResolveRef(*rev)
+		if err != nil {
+			log.Fatalf("Resolving %s: %v", *rev, err)
+		}
+		if err := client.SetCommitStatus(sha, nil, statusURL(owner, repo, dir, sha)); err != nil {
+			log.Fatal(err)
+		}
+		*rev = sha
+	}
+	res, err := client.Run(*rev)
+	var re *fixhub.RefError
+	if errors.Is(err, fixhub.ErrEmptyRepository) {
+		fmt.Printf("%s/%s is empty; there is nothing to check.\n", owner, repo)
+		return
+	} else if errors.As(err, &re) && re.DefaultBranch != "" {
+		log.Fatalf("%s/%s has %v; run with -rev=%s to check it.", owner, repo, err, re.DefaultBranch)
+	} else if err != nil {
+		explainRateLimit(os.Stderr, client, accessToken != "")
+		log.Fatalf("Checking: %v", err)
+	}
+	if *commitStatus {
+		if err := client.SetCommitStatus(res.SHA, res, statusURL(owner, repo, dir, res.SHA)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, w := range res.Warnings {
+		log.Printf("Warning: %s", w)
+	}
+	explainRateLimit(os.Stderr, client, accessToken != "")
+	ps := res.Problems
+
+	if *patch {
+		if err := writePatch(os.Stdout, res); err != nil {
+			log.Fatalf("Writing patch: %v", err)
+		}
+		return
+	}
+	if *interactive {
+		if err := fixInteractively(client, *rev, res, os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("Fixing: %v", err)
+		}
+		return
 	}
-	owner, repo := parts[0], parts[1]
 
-	var accessToken string
-	if pat, err := ioutil.ReadFile(*personalAccessTokenFile); err == nil {
-		// security check
-		fi, err := os.Stat(*personalAccessTokenFile)
+	sort.Sort(ps)
+	if *junitFile != "" {
+		f, err := os.Create(*junitFile)
 		if err != nil {
-			log.Fatalf("os.Stat(%q): %v", *personalAccessTokenFile, err)
+			log.Fatal(err)
+		}
+		if err := (format.JUnit{}).Encode(f, ps); err != nil {
+			log.Fatalf("Writing JUnit XML: %v", err)
 		}
-		if fi.Mode()&0077 != 0 { // check that no group/world perm bits are set
-			log.Fatalf("%s is too accessible; run `chmod go= %s` to fix", *personalAccessTokenFile, *personalAccessTokenFile)
+		if err := f.Close(); err != nil {
+			log.Fatalf("Writing JUnit XML: %v", err)
 		}
+	}
+	if *summary {
+		writeOffenders(os.Stdout, ps, 10)
+	} else {
+		writeProblems(ps, enc, tmpl)
+	}
+	if !*quiet {
+		writeSummary(os.Stderr, client, res, time.Since(start))
+	}
 
-		accessToken = string(bytes.TrimSpace(pat))
+	if *watchInterval > 0 {
+		watch(client, *watchInterval, res.SHA, ps)
 	}
+}
 
+// newClient returns a client for checking a repository as the flags say.
+func newClient(owner, repo, accessToken string) *fixhub.Client {
 	client, err := fixhub.NewClient(owner, repo, accessToken)
 	if err != nil {
 		log.Fatal(err)
 	}
+	client.FetchParallelism = *concurrency
+	client.FileTimeout = *fileTimeout
+	client.CheckerTimeout = *checkerTimeout
+	client.Timeout = *timeout
+	client.MinGoVersion = *minGoVersion
+	if *platforms != "" {
+		client.Platforms = strings.Split(*platforms, ",")
+	}
+	client.MinDocCoverage = *minDocCoverage
+	client.ModuleProxy = *moduleProxy
+	client.Sandbox = *sandbox
+	client.ModCache = *modCache
+	if *protect != "" {
+		client.ProtectedPaths = strings.Split(*protect, ",")
+	}
+	if *cacheDir != "" {
+		client.BlobCache = fixhub.DirCache(*cacheDir)
+	}
+	if client.Enabled, err = fixhub.ParseProblemTypes(*enable); err != nil {
+		log.Fatalf("Bad -enable: %v", err)
+	}
+	if client.Disabled, err = fixhub.ParseProblemTypes(*disable); err != nil {
+		log.Fatalf("Bad -disable: %v", err)
+	}
+	if client.DisabledLintCategories, err = fixhub.ParseLintCategories(*disableLint); err != nil {
+		log.Fatalf("Bad -disable_lint: %v", err)
+	}
+	if !*quiet {
+		level := slog.LevelWarn
+		if *verbose {
+			level = slog.LevelDebug
+		}
+		client.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+	if *waitForReset {
+		client.WaitForRateLimit = waitForRateLimit(os.Stderr)
+	}
+	return client
+}
 
-	ps, err := client.Check(*rev)
+// commitFixes fixes what can be fixed on branch, and reports what it committed.
+func commitFixes(client *fixhub.Client, branch string) {
+	fc, err := client.CommitFixes(branch, "")
 	if err != nil {
-		log.Fatalf("Checking: %v", err)
+		log.Fatalf("Fixing: %v", err)
+	}
+	for _, ex := range fc.Excluded {
+		log.Printf("Not fixing %s: %v", ex.File, ex.Err)
+	}
+	if fc.SHA == "" {
+		fmt.Println("There is nothing that can be fixed.")
+		return
 	}
+	fmt.Printf("Fixed %d problems in %d files; committed to %s as %s.\n", len(fc.Fixed), len(fc.Files), branch, fc.SHA)
+}
 
-	sort.Sort(ps)
-	for _, p := range ps {
-		fmt.Println(p)
+// statusURL returns the URL that a commit status about a check should link to,
+// or "" if there is none.
+func statusURL(owner, repo, dir, sha string) string {
+	if *fixhubdURL == "" {
+		return ""
+	}
+	u := strings.TrimSuffix(*fixhubdURL, "/") + "/github.com/" + owner + "/" + repo + "@" + sha
+	if dir != "" {
+		u += "/" + dir
+	}
+	return u
+}
+
+// checkNames returns the names of the optional checks, for flag documentation.
+func checkNames() string {
+	var names []string
+	for _, t := range fixhub.ProblemTypes {
+		if t != fixhub.Syntax {
+			names = append(names, string(t))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeProblems writes ps to stdout, using enc or tmpl if either is non-nil.
+func writeProblems(ps fixhub.Problems, enc format.Encoder, tmpl *template.Template) {
+	switch {
+	case enc != nil:
+		if err := enc.Encode(os.Stdout, ps); err != nil {
+			log.Fatalf("Writing problems: %v", err)
+		}
+	case tmpl != nil:
+		for _, p := range ps {
+			if err := tmpl.Execute(os.Stdout, p); err != nil {
+				log.Fatalf("Formatting problem: %v", err)
+			}
+			fmt.Println()
+		}
+	case !*raw && isTerminal(os.Stdout):
+		writeGrouped(os.Stdout, ps)
+	default:
+		if err := (format.Text{Codes: *codes, Explain: *explain}).Encode(os.Stdout, ps); err != nil {
+			log.Fatalf("Writing problems: %v", err)
+		}
+	}
+}
+
+var tokens auth.Source
+
+// loadAccessToken returns the GitHub access token to use.
+// The -token flag takes precedence, then a GitHub App, then $GITHUB_TOKEN,
+// and finally the personal access token file.
+func loadAccessToken() string {
+	if tokens == nil {
+		src, err := auth.Credentials{
+			Token:           *token,
+			Keyring:         *keyring,
+			File:            *personalAccessTokenFile,
+			AppID:           *appID,
+			AppInstallation: *appInstallation,
+			AppKeyFile:      *appKeyFile,
+			HTTPClient:      fixhub.NewHTTPClient(nil, ""),
+		}.Source()
+		if err != nil {
+			log.Fatal(err)
+		}
+		tokens = src
+	}
+	t, err := tokens.Token()
+	if err != nil {
+		log.Fatal(err)
 	}
-	log.Printf("wow, there were %d problems!", len(ps))
+	return t
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dsymonds/fixhub"
+)
+
+const interactiveHelp = `y - apply this fix
+n - do not apply this fix
+q - quit; do not apply this fix or any of the remaining ones
+? - print help
+`
+
+// fixInteractively shows the suggested fix for each problem in res that has one,
+// as a diff, and asks whether to apply it. The fixes that are accepted are
+// then committed to branch in a single commit, rebased onto the branch if it
+// has moved on from the revision checked.
+// res must hold the sources of the files with problems.
+func fixInteractively(client *fixhub.Client, branch string, res *fixhub.CheckResult, in io.Reader, out io.Writer) error {
+	var fixable fixhub.Problems
+	for _, p := range res.Problems {
+		if p.Fix != nil && res.Sources[p.File] != nil {
+			fixable = append(fixable, p)
+		}
+	}
+	if len(fixable) == 0 {
+		fmt.Fprintln(out, "There is nothing that can be fixed.")
+		return nil
+	}
+
+	sc := bufio.NewScanner(in)
+	accepted := make(map[string][]fixhub.TextEdit) // file -> edits to it
+	var fixed fixhub.Problems
+ask:
+	for i, p := range fixable {
+		src := res.Sources[p.File]
+		if overlaps(accepted[p.File], p.Fix.Edits) {
+			fmt.Fprintf(out, "\n%v\nSkipped: its fix overlaps one already accepted.\n", p)
+			continue
+		}
+		after, err := p.Fix.Apply(src)
+		if err != nil {
+			fmt.Fprintf(out, "\n%v\nSkipped: its fix does not apply: %v\n", p, err)
+			continue
+		}
+		fmt.Fprintf(out, "\n(%d/%d) %v\n%s\n", i+1, len(fixable), p, p.Fix.Message)
+		out.Write(fixhub.UnifiedDiff(p.File, src, after))
+		for {
+			fmt.Fprint(out, "Apply this fix [y,n,q,?]? ")
+			if !sc.Scan() {
+				break ask // treat end of input like q
+			}
+			switch strings.TrimSpace(sc.Text()) {
+			case "y":
+				accepted[p.File] = append(accepted[p.File], p.Fix.Edits...)
+				fixed = append(fixed, p)
+			case "n":
+			case "q":
+				break ask
+			default:
+				fmt.Fprint(out, interactiveHelp)
+				continue
+			}
+			break
+		}
+	}
+	if len(fixed) == 0 {
+		fmt.Fprintln(out, "No fixes accepted; not committing.")
+		return sc.Err()
+	}
+
+	files := make(map[string][]byte)
+	for file, edits := range accepted {
+		sort.Slice(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+		content, err := fixhub.ApplyEdits(res.Sources[file], edits)
+		if err != nil {
+			return fmt.Errorf("fixing %s: %v", file, err)
+		}
+		files[file] = content
+	}
+	msg := fmt.Sprintf("Fix %d problems found by fixhub\n\n", len(fixed))
+	if len(fixed) == 1 {
+		msg = "Fix a problem found by fixhub\n\n"
+	}
+	for _, p := range fixed {
+		msg += fmt.Sprintf("%v\n", p)
+	}
+	sha1, err := client.Commit(branch, res.SHA, msg, files)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Committed to %s as %s.\n", branch, sha1)
+	return nil
+}
+
+// overlaps reports whether any edit in b overlaps or abuts one in a.
+func overlaps(a, b []fixhub.TextEdit) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Start <= y.End && y.Start <= x.End {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/dsymonds/fixhub"
+	"github.com/dsymonds/fixhub/auth"
+)
+
+// oauthClientID is the default -oauth_client_id. Those distributing fixhub
+// may set it when building, with -ldflags "-X main.oauthClientID=...".
+var oauthClientID string
+
+var (
+	oauthClientIDFlag = flag.String("oauth_client_id", oauthClientID, "the client ID of a GitHub OAuth App, with the device flow enabled, for fixhub auth login to get a token from; if empty, login asks for a personal access token")
+	oauthScope        = flag.String("oauth_scope", "public_repo", "the scopes that fixhub auth login asks for, separated by commas; use repo to check or fix private repositories")
+)
+
+// runAuth manages the token kept in the operating system's credential store.
+// Logging in gets a token by GitHub's device flow, if there is an OAuth App to
+// get one from, or asks for a personal access token.
+func runAuth(args []string) {
+	if len(args) != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "login":
+		var token string
+		if *oauthClientIDFlag != "" {
+			var err error
+			token, err = auth.DeviceFlow{
+				ClientID: *oauthClientIDFlag,
+				Scopes:   strings.Split(*oauthScope, ","),
+				Prompt: func(uri, code string) {
+					fmt.Fprintf(os.Stderr, "To let fixhub use GitHub as you, visit %s and enter the code %s\n", uri, code)
+				},
+				HTTPClient: fixhub.NewHTTPClient(nil, ""),
+			}.Login()
+			if err != nil {
+				log.Fatalf("Logging in: %v", err)
+			}
+		} else if token = readToken(); token == "" {
+			log.Fatal("No token given")
+		}
+		storeToken(token)
+	case "logout":
+		if err := auth.KeyringDelete(); err != nil {
+			log.Fatalf("Removing token: %v", err)
+		}
+		fmt.Fprintln(os.Stderr, "Removed the token from the credential store.")
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+// storeToken keeps token in the credential store or, failing that,
+// in the personal access token file.
+func storeToken(token string) {
+	err := auth.KeyringSet(token)
+	if err == nil {
+		fmt.Fprintln(os.Stderr, "Stored the token in the credential store.")
+		if _, err := os.Stat(*personalAccessTokenFile); err == nil {
+			fmt.Fprintf(os.Stderr, "%s is still there, but is now only used without -keyring; you may delete it.\n", *personalAccessTokenFile)
+		}
+		return
+	}
+	if err != auth.ErrNoKeyring {
+		log.Fatalf("Storing token: %v", err)
+	}
+	if err := ioutil.WriteFile(*personalAccessTokenFile, []byte(token+"\n"), 0600); err != nil {
+		log.Fatalf("Storing token: %v", err)
+	}
+	// WriteFile doesn't change the permissions of an existing file.
+	if err := os.Chmod(*personalAccessTokenFile, 0600); err != nil {
+		log.Fatalf("Storing token: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "There is no credential store, so the token is in %s.\n", *personalAccessTokenFile)
+}
+
+// checkToken warns if token can do much more than fixhub needs, or will soon expire.
+func checkToken(token string) {
+	ti, err := auth.Inspect(fixhub.NewHTTPClient(nil, ""), "", token)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if advice := ti.Advice(); advice != "" {
+		fmt.Fprintln(os.Stderr, advice)
+	}
+}
+
+// readToken reads a token from stdin, without echoing it if stdin is a terminal.
+func readToken() string {
+	if isTerminal(os.Stdin) {
+		fmt.Fprint(os.Stderr, "Paste a GitHub personal access token: ")
+		if runtime.GOOS != "windows" {
+			if echo(false) == nil {
+				defer func() {
+					echo(true)
+					fmt.Fprintln(os.Stderr)
+				}()
+			}
+		}
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return ""
+	}
+	return strings.TrimSpace(line)
+}
+
+// echo turns the terminal's echoing of input on or off.
+func echo(on bool) error {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
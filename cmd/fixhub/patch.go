@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"log"
+	"sort"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// writePatch writes to w a diff against the checked revision that fixes
+// all the fixable problems in res, which must hold the sources of the files
+// with problems. Paths are relative to the top of the repository.
+func writePatch(w io.Writer, res *fixhub.CheckResult) error {
+	var files []string
+	seen := make(map[string]bool)
+	for _, p := range res.Problems {
+		if p.Fixable && res.Sources[p.File] != nil && !seen[p.File] {
+			seen[p.File] = true
+			files = append(files, p.File)
+		}
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		src := res.Sources[file]
+		fixed, err := fixhub.FixFile(file, src, res.Problems)
+		if err != nil {
+			log.Printf("Not fixing %s: %v", file, err)
+			continue
+		}
+		if _, err := w.Write(fixhub.UnifiedDiff(file, src, fixed)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
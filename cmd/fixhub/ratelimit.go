@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// waitForRateLimit returns a fixhub.Client.WaitForRateLimit function
+// that counts down to the reset on w.
+func waitForRateLimit(w io.Writer) func(reset time.Time) bool {
+	return func(reset time.Time) bool {
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
+		for d := time.Until(reset); d > 0; d = time.Until(reset) {
+			fmt.Fprintf(w, "\rGitHub API rate limit exceeded; waiting %v for it to reset... ", d.Round(time.Second))
+			<-tick.C
+		}
+		fmt.Fprintln(w)
+		return true
+	}
+}
+
+// explainRateLimit writes advice to w if client has exhausted its rate limit.
+func explainRateLimit(w io.Writer, client *fixhub.Client, authenticated bool) {
+	rl, ok := client.RateLimit()
+	if !ok || rl.Remaining > 0 {
+		return
+	}
+	fmt.Fprintf(w, "The GitHub API rate limit of %d requests an hour has been used up; it resets at %s.\n", rl.Limit, rl.Reset.Format("15:04"))
+	if !authenticated {
+		fmt.Fprintf(w, "Unauthenticated requests are limited to 60 an hour. For a higher limit, supply a GitHub personal access token\n"+
+			"with -token, $GITHUB_TOKEN, \"fixhub auth login\" or in %s, or act as a GitHub App with -app_id.\n", *personalAccessTokenFile)
+	}
+	if !*waitForReset {
+		fmt.Fprintln(w, "Use -wait_for_ratelimit to wait for the limit to reset instead of skipping files.")
+	}
+}
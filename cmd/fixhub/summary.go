@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// apiCalls counts the GitHub API calls made by a client,
+// once countAPICalls has been called on it.
+var apiCalls int64
+
+func countAPICalls(client *fixhub.Client) {
+	client.Hooks.OnFetch = func(string, time.Duration) { atomic.AddInt64(&apiCalls, 1) }
+	client.Hooks.OnAPIError = func(string, error) { atomic.AddInt64(&apiCalls, 1) }
+}
+
+// writeSummary writes a table summarizing a check that took elapsed.
+func writeSummary(w io.Writer, client *fixhub.Client, res *fixhub.CheckResult, elapsed time.Duration) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Files checked\t%d\n", res.Checked)
+	fmt.Fprintf(tw, "Files skipped\t%d\n", len(res.Skipped))
+
+	types := make(map[fixhub.ProblemType]int)
+	severities := make(map[fixhub.Severity]int)
+	fixable := 0
+	for _, p := range res.Problems {
+		types[p.Type]++
+		severities[p.Severity]++
+		if p.Fixable {
+			fixable++
+		}
+	}
+	fmt.Fprintf(tw, "Problems\t%d (%d fixable)\n", len(res.Problems), fixable)
+	for _, t := range fixhub.ProblemTypes {
+		if n := types[t]; n > 0 {
+			fmt.Fprintf(tw, "  %s\t%d\n", t, n)
+		}
+	}
+	var sev []string
+	for _, s := range []fixhub.Severity{fixhub.Error, fixhub.Warning, fixhub.Info} {
+		if n := severities[s]; n > 0 {
+			sev = append(sev, fmt.Sprintf("%d %s", n, s))
+		}
+	}
+	if len(sev) > 0 {
+		fmt.Fprintf(tw, "By severity\t%s\n", strings.Join(sev, ", "))
+	}
+
+	fmt.Fprintf(tw, "Elapsed\t%v\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(tw, "API calls\t%d\n", atomic.LoadInt64(&apiCalls))
+	if rl, ok := client.RateLimit(); ok {
+		fmt.Fprintf(tw, "Rate limit\t%d of %d left, until %s\n", rl.Remaining, rl.Limit, rl.Reset.Format("15:04"))
+	}
+	tw.Flush()
+}
+
+// writeOffenders writes the n files, packages and types of problem
+// with the most problems in ps.
+func writeOffenders(w io.Writer, ps fixhub.Problems, n int) {
+	files, dirs, types := fixhub.TopOffenders(ps, n)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, sec := range []struct {
+		title   string
+		tallies []fixhub.Tally
+	}{
+		{"Files", files},
+		{"Packages", dirs},
+		{"Types", types},
+	} {
+		if len(sec.tallies) == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%s with the most problems:\n", sec.title)
+		for _, t := range sec.tallies {
+			fmt.Fprintf(tw, "  %s\t%d\n", t.Name, t.N)
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// ANSI escape sequences.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+
+	ansiClearLine = "\r\x1b[K"
+)
+
+// isTerminal reports whether f looks like an interactive terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// typeColors are the colors of the types of problems, by the kind of check:
+// red for broken code, blue for formatting, cyan for style,
+// green for likely bugs and magenta for the state of the package as a whole.
+var typeColors = map[fixhub.ProblemType]string{
+	fixhub.Syntax:   ansiRed,
+	fixhub.Types:    ansiRed,
+	fixhub.Internal: ansiRed,
+
+	fixhub.Gofmt:    ansiBlue,
+	fixhub.Encoding: ansiBlue,
+
+	fixhub.Lint:           ansiCyan,
+	fixhub.Unconvert:      ansiCyan,
+	fixhub.FieldAlignment: ansiCyan,
+
+	fixhub.Vet:         ansiGreen,
+	fixhub.IneffAssign: ansiGreen,
+	fixhub.Deprecated:  ansiGreen,
+
+	fixhub.GoMod:       ansiMagenta,
+	fixhub.DocCoverage: ansiMagenta,
+	fixhub.NoTests:     ansiMagenta,
+	fixhub.Unused:      ansiMagenta,
+}
+
+var severityColors = map[fixhub.Severity]string{
+	fixhub.Error:   ansiBold + ansiRed,
+	fixhub.Warning: ansiYellow,
+	fixhub.Info:    ansiBlue,
+}
+
+// writeGrouped writes ps to w grouped by file, with colors,
+// giving the severity and type of each problem.
+// ps must be sorted.
+func writeGrouped(w io.Writer, ps fixhub.Problems) {
+	width := 0
+	for _, p := range ps {
+		if n := len(p.Type); n > width {
+			width = n
+		}
+	}
+	for i, p := range ps {
+		if i == 0 || ps[i-1].File != p.File {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s%s%s\n", ansiBold, p.File, ansiReset)
+		}
+		line := "-"
+		if p.Line > 0 {
+			line = fmt.Sprint(p.Line)
+		}
+		fmt.Fprintf(w, "  %5s  %s%-7s%s  %s%-*s%s  %s\n", line,
+			severityColors[p.Severity], p.Severity, ansiReset,
+			typeColors[p.Type], width, p.Type, ansiReset, p.Text)
+		if *explain && p.URL != "" {
+			fmt.Fprintf(w, "  %5s  %-7s  %-*s  why? %s\n", "", "", width, "", p.URL)
+		}
+	}
+}
+
+// progressPrinter returns a function suitable for fixhub.Client.Progress.
+// On a terminal it redraws a single status line; otherwise it writes a line per call.
+func progressPrinter(f *os.File) func(checked, total int) {
+	if !isTerminal(f) {
+		return func(checked, total int) {
+			fmt.Fprintf(f, "checked %d/%d files\n", checked, total)
+		}
+	}
+	return func(checked, total int) {
+		fmt.Fprintf(f, "%schecked %d/%d files", ansiClearLine, checked, total)
+		if checked == total {
+			fmt.Fprint(f, ansiClearLine)
+		}
+	}
+}
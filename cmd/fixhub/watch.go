@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// watch re-checks the repository every interval, printing problems that
+// were introduced ("+") or resolved ("-") since the previous check,
+// of commit sha, which found prev.
+// It runs until interrupted, and then exits with a non-zero status
+// if any check found new problems.
+func watch(client *fixhub.Client, interval time.Duration, sha string, prev fixhub.Problems) {
+	intr := make(chan os.Signal, 1)
+	signal.Notify(intr, os.Interrupt)
+
+	regressed := false
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-intr:
+			if regressed {
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case <-tick.C:
+		}
+
+		res, err := client.Run(*rev)
+		if err != nil {
+			// Probably transient; try again next time.
+			log.Printf("Checking: %v", err)
+			continue
+		}
+		ps := res.Problems
+		sort.Sort(ps)
+		if res.SHA != sha {
+			// Problems in renamed files are not new.
+			renames, err := client.Renames(sha, res.SHA)
+			if err != nil {
+				log.Printf("Finding renamed files: %v", err)
+			}
+			prev = prev.Renamed(renames)
+		}
+		added, resolved := fixhub.Diff(prev, ps)
+		sha, prev = res.SHA, ps
+
+		now := time.Now().Format("15:04:05")
+		for _, p := range resolved {
+			fmt.Printf("%s - %v\n", now, p)
+		}
+		for _, p := range added {
+			fmt.Printf("%s + %v\n", now, p)
+		}
+		if len(added) > 0 {
+			regressed = true
+		}
+		if !*quiet {
+			log.Printf("%d new, %d resolved, %d total", len(added), len(resolved), len(ps))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// An asset is a static file that pages use, such as style.css.
+type asset struct {
+	content []byte
+	hash    string // of the content, to version its URL
+}
+
+// assets are the static files served under /assets/, by name.
+// They are the embedded files in the static directory,
+// each replaced by the file of the same name in -templates, if there is one.
+var assets = make(map[string]*asset)
+
+func loadAssets() error {
+	ents, err := fs.ReadDir(staticFiles, "static")
+	if err != nil {
+		return err
+	}
+	for _, ent := range ents {
+		name := ent.Name()
+		b, err := staticFiles.ReadFile("static/" + name)
+		if err != nil {
+			return err
+		}
+		if *templatesDir != "" {
+			ob, err := ioutil.ReadFile(filepath.Join(*templatesDir, name))
+			if err == nil {
+				b = ob
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+		}
+		h := sha256.Sum256(b)
+		assets[name] = &asset{content: b, hash: fmt.Sprintf("%x", h[:6])}
+	}
+	return nil
+}
+
+// assetURL returns the URL of the named asset, versioned by its content,
+// so that it may be cached for as long as it is unchanged.
+// It is available to templates as "asset".
+func assetURL(name string) string {
+	a, ok := assets[name]
+	if !ok {
+		return "/assets/" + name
+	}
+	return "/assets/" + name + "?v=" + a.hash
+}
+
+// assetHandler serves assets. When asked for the current version of an asset,
+// it lets it be cached indefinitely; otherwise it must be revalidated.
+func assetHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/assets/")
+	if name == r.URL.Path {
+		name = strings.TrimPrefix(r.URL.Path, "/") // the unversioned /style.css and /script.js
+	}
+	a, ok := assets[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.FormValue("v") == a.hash {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("ETag", `"`+a.hash+`"`)
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(a.content))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+const (
+	auditFile = "audit.jsonl"
+	maxAudit  = 500 // entries shown by auditHandler
+)
+
+// An auditEntry records a change that fixhubd made to GitHub
+// on a user's behalf.
+type auditEntry struct {
+	Time    time.Time `json:"time"`
+	Request string    `json:"request"`          // what the user asked for, e.g. "revert"
+	Action  string    `json:"action"`           // what was done, e.g. "commit"; see fixhub.Write
+	Repo    string    `json:"repo"`             // "owner/repo" changed
+	Branch  string    `json:"branch,omitempty"` // committed to
+	SHA     string    `json:"sha,omitempty"`    // the commit acted on
+	Result  string    `json:"result,omitempty"` // e.g. the SHA-1 of the commit made
+	Files   []string  `json:"files,omitempty"`  // the files changed
+	Remote  string    `json:"remote"`           // the requester; see requester
+}
+
+var auditMu sync.Mutex
+
+// auditWrites records each change client makes to GitHub in the audit log,
+// as made for r, which asked for request to be done to the commit of.
+func auditWrites(r *http.Request, client *fixhub.Client, request, of string) {
+	client.Hooks.OnWrite = func(w fixhub.Write) {
+		err := audit(r, auditEntry{
+			Request: request,
+			Action:  w.Action,
+			Repo:    w.Repo,
+			Branch:  w.Branch,
+			SHA:     of,
+			Result:  w.SHA,
+			Files:   w.Files,
+		})
+		if err != nil {
+			requestLogger(r).Error("writing to the audit log", "err", err)
+		}
+	}
+}
+
+// audit appends e to the audit log in -data_dir, one JSON object a line,
+// and logs it too.
+func audit(r *http.Request, e auditEntry) error {
+	e.Time, e.Remote = time.Now(), requester(r)
+	requestLogger(r).Info("audit", "request", e.Request, "action", e.Action, "repo", e.Repo, "branch", e.Branch, "sha", e.SHA, "result", e.Result, "files", e.Files)
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.OpenFile(filepath.Join(*dataDir, auditFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readAudit returns the most recent n entries of the audit log, newest first.
+func readAudit(n int) ([]auditEntry, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.Open(filepath.Join(*dataDir, auditFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var es []auditEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var e auditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+		if len(es) > n {
+			es = es[1:]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(es)-1; i < j; i, j = i+1, j-1 {
+		es[i], es[j] = es[j], es[i]
+	}
+	return es, nil
+}
+
+// auditHandler serves the most recent entries of the audit log
+// to the configured admins.
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	if !persistent() || !isAdmin(requester(r)) {
+		errf(w, http.StatusForbidden, "only admins may see the audit log")
+		return
+	}
+	es, err := readAudit(maxAudit)
+	if err != nil {
+		requestLogger(r).Error("reading the audit log", "err", err)
+		errf(w, http.StatusInternalServerError, "reading the audit log failed")
+		return
+	}
+	buf := new(bytes.Buffer)
+	if err := auditTmpl.Execute(buf, es); err != nil {
+		errf(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-store")
+	io.Copy(w, buf)
+}
+
+var auditTmpl = template.Must(template.New("audit.html").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>fixhub: audit log</title>
+<link rel="stylesheet" type="text/css" href="{{asset "style.css"}}">
+</head>
+<body>
+<div id="header">
+Changes made to GitHub, newest first
+</div>
+
+{{if .}}
+<table>
+<tr><th>Time</th><th>Requester</th><th>Request</th><th>Action</th><th>Repository</th><th>Branch</th><th>Of</th><th>Made</th><th>Files</th></tr>
+{{range .}}
+<tr>{{$repo := .Repo}}
+<td>{{.Time.Format "2006-01-02 15:04:05"}}</td>
+<td>{{.Remote}}</td>
+<td>{{.Request}}</td>
+<td>{{.Action}}</td>
+<td><a href="https://github.com/{{.Repo}}">{{.Repo}}</a></td>
+<td>{{.Branch}}</td>
+<td>{{with .SHA}}<a href="https://github.com/{{$repo}}/commit/{{.}}">{{printf "%.7s" .}}</a>{{end}}</td>
+<td>{{with .Result}}<a href="https://github.com/{{$repo}}/commit/{{.}}">{{printf "%.7s" .}}</a>{{end}}</td>
+<td>{{range $i, $f := .Files}}{{if $i}}, {{end}}{{$f}}{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>Nothing has been changed.</p>
+{{end}}
+</body>
+</html>
+`))
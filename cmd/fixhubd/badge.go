@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// A shieldsBadge is the JSON that shields.io expects from an endpoint badge;
+// see https://shields.io/badges/endpoint-badge.
+type shieldsBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// badgeHandler serves a shields.io endpoint badge showing how many problems
+// the last check of a whole repository found, not counting ignored problems.
+// It never starts a check, so a repository must have been checked before
+// its badge says anything useful.
+func badgeHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/v1/badge/github.com/"), "/", 3)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		errf(w, http.StatusBadRequest, "not a valid github owner/repo: %v", parts)
+		return
+	}
+	owner, repo := parts[0], parts[1]
+	if !allowed(owner, repo) {
+		errf(w, http.StatusForbidden, "checking %s/%s is not permitted here", owner, repo)
+		return
+	}
+
+	b := shieldsBadge{SchemaVersion: 1, Label: "fixhub", Message: "not checked", Color: "lightgrey"}
+	ps, ok, err := lastProblems(owner, repo)
+	if err != nil {
+		requestLogger(r).Error("loading last result", "owner", owner, "repo", repo, "err", err)
+		errf(w, http.StatusInternalServerError, "loading last result failed")
+		return
+	}
+	if ok {
+		ps, _ = filterIgnored(owner, repo, ps)
+		switch n := len(ps); {
+		case n == 0:
+			b.Message, b.Color = "no problems", "brightgreen"
+		case n == 1:
+			b.Message, b.Color = "1 problem", "yellow"
+		case n < 10:
+			b.Message, b.Color = fmt.Sprintf("%d problems", n), "yellow"
+		default:
+			b.Message, b.Color = fmt.Sprintf("%d problems", n), "red"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// shields.io caches for at least five minutes anyway.
+	w.Header().Set("Cache-Control", "max-age=300")
+	json.NewEncoder(w).Encode(b)
+}
+
+// lastProblems returns the problems found by the last check of a whole repository
+// with the default checks, and whether there was one.
+func lastProblems(owner, repo string) (fixhub.Problems, bool, error) {
+	if cr := latestResultFor(owner, repo); cr != nil {
+		return cr.res.Problems, true, nil
+	}
+	if !persistent() {
+		return nil, false, nil
+	}
+	last, err := loadResult(owner, repo)
+	if err != nil || last == nil {
+		return nil, false, err
+	}
+	return last.Problems, true, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// maxCached is how many check results are kept in memory.
+const maxCached = 100
+
+// shaRE matches a full commit SHA-1, which names a result that can't change.
+var shaRE = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// A resultKey identifies the result of checking a repository at a commit.
+type resultKey struct {
+	owner, repo, sha, dir string
+	enable, disable       string // the parameters choosing the checks
+}
+
+type cachedResult struct {
+	res     *fixhub.CheckResult
+	added   fixhub.Problems // problems new since the previous commit checked
+	checked time.Time
+}
+
+// resultCache holds recent results, so that pinned result pages
+// can be shown again without re-checking.
+var resultCache struct {
+	sync.Mutex
+	m     map[resultKey]*cachedResult
+	order []resultKey // oldest first
+}
+
+func cachedResultFor(key resultKey) *cachedResult {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	return resultCache.m[key]
+}
+
+func cacheResult(key resultKey, cr *cachedResult) {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	if resultCache.m == nil {
+		resultCache.m = make(map[resultKey]*cachedResult)
+	}
+	if _, ok := resultCache.m[key]; !ok {
+		resultCache.order = append(resultCache.order, key)
+	}
+	resultCache.m[key] = cr
+	for len(resultCache.order) > maxCached {
+		delete(resultCache.m, resultCache.order[0])
+		resultCache.order = resultCache.order[1:]
+	}
+}
+
+// latestResultFor returns the most recently cached result of checking
+// the whole of a repository with the default checks, or nil if there is none.
+func latestResultFor(owner, repo string) *cachedResult {
+	resultCache.Lock()
+	defer resultCache.Unlock()
+	for i := len(resultCache.order) - 1; i >= 0; i-- {
+		k := resultCache.order[i]
+		if k.owner == owner && k.repo == repo && k.dir == "" && k.enable == "" && k.disable == "" {
+			return resultCache.m[k]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/dsymonds/fixhub"
+	"github.com/dsymonds/fixhub/auth"
+	"gopkg.in/yaml.v2"
+)
+
+var configFile = flag.String("config", "", "YAML configuration file; flags override its settings")
+
+// serverConfig is the configuration in -config.
+//
+// An example:
+//
+//	http: :80
+//	https: :443
+//	autocert_domains: [fixhub.org]
+//	access_token_file: /etc/fixhub/token
+//	log_format: json
+//	allow: [dsymonds/*, golang]
+//	deny: [dsymonds/secret]
+//	checks:
+//	  enable: [gofmt, lint, vet]
+//	org:
+//	  include_forks: true
+//	  queue: 50
+//	trusted_proxies: [10.0.0.1]
+//	admins: [127.0.0.1, 10.0.0.0/8]
+//	write_quota:
+//	  per_user: 5
+//	  exempt: [10.0.0.0/8]
+//	revert:
+//	  branch: fixhub
+//	  allow: [dsymonds/*]
+//	watch:
+//	  allow: [dsymonds/*]
+//	  max: 20
+//	  email:
+//	    dsymonds/*: [dsymonds@example.com]
+//	ignore:
+//	  max: 500
+//
+// The listen addresses and TLS settings take effect only at startup;
+// the rest are reloaded on SIGHUP.
+type serverConfig struct {
+	HTTP            string   `yaml:"http"`
+	HTTPS           string   `yaml:"https"`
+	AutocertDomains []string `yaml:"autocert_domains"`
+	AutocertCache   string   `yaml:"autocert_cache"`
+	TLSCert         string   `yaml:"tls_cert"`
+	TLSKey          string   `yaml:"tls_key"`
+	LogFormat       string   `yaml:"log_format"`
+	AccessTokenFile string   `yaml:"access_token_file"`
+	AppID           string   `yaml:"app_id"`
+	AppInstallation string   `yaml:"app_installation_id"`
+	AppKeyFile      string   `yaml:"app_key_file"`
+	Rev             string   `yaml:"rev"`
+	DataDir         string   `yaml:"data_dir"`
+	RecheckInterval string   `yaml:"recheck_interval"`
+	CheckTimeout    string   `yaml:"check_timeout"`
+	CheckDeadline   string   `yaml:"check_deadline"`
+	CheckerTimeout  string   `yaml:"checker_timeout"`
+	PageSize        string   `yaml:"page_size"`
+	Templates       string   `yaml:"templates"`
+	Messages        string   `yaml:"messages"`
+
+	SMTPAddr         string `yaml:"smtp_addr"`
+	SMTPFrom         string `yaml:"smtp_from"`
+	SMTPUser         string `yaml:"smtp_user"`
+	SMTPPasswordFile string `yaml:"smtp_password_file"`
+	SecretKeyFile    string `yaml:"secret_key_file"`
+
+	// Allow, if non-empty, restricts fixhubd to repositories matching
+	// one of its patterns. Deny lists repositories it refuses.
+	// Patterns are "owner" or "owner/repo", and may use path.Match syntax.
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+
+	// Checks are the checks run when a request doesn't choose.
+	Checks struct {
+		Enable  []string `yaml:"enable"`
+		Disable []string `yaml:"disable"`
+	} `yaml:"checks"`
+
+	// Org chooses which of an owner's repositories the org pages cover.
+	// Archived repositories and forks are left out unless included.
+	// Queue limits how many of their checks may be queued at once.
+	Org struct {
+		IncludeArchived bool   `yaml:"include_archived"`
+		IncludeForks    bool   `yaml:"include_forks"`
+		Queue           string `yaml:"queue"`
+	} `yaml:"org"`
+
+	// TrustedProxies are the addresses and CIDR blocks of reverse proxies
+	// in front of fixhubd. For requests from them, the requester is taken
+	// from the X-Forwarded-For header instead, for write quotas, admins
+	// and the audit log.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	// Admins are the addresses and CIDR blocks of requesters
+	// who may see the audit log, at /admin/audit.
+	Admins []string `yaml:"admins"`
+
+	// WriteQuota limits how many changes to repositories, such as reverts,
+	// fixhubd makes an hour, for each requester and in all. Requesters
+	// from Exempt, a list of addresses and CIDR blocks, are not limited.
+	WriteQuota struct {
+		PerUser string   `yaml:"per_user"`
+		Global  string   `yaml:"global"`
+		Exempt  []string `yaml:"exempt"`
+	} `yaml:"write_quota"`
+
+	// Revert lets visitors revert commits of fixes made by fixhub
+	// on Branch, the branch fixes are committed to, of the repositories
+	// matching Allow. Unless both are set, reverting is off.
+	Revert struct {
+		Branch string   `yaml:"branch"`
+		Allow  []string `yaml:"allow"`
+	} `yaml:"revert"`
+
+	// Watch says which repositories visitors may have re-checked regularly,
+	// with patterns as for Allow, and how many may be watched at once.
+	// Unless Allow is set, none may be.
+	//
+	// Email sends notifications of new problems in watched repositories
+	// matching each pattern to the listed addresses; visitors can't choose
+	// where mail goes. Visitors' Slack webhooks must be on one of WebhookHosts,
+	// which is hooks.slack.com if empty.
+	Watch struct {
+		Allow        []string            `yaml:"allow"`
+		Max          string              `yaml:"max"`
+		Email        map[string][]string `yaml:"email"`
+		WebhookHosts []string            `yaml:"webhook_hosts"`
+	} `yaml:"watch"`
+
+	// Ignore limits how many problems visitors may ignore in each repository.
+	Ignore struct {
+		Max string `yaml:"max"`
+	} `yaml:"ignore"`
+}
+
+// settings holds the configuration that may change while running.
+var settings struct {
+	sync.RWMutex
+	tokens            auth.Source
+	allow, deny       []string
+	enabled, disabled []fixhub.ProblemType
+	org               orgOptions
+	trustedProxies    []string // addresses and CIDR blocks
+	quotaExempt       []string // addresses and CIDR blocks
+	admins            []string // addresses and CIDR blocks
+	revertAllow       []string // patterns of repositories whose fixes may be reverted
+	watchAllow        []string // patterns of repositories that may be watched
+	watchEmail        map[string][]string
+	webhookHosts      []string
+}
+
+// loadConfig reads -config, if set, and applies it to the flags not in explicit,
+// those set on the command line, and to settings. Those flags that the
+// configuration doesn't set go back to their defaults. At startup, fixed is true,
+// and settings that can't change once the server is running are applied too.
+func loadConfig(fixed bool, explicit map[string]bool) error {
+	var cfg serverConfig
+	if *configFile != "" {
+		b, err := ioutil.ReadFile(*configFile)
+		if err != nil {
+			return err
+		}
+		if err := yaml.UnmarshalStrict(b, &cfg); err != nil {
+			return fmt.Errorf("parsing %s: %v", *configFile, err)
+		}
+	}
+
+	vals := map[string]string{
+		"access_token_file":    cfg.AccessTokenFile,
+		"app_id":               cfg.AppID,
+		"app_installation_id":  cfg.AppInstallation,
+		"app_key_file":         cfg.AppKeyFile,
+		"write_quota_per_user": cfg.WriteQuota.PerUser,
+		"write_quota":          cfg.WriteQuota.Global,
+		"max_watches":          cfg.Watch.Max,
+		"max_ignored":          cfg.Ignore.Max,
+		"org_queue":            cfg.Org.Queue,
+		"revert_branch":        cfg.Revert.Branch,
+	}
+	if fixed {
+		vals["rev"] = cfg.Rev
+		vals["data_dir"] = cfg.DataDir
+		vals["recheck_interval"] = cfg.RecheckInterval
+		vals["check_timeout"] = cfg.CheckTimeout
+		vals["check_deadline"] = cfg.CheckDeadline
+		vals["checker_timeout"] = cfg.CheckerTimeout
+		vals["page_size"] = cfg.PageSize
+		vals["templates"] = cfg.Templates
+		vals["messages"] = cfg.Messages
+		vals["smtp_addr"] = cfg.SMTPAddr
+		vals["smtp_from"] = cfg.SMTPFrom
+		vals["smtp_user"] = cfg.SMTPUser
+		vals["smtp_password_file"] = cfg.SMTPPasswordFile
+		vals["secret_key_file"] = cfg.SecretKeyFile
+		vals["http"] = cfg.HTTP
+		vals["https"] = cfg.HTTPS
+		vals["autocert_domains"] = strings.Join(cfg.AutocertDomains, ",")
+		vals["autocert_cache"] = cfg.AutocertCache
+		vals["tls_cert"] = cfg.TLSCert
+		vals["tls_key"] = cfg.TLSKey
+		vals["log_format"] = cfg.LogFormat
+	}
+	for name, v := range vals {
+		if explicit[name] {
+			continue
+		}
+		if v == "" {
+			v = flag.Lookup(name).DefValue
+		}
+		if err := flag.Set(name, v); err != nil {
+			return fmt.Errorf("setting %s from %s: %v", name, *configFile, err)
+		}
+	}
+
+	enabled, err := fixhub.ParseProblemTypes(strings.Join(cfg.Checks.Enable, ","))
+	if err != nil {
+		return fmt.Errorf("bad checks.enable in %s: %v", *configFile, err)
+	}
+	disabled, err := fixhub.ParseProblemTypes(strings.Join(cfg.Checks.Disable, ","))
+	if err != nil {
+		return fmt.Errorf("bad checks.disable in %s: %v", *configFile, err)
+	}
+	for _, e := range cfg.WriteQuota.Exempt {
+		if _, _, err := net.ParseCIDR(e); strings.Contains(e, "/") && err != nil {
+			return fmt.Errorf("bad write_quota.exempt entry %q in %s: %v", e, *configFile, err)
+		}
+	}
+	for _, e := range cfg.Admins {
+		if _, _, err := net.ParseCIDR(e); strings.Contains(e, "/") && err != nil {
+			return fmt.Errorf("bad admins entry %q in %s: %v", e, *configFile, err)
+		}
+	}
+	for _, e := range cfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(e); strings.Contains(e, "/") && err != nil {
+			return fmt.Errorf("bad trusted_proxies entry %q in %s: %v", e, *configFile, err)
+		}
+	}
+	pats := append(append(append(cfg.Allow, cfg.Deny...), cfg.Watch.Allow...), cfg.Revert.Allow...)
+	for pat, addrs := range cfg.Watch.Email {
+		pats = append(pats, pat)
+		for _, addr := range addrs {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return fmt.Errorf("bad email address %q in %s: %v", addr, *configFile, err)
+			}
+		}
+	}
+	for _, pat := range pats {
+		if _, err := path.Match(pat, ""); err != nil {
+			return fmt.Errorf("bad repository pattern %q in %s: %v", pat, *configFile, err)
+		}
+	}
+
+	tokens, err := tokenSource()
+	if err != nil {
+		return err
+	}
+
+	settings.Lock()
+	defer settings.Unlock()
+	settings.tokens = tokens
+	settings.allow, settings.deny = cfg.Allow, cfg.Deny
+	settings.enabled, settings.disabled = enabled, disabled
+	settings.org = orgOptions{archived: cfg.Org.IncludeArchived, forks: cfg.Org.IncludeForks}
+	settings.trustedProxies = cfg.TrustedProxies
+	settings.quotaExempt = cfg.WriteQuota.Exempt
+	settings.admins = cfg.Admins
+	settings.revertAllow = cfg.Revert.Allow
+	settings.watchAllow = cfg.Watch.Allow
+	settings.watchEmail = cfg.Watch.Email
+	settings.webhookHosts = cfg.Watch.WebhookHosts
+	if len(settings.webhookHosts) == 0 {
+		settings.webhookHosts = []string{"hooks.slack.com"}
+	}
+	return nil
+}
+
+// currentAccessToken returns the GitHub access token to use, if any.
+func currentAccessToken() string {
+	settings.RLock()
+	tokens := settings.tokens
+	settings.RUnlock()
+	if tokens == nil {
+		return ""
+	}
+	t, err := tokens.Token()
+	if err != nil {
+		logger.Error("getting GitHub access token", "err", err)
+		return ""
+	}
+	return t
+}
+
+// defaultClient returns a client with the configured default checks,
+// for showing their state in the UI.
+func defaultClient() *fixhub.Client {
+	settings.RLock()
+	defer settings.RUnlock()
+	return &fixhub.Client{Enabled: settings.enabled, Disabled: settings.disabled}
+}
+
+// reloadOnSIGHUP reloads -config whenever the process receives SIGHUP.
+// explicit is as for loadConfig.
+func reloadOnSIGHUP(explicit map[string]bool) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		if err := loadConfig(false, explicit); err != nil {
+			logger.Error("reloading configuration", "file", *configFile, "err", err)
+			continue
+		}
+		logger.Info("reloaded configuration", "file", *configFile)
+	}
+}
+
+// allowed reports whether fixhubd may check the given repository.
+func allowed(owner, repo string) bool {
+	settings.RLock()
+	defer settings.RUnlock()
+	if matchRepo(settings.deny, owner, repo) {
+		return false
+	}
+	return len(settings.allow) == 0 || matchRepo(settings.allow, owner, repo)
+}
+
+// watchable reports whether visitors may start or stop re-checking the given repository.
+func watchable(owner, repo string) bool {
+	if !allowed(owner, repo) {
+		return false
+	}
+	settings.RLock()
+	defer settings.RUnlock()
+	return matchRepo(settings.watchAllow, owner, repo)
+}
+
+// revertible reports whether visitors may revert fixes in the given repository.
+func revertible(owner, repo string) bool {
+	if *revertBranch == "" || !allowed(owner, repo) {
+		return false
+	}
+	settings.RLock()
+	defer settings.RUnlock()
+	return matchRepo(settings.revertAllow, owner, repo)
+}
+
+// emailFor returns the addresses to send notifications about the given repository to.
+func emailFor(owner, repo string) []string {
+	settings.RLock()
+	defer settings.RUnlock()
+	var to []string
+	seen := make(map[string]bool)
+	for pat, addrs := range settings.watchEmail {
+		if !matchRepo([]string{pat}, owner, repo) {
+			continue
+		}
+		for _, addr := range addrs {
+			if !seen[addr] {
+				seen[addr] = true
+				to = append(to, addr)
+			}
+		}
+	}
+	sort.Strings(to)
+	return to
+}
+
+// checkWebhook reports whether u may be posted notifications:
+// it must be an https URL on one of the configured webhook hosts.
+func checkWebhook(u string) error {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if pu.Scheme != "https" || pu.User != nil {
+		return fmt.Errorf("webhook URL must be https://host/...")
+	}
+	settings.RLock()
+	defer settings.RUnlock()
+	for _, h := range settings.webhookHosts {
+		if strings.EqualFold(pu.Host, h) {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhooks must be on %s", strings.Join(settings.webhookHosts, " or "))
+}
+
+func matchRepo(patterns []string, owner, repo string) bool {
+	for _, pat := range patterns {
+		name := owner
+		if strings.Contains(pat, "/") {
+			name += "/" + repo
+		}
+		if ok, _ := path.Match(pat, name); ok {
+			return true
+		}
+	}
+	return false
+}
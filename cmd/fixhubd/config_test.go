@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixhubd-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "fixhubd.yml")
+	defer func(old string) { *configFile = old }(*configFile)
+	*configFile = name
+	defer func(old string) { *tokenFlag = old }(*tokenFlag)
+	*tokenFlag = "token"
+
+	// -max_watches is set on the command line, so the configuration doesn't change it.
+	explicit := map[string]bool{"max_watches": true}
+	*maxWatches = 7
+
+	write := func(cfg string) {
+		if err := ioutil.WriteFile(name, []byte(cfg), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(when string, perUser, global, watches int, branch string) {
+		if *userWriteQuota != perUser || *globalWriteQuota != global || *maxWatches != watches || *revertBranch != branch {
+			t.Errorf("%s: write_quota_per_user, write_quota, max_watches, revert_branch = %d, %d, %d, %q; want %d, %d, %d, %q",
+				when, *userWriteQuota, *globalWriteQuota, *maxWatches, *revertBranch, perUser, global, watches, branch)
+		}
+	}
+
+	write("write_quota:\n  per_user: 5\n  global: 50\nwatch:\n  max: 20\nrevert:\n  branch: fixhub\n")
+	if err := loadConfig(true, explicit); err != nil {
+		t.Fatalf("loading: %v", err)
+	}
+	check("at startup", 5, 50, 7, "fixhub")
+
+	write("write_quota:\n  per_user: 3\nwatch:\n  max: 30\n")
+	if err := loadConfig(false, explicit); err != nil {
+		t.Fatalf("reloading: %v", err)
+	}
+	check("after reloading", 3, 100, 7, "")
+
+	write("write_quota:\n  per_user: lots\n")
+	if err := loadConfig(false, explicit); err == nil {
+		t.Errorf("reloading a bad write_quota.per_user succeeded")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+// csrfCookie holds a random token for each browser. Forms that change
+// anything include the token, and requests to make the change are refused
+// unless the form's token is the same as the cookie's. Another site's page
+// can make a browser submit a form to fixhubd, but it can't read the cookie
+// to know what token to put in it.
+const csrfCookie = "fixhub_csrf"
+
+// csrfToken returns the token that forms in the page responding to r must
+// include, setting the cookie if r's browser doesn't have one yet.
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(csrfCookie); err == nil && len(c.Value) == 43 {
+		return c.Value
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand: " + err.Error()) // can't happen
+	}
+	tok := base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    tok,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return tok
+}
+
+// checkCSRF reports whether r is a POST whose csrf form value is the token
+// in r's cookie. If it isn't, it responds with an error.
+func checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if c, err := r.Cookie(csrfCookie); err == nil && c.Value != "" && r.Method == "POST" &&
+		subtle.ConstantTimeCompare([]byte(c.Value), []byte(r.PostFormValue("csrf"))) == 1 {
+		return true
+	}
+	requestLogger(r).Warn("refusing request without a valid form token", "path", r.URL.Path)
+	errf(w, http.StatusForbidden, "the form was missing its token or has expired; reload the page and try again")
+	return false
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// serverVersion identifies this build of fixhubd, and so of its checkers,
+// for entity tags. Without VCS information, it is when the server started,
+// so that tags at least don't outlive a rebuild.
+var serverVersion = func() string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+		if v := bi.Main.Version; v != "" && v != "(devel)" {
+			return v
+		}
+	}
+	return start.Format(time.RFC3339Nano)
+}()
+
+// etag returns an entity tag for a response derived from the result of
+// checking the commit sha with this version of fixhubd,
+// and from parts, which are whatever else the response depends on.
+func etag(sha string, parts ...string) string {
+	h := sha1.New()
+	io.WriteString(h, serverVersion+"\x00"+sha)
+	for _, p := range parts {
+		io.WriteString(h, "\x00"+p)
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum(nil)[:12])
+}
+
+// notModified sets w's ETag header to tag and, if r's If-None-Match header
+// says that the client already has that version, responds that it is not modified.
+// It reports whether it did.
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+	for _, t := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == tag || t == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredState returns a string that changes whenever
+// the problems ignored in a repository do.
+func ignoredState(owner, repo string) string {
+	ignores.Lock()
+	var fps []string
+	for fp := range ignores.m[owner+"/"+repo] {
+		fps = append(fps, fp)
+	}
+	ignores.Unlock()
+	sort.Strings(fps)
+	return strings.Join(fps, ",")
+}
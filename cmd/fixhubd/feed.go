@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// Atom feed types; see RFC 4287.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedHandler serves an Atom feed of the problems found by the last check of a repository,
+// with an entry for the check and one for each problem it newly found.
+// The check is whichever was last recorded, by a visit or a scheduled re-check.
+func feedHandler(w http.ResponseWriter, r *http.Request, owner, repo string) {
+	if !persistent() {
+		errf(w, http.StatusNotFound, "feeds are not available here")
+		return
+	}
+	last, err := loadResult(owner, repo)
+	if err != nil {
+		requestLogger(r).Error("loading last result", "owner", owner, "repo", repo, "err", err)
+		errf(w, http.StatusInternalServerError, "loading last result failed")
+		return
+	}
+	if last == nil {
+		errf(w, http.StatusNotFound, "%s/%s has not been checked yet", owner, repo)
+		return
+	}
+
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	page := scheme + "://" + r.Host + "/github.com/" + owner + "/" + repo
+	commit := fmt.Sprintf("https://github.com/%s/%s/commit/%s", owner, repo, last.SHA)
+	updated := last.Time.UTC().Format(time.RFC3339)
+	id := func(suffix string) string {
+		return "tag:fixhub:" + owner + "/" + repo + "/" + suffix
+	}
+
+	var added, resolved fixhub.Problems
+	if last.Prev != nil {
+		added, resolved = fixhub.Diff(last.Prev, last.Problems)
+	}
+	summary := new(bytes.Buffer)
+	fmt.Fprintf(summary, "%d problems", len(last.Problems))
+	if last.Prev != nil {
+		fmt.Fprintf(summary, " (%d new, %d resolved since the previous commit checked)", len(added), len(resolved))
+	}
+	summary.WriteString(":\n\n")
+	for _, p := range last.Problems {
+		fmt.Fprintf(summary, "%s\n", p)
+	}
+
+	feed := atomFeed{
+		ID:      "tag:fixhub:" + owner + "/" + repo,
+		Title:   fmt.Sprintf("fixhub: problems in %s/%s", owner, repo),
+		Updated: updated,
+		Link: []atomLink{
+			{Href: page},
+			{Rel: "self", Href: page + "/feed.atom"},
+		},
+		Author: atomAuthor{Name: "fixhub"},
+		Entries: []atomEntry{{
+			ID:      id(last.SHA),
+			Title:   fmt.Sprintf("Checked %.7s: %d problems", last.SHA, len(last.Problems)),
+			Updated: updated,
+			Link:    atomLink{Href: commit},
+			Content: atomContent{Type: "text", Body: summary.String()},
+		}},
+	}
+	for _, p := range added {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      id(last.SHA + "/" + p.Fingerprint()),
+			Title:   fmt.Sprintf("New %s problem in %s", p.Type, p.File),
+			Updated: updated,
+			Link:    atomLink{Href: fmt.Sprintf("https://github.com/%s/%s/blob/%s/%s", owner, repo, last.SHA, p.File)},
+			Content: atomContent{Type: "text", Body: p.String()},
+		})
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "\t")
+	if err := enc.Encode(feed); err != nil {
+		errf(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	io.Copy(w, buf)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// Filter selects and orders the problems shown on a results page.
+// It is set by the query parameters "type", "file", "fixable" and "sort",
+// so a filtered page can be shared by its URL, or linked to directly,
+// e.g. /github.com/owner/repo?type=gofmt,vet&fixable=1.
+// The type parameter may be repeated or comma-separated.
+type Filter struct {
+	Types   []fixhub.ProblemType // if non-empty, only these types
+	File    string               // if non-empty, only files with this path prefix
+	Fixable bool                 // only problems that Fix can fix
+	Sort    string               // "file" (the default) or "type"
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	f := Filter{
+		File: strings.TrimPrefix(r.FormValue("file"), "/"),
+		Sort: r.FormValue("sort"),
+	}
+	var err error
+	if v := r.FormValue("fixable"); v != "" {
+		if f.Fixable, err = strconv.ParseBool(v); err != nil {
+			return Filter{}, fmt.Errorf("bad fixable parameter %q", v)
+		}
+	}
+	if f.Types, err = fixhub.ParseProblemTypes(strings.Join(r.Form["type"], ",")); err != nil {
+		return Filter{}, fmt.Errorf("bad type parameter: %v", err)
+	}
+	switch f.Sort {
+	case "":
+		f.Sort = "file"
+	case "file", "type":
+	default:
+		return Filter{}, fmt.Errorf("bad sort parameter %q", f.Sort)
+	}
+	return f, nil
+}
+
+// Active reports whether f hides any problems.
+func (f Filter) Active() bool {
+	return len(f.Types) > 0 || f.File != "" || f.Fixable
+}
+
+// HasType reports whether f selects problems of type t.
+func (f Filter) HasType(t fixhub.ProblemType) bool {
+	for _, ft := range f.Types {
+		if ft == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) apply(ps fixhub.Problems) fixhub.Problems {
+	var out fixhub.Problems
+	for _, p := range ps {
+		if len(f.Types) > 0 && !f.HasType(p.Type) {
+			continue
+		}
+		if f.File != "" && !strings.HasPrefix(p.File, f.File) {
+			continue
+		}
+		if f.Fixable && !p.Fixable {
+			continue
+		}
+		out = append(out, p)
+	}
+	// Problems come sorted by file.
+	if f.Sort == "type" {
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	}
+	return out
+}
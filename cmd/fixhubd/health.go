@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+	"github.com/dsymonds/fixhub/auth"
+)
+
+const githubAPI = "https://api.github.com/"
+
+// healthzHandler reports that the process is up.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "ok\n")
+}
+
+var readiness struct {
+	sync.Mutex
+	checked  time.Time
+	checking bool // whether a probe is finding out again
+	err      error
+}
+
+// readyzHandler reports whether the GitHub API is reachable
+// and, if there is an access token, that it is accepted, and that
+// the datastore in -data_dir, if any, is available.
+// The result is cached for a short time so that frequent probes
+// don't hammer GitHub; while one probe finds out again, others
+// get the previous result rather than wait.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	readiness.Lock()
+	stale := time.Since(readiness.checked) > 30*time.Second && !readiness.checking
+	readiness.checking = readiness.checking || stale
+	err, checked := readiness.err, readiness.checked
+	readiness.Unlock()
+
+	if stale {
+		if err = checkDataDir(); err == nil {
+			err = checkGitHub()
+		}
+		readiness.Lock()
+		readiness.err, readiness.checked, readiness.checking = err, time.Now(), false
+		readiness.Unlock()
+	} else if checked.IsZero() {
+		err = errors.New("readiness is still being checked")
+	}
+
+	if err != nil {
+		requestLogger(r).Warn("not ready", "err", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(w, "ok\n")
+}
+
+// checkDataDir checks that files can be written in -data_dir, if it is set.
+func checkDataDir() error {
+	if !persistent() {
+		return nil
+	}
+	f, err := ioutil.TempFile(*dataDir, ".readyz-")
+	if err != nil {
+		return fmt.Errorf("datastore unavailable: %v", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("ok\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("datastore unavailable: %v", err)
+	}
+	return nil
+}
+
+// checkToken logs a warning if the access token can do much more than fixhubd needs,
+// or will soon expire.
+func checkToken() {
+	t := currentAccessToken()
+	if t == "" {
+		return
+	}
+	ti, err := auth.Inspect(fixhub.NewHTTPClient(nil, ""), githubAPI, t)
+	if err != nil {
+		logger.Warn("checking access token", "err", err)
+		return
+	}
+	if advice := ti.Advice(); advice != "" {
+		logger.Warn(advice, "kind", ti.Kind, "scopes", ti.Scopes)
+	}
+}
+
+// checkGitHub checks that the GitHub API is reachable and accepts the access token.
+// It uses the rate limit endpoint, which doesn't count against the rate limit.
+func checkGitHub() error {
+	req, err := http.NewRequest("GET", githubAPI+"rate_limit", nil)
+	if err != nil {
+		return err
+	}
+	client := fixhub.NewHTTPClient(nil, currentAccessToken())
+	client.Timeout = 10 * time.Second
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("reaching GitHub: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return fmt.Errorf("GitHub rejected the access token")
+	}
+	return fmt.Errorf("GitHub API returned %s", resp.Status)
+}
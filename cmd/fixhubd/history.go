@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+const (
+	historyFile = "history.json"
+	maxHistory  = 500 // per repository
+)
+
+// A checkSummary records the outcome of one check of a whole repository.
+type checkSummary struct {
+	SHA    string                     `json:"sha"`
+	Time   time.Time                  `json:"time"`
+	Counts map[fixhub.ProblemType]int `json:"counts"`
+}
+
+func (cs checkSummary) Total() int {
+	n := 0
+	for _, c := range cs.Counts {
+		n += c
+	}
+	return n
+}
+
+var history struct {
+	sync.Mutex
+	m map[string][]checkSummary // "owner/repo" -> summaries, oldest first
+}
+
+func loadHistory() error {
+	history.Lock()
+	defer history.Unlock()
+	history.m = make(map[string][]checkSummary)
+	return loadJSON(historyFile, &history.m)
+}
+
+// recordCheck adds a summary of a check to the history of a repository.
+// A re-check of the same commit replaces the earlier summary.
+func recordCheck(owner, repo, sha string, ps fixhub.Problems) error {
+	cs := checkSummary{SHA: sha, Time: time.Now(), Counts: make(map[fixhub.ProblemType]int)}
+	for _, p := range ps {
+		cs.Counts[p.Type]++
+	}
+
+	history.Lock()
+	defer history.Unlock()
+	key := owner + "/" + repo
+	h := history.m[key]
+	if n := len(h); n > 0 && h[n-1].SHA == sha {
+		h = h[:n-1]
+	}
+	h = append(h, cs)
+	if len(h) > maxHistory {
+		h = h[len(h)-maxHistory:]
+	}
+	history.m[key] = h
+	return saveJSON(historyFile, history.m)
+}
+
+// historyHandler serves the page showing how a repository's problem counts have changed.
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/history/"), "/", 3)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		errf(w, http.StatusBadRequest, "not a valid github owner/repo: %v", parts)
+		return
+	}
+	history.Lock()
+	h := append([]checkSummary(nil), history.m[parts[0]+"/"+parts[1]]...)
+	history.Unlock()
+
+	types := make(map[fixhub.ProblemType]bool)
+	for _, cs := range h {
+		for t := range cs.Counts {
+			types[t] = true
+		}
+	}
+	data := struct {
+		Owner, Repo string
+		History     []checkSummary
+		Types       []fixhub.ProblemType
+		Chart       template.HTML
+	}{Owner: parts[0], Repo: parts[1], History: h, Chart: trendChart(h)}
+	for t := range types {
+		data.Types = append(data.Types, t)
+	}
+	sort.Slice(data.Types, func(i, j int) bool { return data.Types[i] < data.Types[j] })
+
+	buf := new(bytes.Buffer)
+	if err := historyTmpl.Execute(buf, data); err != nil {
+		errf(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	io.Copy(w, buf)
+}
+
+// trendChart returns an SVG line chart of the total problem count over time.
+func trendChart(h []checkSummary) template.HTML {
+	if len(h) < 2 {
+		return ""
+	}
+	const width, height, pad = 600, 200, 20
+	t0, t1 := h[0].Time, h[len(h)-1].Time
+	span := t1.Sub(t0).Seconds()
+	max := 1
+	for _, cs := range h {
+		if n := cs.Total(); n > max {
+			max = n
+		}
+	}
+	var points []string
+	for i, cs := range h {
+		// Spread the points by time, or evenly if they're all at once.
+		frac := float64(i) / float64(len(h)-1)
+		if span > 0 {
+			frac = cs.Time.Sub(t0).Seconds() / span
+		}
+		x := pad + frac*(width-2*pad)
+		y := height - pad - float64(cs.Total())/float64(max)*(height-2*pad)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return template.HTML(fmt.Sprintf(`<svg width="%d" height="%d" viewBox="0 0 %d %d">
+<text x="0" y="%d" font-size="10">%d</text>
+<text x="0" y="%d" font-size="10">0</text>
+<polyline fill="none" stroke="steelblue" stroke-width="2" points="%s"/>
+</svg>`, width, height, width, height, pad, max, height-pad, strings.Join(points, " ")))
+}
+
+var historyTmpl = template.Must(template.New("history.html").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>fixhub: history of {{.Owner}}/{{.Repo}}</title>
+<link rel="stylesheet" type="text/css" href="{{asset "style.css"}}">
+</head>
+<body>
+<div id="header">
+Problems over time in <a href="/github.com/{{.Owner}}/{{.Repo}}">{{.Owner}}/{{.Repo}}</a>
+</div>
+
+{{if .History}}
+{{.Chart}}
+<table>
+<tr><th>Checked</th><th>Commit</th><th>Total</th>{{range .Types}}<th>{{.}}</th>{{end}}</tr>
+{{range .History}}
+<tr>
+<td>{{.Time.Format "2006-01-02 15:04"}}</td>
+<td><a href="https://github.com/{{$.Owner}}/{{$.Repo}}/commit/{{.SHA}}">{{printf "%.7s" .SHA}}</a></td>
+<td>{{.Total}}</td>
+{{$cs := .}}{{range $.Types}}<td>{{index $cs.Counts .}}</td>{{end}}
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>{{.Owner}}/{{.Repo}} has not been checked yet.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var messagesDir = flag.String("messages", "", "a directory of message catalogs for translating pages, each a JSON object mapping English messages to translations, named for its language (e.g. de.json or pt-br.json)")
+
+// Messages are in English unless a catalog for a language the request prefers,
+// by its Accept-Language header, has a translation of them.
+// A catalog is a JSON object that maps English messages to translations,
+// which must have the same formatting verbs in the same order:
+//
+//	{
+//		"can't revert commits here": "Commits können hier nicht rückgängig gemacht werden",
+//		"Revert %s on %s of %s": "%[1]s auf %[2]s von %[3]s rückgängig machen"
+//	}
+//
+// Error messages and the confirmation pages of changes to repositories
+// are translated; the rest of the pages are not yet.
+var catalogs = make(map[string]map[string]string) // language -> English -> translation
+
+// loadCatalogs loads the catalogs in -messages, if set.
+func loadCatalogs() error {
+	if *messagesDir == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(*messagesDir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var cat map[string]string
+		if err := json.Unmarshal(b, &cat); err != nil {
+			return fmt.Errorf("parsing %s: %v", file, err)
+		}
+		lang := strings.ToLower(strings.TrimSuffix(filepath.Base(file), ".json"))
+		catalogs[lang] = cat
+		logger.Info("loaded message catalog", "lang", lang, "messages", len(cat))
+	}
+	return nil
+}
+
+// language returns the language of the catalog that r prefers,
+// or "" if it prefers English or there is no catalog it accepts.
+func language(r *http.Request) string {
+	if len(catalogs) == 0 {
+		return ""
+	}
+	type accept struct {
+		tag string
+		q   float64
+	}
+	var accepts []accept
+	for _, s := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		parts := strings.Split(s, ";")
+		a := accept{strings.ToLower(strings.TrimSpace(parts[0])), 1}
+		for _, p := range parts[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+				q, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					q = 0
+				}
+				a.q = q
+			}
+		}
+		if a.tag != "" && a.q > 0 {
+			accepts = append(accepts, a)
+		}
+	}
+	sort.SliceStable(accepts, func(i, j int) bool { return accepts[i].q > accepts[j].q })
+	for _, a := range accepts {
+		base := strings.SplitN(a.tag, "-", 2)[0]
+		switch {
+		case catalogs[a.tag] != nil:
+			return a.tag
+		case catalogs[base] != nil:
+			return base
+		case base == "en" || a.tag == "*":
+			return ""
+		}
+	}
+	return ""
+}
+
+// msg translates the English message format into lang,
+// then formats it with args as for fmt.Sprintf.
+func msg(lang, format string, args ...interface{}) string {
+	if t, ok := catalogs[lang][format]; ok && t != "" {
+		format = t
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// msgHTML is like msg, but for templates: the result is HTML,
+// and args that are HTML, such as from link, are not escaped.
+func msgHTML(lang, format string, args ...interface{}) template.HTML {
+	if t, ok := catalogs[lang][format]; ok && t != "" {
+		format = t
+	}
+	for i, a := range args {
+		if h, ok := a.(template.HTML); ok {
+			args[i] = string(h)
+		} else {
+			args[i] = template.HTMLEscapeString(fmt.Sprint(a))
+		}
+	}
+	return template.HTML(fmt.Sprintf(template.HTMLEscapeString(format), args...))
+}
+
+// link returns an HTML link to url with the given text, for msgHTML.
+func link(url, text string) template.HTML {
+	return template.HTML(`<a href="` + template.HTMLEscapeString(url) + `">` + template.HTMLEscapeString(text) + `</a>`)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+const ignoredFile = "ignored.json"
+
+var maxIgnored = flag.Int("max_ignored", 1000, "how many problems may be ignored in each repository")
+
+// An ignored problem is one that a user asked not to be shown again.
+// Problems are matched by fingerprint, so moving one doesn't bring it back.
+type ignored struct {
+	File     string             `json:"file"`
+	Type     fixhub.ProblemType `json:"type"`
+	Code     string             `json:"code,omitempty"`
+	Text     string             `json:"text"`
+	LineText string             `json:"line_text,omitempty"`
+	Time     time.Time          `json:"time"` // when it was ignored
+}
+
+var ignores struct {
+	sync.Mutex
+	m map[string]map[string]ignored // "owner/repo" -> fingerprint -> ignored problem
+}
+
+func loadIgnored() error {
+	ignores.Lock()
+	defer ignores.Unlock()
+	ignores.m = make(map[string]map[string]ignored)
+	return loadJSON(ignoredFile, &ignores.m)
+}
+
+// filterIgnored removes the ignored problems of a repository from ps,
+// returning the rest and the number removed.
+func filterIgnored(owner, repo string, ps fixhub.Problems) (fixhub.Problems, int) {
+	ignores.Lock()
+	defer ignores.Unlock()
+	m := ignores.m[owner+"/"+repo]
+	var kept fixhub.Problems
+	for _, p := range ps {
+		if _, ok := m[p.Fingerprint()]; ok {
+			continue
+		}
+		// Problems ignored before fingerprints used the line's text
+		// are keyed by the fingerprint of just the file, type and text.
+		legacy := fixhub.Problem{File: p.File, Type: p.Type, Text: p.Text}
+		if _, ok := m[legacy.Fingerprint()]; ok {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept, len(ps) - len(kept)
+}
+
+// renameIgnored moves the ignored problems of a repository that are in renamed files,
+// as from Client.Renames, to their new paths, so that they stay ignored.
+func renameIgnored(owner, repo string, renames map[string]string) error {
+	ignores.Lock()
+	defer ignores.Unlock()
+	m := ignores.m[owner+"/"+repo]
+	moved := make(map[string]ignored)
+	for fp, ig := range m {
+		if to, ok := renames[ig.File]; ok {
+			delete(m, fp)
+			ig.File = to
+			moved[ig.problem().Fingerprint()] = ig
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	for fp, ig := range moved {
+		m[fp] = ig
+	}
+	return saveJSON(ignoredFile, ignores.m)
+}
+
+// problem returns the problem that ig records, with the same fingerprint.
+func (ig ignored) problem() fixhub.Problem {
+	return fixhub.Problem{File: ig.File, Type: ig.Type, Code: ig.Code, Text: ig.Text, LineText: ig.LineText}
+}
+
+// ignoreHandler records a problem as ignored, then returns to the problems page.
+func ignoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !persistent() {
+		errf(w, http.StatusMethodNotAllowed, "can't ignore problems here")
+		return
+	}
+	if !checkCSRF(w, r) {
+		return
+	}
+	owner, repo, ok := ignoreRepo(w, r)
+	if !ok {
+		return
+	}
+	p := fixhub.Problem{
+		File:     r.FormValue("file"),
+		Type:     fixhub.ProblemType(r.FormValue("type")),
+		Code:     r.FormValue("code"),
+		Text:     r.FormValue("text"),
+		LineText: r.FormValue("line_text"),
+	}
+	if p.File == "" {
+		errf(w, http.StatusBadRequest, "missing file")
+		return
+	}
+
+	ignores.Lock()
+	key := owner + "/" + repo
+	if ignores.m[key] == nil {
+		ignores.m[key] = make(map[string]ignored)
+	}
+	fp := p.Fingerprint()
+	if _, ok := ignores.m[key][fp]; !ok && len(ignores.m[key]) >= *maxIgnored {
+		ignores.Unlock()
+		errf(w, http.StatusServiceUnavailable, "no more problems can be ignored in %s; unignore some first", key)
+		return
+	}
+	ignores.m[key][fp] = ignored{File: p.File, Type: p.Type, Code: p.Code, Text: p.Text, LineText: p.LineText, Time: time.Now()}
+	err := saveJSON(ignoredFile, ignores.m)
+	ignores.Unlock()
+	if err != nil {
+		requestLogger(r).Error("saving ignored problems", "err", err)
+		errf(w, http.StatusInternalServerError, "saving ignored problems failed")
+		return
+	}
+	http.Redirect(w, r, returnPath(r, "/github.com/"+key), http.StatusSeeOther)
+}
+
+// unignoreHandler forgets that a problem was ignored.
+func unignoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !persistent() {
+		errf(w, http.StatusMethodNotAllowed, "can't unignore problems here")
+		return
+	}
+	if !checkCSRF(w, r) {
+		return
+	}
+	owner, repo, ok := ignoreRepo(w, r)
+	if !ok {
+		return
+	}
+	key := owner + "/" + repo
+
+	ignores.Lock()
+	delete(ignores.m[key], r.FormValue("fingerprint"))
+	if len(ignores.m[key]) == 0 {
+		delete(ignores.m, key)
+	}
+	err := saveJSON(ignoredFile, ignores.m)
+	ignores.Unlock()
+	if err != nil {
+		requestLogger(r).Error("saving ignored problems", "err", err)
+		errf(w, http.StatusInternalServerError, "saving ignored problems failed")
+		return
+	}
+	http.Redirect(w, r, "/ignored/"+key, http.StatusSeeOther)
+}
+
+// ignoreRepo returns the repository named by the owner and repo form values,
+// if it is one whose problems may be ignored here.
+// Otherwise it replies with an error.
+func ignoreRepo(w http.ResponseWriter, r *http.Request) (owner, repo string, ok bool) {
+	owner, repo = r.FormValue("owner"), r.FormValue("repo")
+	rp, err := fixhub.ParseRepoPath(owner + "/" + repo)
+	if err == nil && (rp.Owner != owner || rp.Repo != repo || rp.Rev != "" || rp.Dir != "") {
+		err = fmt.Errorf("%q is not a valid repository", owner+"/"+repo)
+	}
+	if err != nil {
+		errf(w, http.StatusBadRequest, "%v", err)
+		return "", "", false
+	}
+	if !allowed(owner, repo) {
+		errf(w, http.StatusForbidden, "ignoring problems in %s/%s is not permitted here", owner, repo)
+		return "", "", false
+	}
+	return owner, repo, true
+}
+
+// returnPath returns the local path named by the "return" form value, or def.
+func returnPath(r *http.Request, def string) string {
+	ret := r.FormValue("return")
+	if !strings.HasPrefix(ret, "/") || strings.HasPrefix(ret, "//") {
+		return def // only go back to our own pages
+	}
+	return ret
+}
+
+// ignoredHandler serves the page listing a repository's ignored problems.
+func ignoredHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/ignored/"), "/", 3)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		errf(w, http.StatusBadRequest, "not a valid github owner/repo: %v", parts)
+		return
+	}
+	data := struct {
+		Owner, Repo string
+		Ignored     []ignoredEntry
+		CSRF        string
+	}{Owner: parts[0], Repo: parts[1], CSRF: csrfToken(w, r)}
+
+	ignores.Lock()
+	for fp, ig := range ignores.m[parts[0]+"/"+parts[1]] {
+		data.Ignored = append(data.Ignored, ignoredEntry{fp, ig})
+	}
+	ignores.Unlock()
+	sort.Slice(data.Ignored, func(i, j int) bool {
+		a, b := data.Ignored[i], data.Ignored[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Text < b.Text
+	})
+
+	buf := new(bytes.Buffer)
+	if err := ignoredTmpl.Execute(buf, data); err != nil {
+		errf(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	io.Copy(w, buf)
+}
+
+type ignoredEntry struct {
+	Fingerprint string
+	ignored
+}
+
+var ignoredTmpl = template.Must(template.New("ignored.html").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>fixhub: ignored problems in {{.Owner}}/{{.Repo}}</title>
+<link rel="stylesheet" type="text/css" href="{{asset "style.css"}}">
+</head>
+<body>
+<div id="header">
+Ignored problems in <a href="/github.com/{{.Owner}}/{{.Repo}}">{{.Owner}}/{{.Repo}}</a>
+</div>
+
+{{if .Ignored}}
+<ul>
+{{range .Ignored}}
+<li>{{.File}}: {{.Text}} <small>({{.Type}}, ignored {{.Time.Format "2006-01-02"}})</small>
+<form method="POST" action="/unignore" class="inline">
+<input type="hidden" name="owner" value="{{$.Owner}}">
+<input type="hidden" name="repo" value="{{$.Repo}}">
+<input type="hidden" name="fingerprint" value="{{.Fingerprint}}">
+<input type="hidden" name="csrf" value="{{$.CSRF}}">
+<input type="submit" value="unignore">
+</form>
+</li>
+{{end}}
+</ul>
+{{else}}
+<p>No problems are ignored.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestIgnoreRepo(t *testing.T) {
+	settings.Lock()
+	settings.allow, settings.deny = []string{"dsymonds"}, []string{"dsymonds/secret"}
+	settings.Unlock()
+	defer func() {
+		settings.Lock()
+		settings.allow, settings.deny = nil, nil
+		settings.Unlock()
+	}()
+
+	tests := []struct {
+		owner, repo string
+		code        int // 0 if allowed
+	}{
+		{"dsymonds", "fixhub", 0},
+		{"dsymonds", "fixhub.git", http.StatusBadRequest},
+		{"dsymonds", "fixhub/sub", http.StatusBadRequest},
+		{"dsymonds", "fixhub@v1", http.StatusBadRequest},
+		{"dsymonds", "", http.StatusBadRequest},
+		{"-dsymonds", "fixhub", http.StatusBadRequest},
+		{"../..", "x", http.StatusBadRequest},
+		{"dsymonds", "secret", http.StatusForbidden},
+		{"golang", "go", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		form := url.Values{"owner": {tt.owner}, "repo": {tt.repo}}
+		r := httptest.NewRequest("POST", "/ignore", nil)
+		r.Form = form
+		w := httptest.NewRecorder()
+		owner, repo, ok := ignoreRepo(w, r)
+		if tt.code == 0 {
+			if !ok || owner != tt.owner || repo != tt.repo {
+				t.Errorf("ignoreRepo(%q, %q) = %q, %q, %v; want it accepted", tt.owner, tt.repo, owner, repo, ok)
+			}
+			continue
+		}
+		if ok || w.Code != tt.code {
+			t.Errorf("ignoreRepo(%q, %q) = %v with status %d, want status %d", tt.owner, tt.repo, ok, w.Code, tt.code)
+		}
+	}
+}
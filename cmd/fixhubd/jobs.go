@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+var (
+	checkTimeout   = flag.Duration("check_timeout", time.Minute, "how long a request waits for a check before being asked to come back later")
+	checkDeadline  = flag.Duration("check_deadline", 10*time.Minute, "if positive, how long a check spends on files, even after requests stop waiting for it, before skipping the rest")
+	checkerTimeout = flag.Duration("checker_timeout", time.Minute, "if positive, how long each checker may spend on a file or package before it is abandoned")
+)
+
+// limit applies the limits on how long checks may take to client.
+func limit(client *fixhub.Client) {
+	client.Timeout = *checkDeadline
+	client.CheckerTimeout = *checkerTimeout
+}
+
+// jobReuse is how long after a check finishes that a request for the same revision
+// gets its result rather than starting another check.
+const jobReuse = time.Minute
+
+// A job is a check being run on behalf of one or more requests.
+// It runs to completion even if the requests give up waiting,
+// so that a later request can collect its result.
+type job struct {
+	done chan struct{} // closed when finished
+
+	// Set when done is closed.
+	cr       *cachedResult
+	err      error
+	finished time.Time
+}
+
+var jobs struct {
+	sync.Mutex
+	m map[resultKey]*job // key has the requested revision in place of the SHA-1
+}
+
+// startCheck returns the job checking the revision named in key,
+// starting one if there is none in progress or recently finished.
+// The check itself is done by run. If admit is not nil, a new job is
+// only started if admit returns true; otherwise startCheck returns nil.
+func startCheck(key resultKey, l *slog.Logger, admit func() bool, run func() (*cachedResult, error)) *job {
+	jobs.Lock()
+	defer jobs.Unlock()
+	if jobs.m == nil {
+		jobs.m = make(map[resultKey]*job)
+	}
+	for k, j := range jobs.m {
+		select {
+		case <-j.done:
+			if time.Since(j.finished) > jobReuse {
+				delete(jobs.m, k)
+			}
+		default:
+		}
+	}
+	if j, ok := jobs.m[key]; ok {
+		select {
+		case <-j.done:
+			if j.err == nil {
+				return j
+			}
+			// Try again after a failure.
+		default:
+			return j
+		}
+	}
+
+	if admit != nil && !admit() {
+		return nil
+	}
+	j := &job{done: make(chan struct{})}
+	jobs.m[key] = j
+	go func() {
+		defer close(j.done)
+		defer func() { j.finished = time.Now() }()
+		j.cr, j.err = run()
+		if j.err != nil {
+			l.Error("check failed", "rev", key.sha, "err", j.err)
+		}
+	}()
+	return j
+}
+
+// checkRepo checks a repository at a revision with client, recording the result
+// in the history if record is set, and caching it.
+func checkRepo(client *fixhub.Client, key resultKey, record bool, l *slog.Logger) (*cachedResult, error) {
+	t0 := time.Now()
+	res, err := client.Run(key.sha)
+	if err != nil {
+		return nil, err
+	}
+	l.Info("checked", "rev", key.sha, "sha1", res.SHA, "dir", key.dir, "problems", len(res.Problems), "duration", time.Since(t0))
+	cr := &cachedResult{res: res, checked: time.Now()}
+	if record {
+		if cr.added, err = recordResult(client, l, key.owner, key.repo, res.SHA, res.Problems); err != nil {
+			l.Error("recording check result", "err", err)
+		}
+	}
+	key.sha = res.SHA
+	cacheResult(key, cr)
+	return cr, nil
+}
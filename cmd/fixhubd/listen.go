@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd socket activation.
+const listenFdsStart = 3
+
+// activated returns the sockets passed by systemd socket activation, if any.
+// See sd_listen_fds(3).
+func activated() []*os.File {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	var fs []*os.File
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		fs = append(fs, os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd)))
+	}
+	return fs
+}
+
+var activatedFiles = activated()
+
+// listen returns a listener for a service address, which is either
+// a TCP address, or "unix:" followed by the path of a Unix domain socket.
+// Under systemd socket activation the passed sockets are used instead,
+// in order: the first for -http and the second for -https.
+func listen(addr string, index int) (net.Listener, error) {
+	if len(activatedFiles) > 0 {
+		if index >= len(activatedFiles) {
+			return nil, fmt.Errorf("systemd passed %d sockets, but %d are needed", len(activatedFiles), index+1)
+		}
+		return net.FileListener(activatedFiles[index])
+	}
+	if path := strings.TrimPrefix(addr, "unix:"); path != addr {
+		// Remove a socket left by an earlier run.
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
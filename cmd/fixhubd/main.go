@@ -3,31 +3,40 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"io"
-	"io/ioutil"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dsymonds/fixhub"
+	"github.com/dsymonds/fixhub/auth"
 )
 
 var (
 	accessTokenFile = flag.String("access_token_file", filepath.Join(os.Getenv("HOME"), ".fixhub-token"), "a file containing a GitHub access token")
-	rev             = flag.String("rev", "master", "revision of the repo to check")
-	httpAddr        = flag.String("http", ":6061", "HTTP service address")
+	tokenFlag       = flag.String("token", "", "a GitHub access token; overrides $GITHUB_TOKEN and -access_token_file")
+	appID           = flag.Int64("app_id", 0, "the ID of a GitHub App to act as instead of using an access token; needs -app_installation_id and -app_key_file")
+	appInstallation = flag.Int64("app_installation_id", 0, "the ID of the GitHub App's installation")
+	appKeyFile      = flag.String("app_key_file", "", "a file containing the GitHub App's private key, in PEM")
+	rev             = flag.String("rev", "", "revision of the repo to check; the repo's default branch if empty")
+	httpAddr        = flag.String("http", ":6061", "HTTP service address, or unix:/path/to/socket")
+	logFormat       = flag.String("log_format", "text", "format of log output (text or json)")
 )
 
 var (
-	accessToken = ""
-	start       = time.Now()
+	start  = time.Now()
+	logger *slog.Logger
 )
 
 func main() {
@@ -37,73 +46,460 @@ func main() {
 		os.Exit(2)
 	}
 	flag.Parse()
+	// The transport is set up first, for the GitHub App token requests that the configuration may need.
+	if err := setupTransport(); err != nil {
+		log.Fatalf("Setting up outbound requests: %v", err)
+	}
+	// loadConfig sets flags too, so those set on the command line are noted first.
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if err := loadConfig(true, explicit); err != nil {
+		log.Fatalf("Loading configuration: %v", err)
+	}
 
-	if pat, err := ioutil.ReadFile(*accessTokenFile); err == nil {
-		// security check
-		fi, err := os.Stat(*accessTokenFile)
-		if err != nil {
-			log.Fatalf("os.Stat(%q): %v", *accessTokenFile, err)
-		}
-		if fi.Mode()&0077 != 0 { // check that no group/world perm bits are set
-			log.Fatalf("%s is too accessible; run `chmod go= %s` to fix", *accessTokenFile, *accessTokenFile)
-		}
-		accessToken = string(bytes.TrimSpace(pat))
+	switch *logFormat {
+	case "text":
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	default:
+		log.Fatalf("Bad -log_format %q", *logFormat)
 	}
+	checkToken()
 
-	mainTextBuf := new(bytes.Buffer)
-	if err := problemsTmpl.Execute(mainTextBuf, Data{}); err != nil {
-		log.Fatal(err)
+	if err := loadTemplates(); err != nil {
+		log.Fatalf("Loading templates: %v", err)
+	}
+	if err := loadAssets(); err != nil {
+		log.Fatalf("Loading static files: %v", err)
 	}
+	if err := loadCatalogs(); err != nil {
+		log.Fatalf("Loading message catalogs: %v", err)
+	}
+	if err := loadSecretKeys(); err != nil {
+		log.Fatalf("Loading secret keys: %v", err)
+	}
+	if persistent() {
+		if err := loadIgnored(); err != nil {
+			log.Fatalf("Loading ignored problems: %v", err)
+		}
+		if err := loadHistory(); err != nil {
+			log.Fatalf("Loading check history: %v", err)
+		}
+		if err := loadWatched(); err != nil {
+			log.Fatalf("Loading watched repositories: %v", err)
+		}
+		go recheckWatched()
+	}
+	go reloadOnSIGHUP(explicit)
 
 	http.HandleFunc("/github.com/", fixhubHandler)
-	staticHandler("/style.css", styleText)
-	staticHandler("/script.js", scriptText)
-	staticHandler("/", mainTextBuf.String())
-	log.Fatal(http.ListenAndServe(*httpAddr, nil))
+	http.HandleFunc("/go", goHandler)
+	http.HandleFunc("/ignore", ignoreHandler)
+	http.HandleFunc("/unignore", unignoreHandler)
+	http.HandleFunc("/ignored/", ignoredHandler)
+	http.HandleFunc("/history/", historyHandler)
+	http.HandleFunc("/revert", revertHandler)
+	http.HandleFunc("/admin/audit", auditHandler)
+	http.HandleFunc("/org/github.com/", orgPageHandler)
+	http.HandleFunc("/watch", watchHandler)
+	http.HandleFunc("/api/v1/badge/github.com/", badgeHandler)
+	http.HandleFunc("/api/v1/org/", orgAPIHandler)
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/readyz", readyzHandler)
+	http.HandleFunc("/assets/", assetHandler)
+	http.HandleFunc("/style.css", assetHandler)
+	http.HandleFunc("/script.js", assetHandler)
+	http.HandleFunc("/", mainHandler)
+	if *httpsAddr != "" {
+		serveHTTPS(logRequests(http.DefaultServeMux))
+		return
+	}
+	l, err := listen(*httpAddr, 0)
+	if err != nil {
+		log.Fatalf("Listening on %s: %v", *httpAddr, err)
+	}
+	logger.Info("serving", "addr", l.Addr().String())
+	log.Fatal(http.Serve(l, logRequests(http.DefaultServeMux)))
 }
 
-func staticHandler(name, text string) {
-	b := []byte(text)
-	http.HandleFunc(name, func(w http.ResponseWriter, r *http.Request) {
-		http.ServeContent(w, r, name, start, bytes.NewReader(b))
+type loggerKey struct{}
+
+// logRequests wraps h to log each request with its status and duration.
+// Each request is given an ID, taken from its X-Request-Id header if present,
+// which is attached to the logger that requestLogger returns for it.
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if id == "" {
+			id = newRequestID()
+		}
+		l := logger.With("request_id", id)
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK, lang: language(r)}
+		t0 := time.Now()
+		h.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), loggerKey{}, l)))
+		l.Info("request", "method", r.Method, "path", r.URL.Path, "status", sw.status, "duration", time.Since(t0))
 	})
 }
 
+// requestLogger returns the logger for a request.
+func requestLogger(r *http.Request) *slog.Logger {
+	if l, ok := r.Context().Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return logger
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
+
+// statusWriter records the status code written to an http.ResponseWriter.
+// It also carries the language of the request, for errf.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	lang   string
+}
+
+// langOf returns the language to respond to w in, as language chose.
+func langOf(w http.ResponseWriter) string {
+	if sw, ok := w.(*statusWriter); ok {
+		return sw.lang
+	}
+	return ""
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// tokenSource returns the source of GitHub access tokens that the flags say to use:
+// -token, a GitHub App, $GITHUB_TOKEN or -access_token_file, whichever is set first.
+func tokenSource() (auth.Source, error) {
+	return auth.Credentials{
+		Token:           *tokenFlag,
+		File:            *accessTokenFile,
+		AppID:           *appID,
+		AppInstallation: *appInstallation,
+		AppKeyFile:      *appKeyFile,
+		APIURL:          githubAPI,
+		HTTPClient:      fixhub.NewHTTPClient(nil, ""),
+	}.Source()
+}
+
+// mainHandler serves the front page, which shows the default checks.
+func mainHandler(w http.ResponseWriter, r *http.Request) {
+	buf := new(bytes.Buffer)
+	if err := problemsTmpl.Execute(buf, Data{Checks: checks(defaultClient())}); err != nil {
+		errf(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	io.Copy(w, buf)
+}
+
+// goHandler redirects the front page's form to the problems page of the
+// repository it names, with the checks it selects.
+// script.js does the same in the browser, if it can.
+func goHandler(w http.ResponseWriter, r *http.Request) {
+	rp, err := fixhub.ParseRepoPath(r.FormValue("repo"))
+	if err != nil {
+		errf(w, http.StatusBadRequest, "%v; enter a repository as github.com/owner/repo", err)
+		return
+	}
+	var enable []string
+	for _, c := range r.Form["check"] {
+		if _, err := fixhub.ParseProblemTypes(c); err != nil {
+			errf(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		enable = append(enable, c)
+	}
+	u := "/github.com/" + rp.String()
+	if len(enable) > 0 {
+		u += "?enable=" + strings.Join(enable, ",") // checked to be problem types
+	}
+	http.Redirect(w, r, u, http.StatusSeeOther)
+}
+
 type Data struct {
 	Path     string
 	Rev      string
 	Owner    string
 	Repo     string
+	Dir      string // subdirectory checked, if any
 	Problems fixhub.Problems
+	Checks   []Check // toggles for the optional checks
+	Filter   Filter  // which problems are shown, and how
+	Page     Page    // which of the problems the filter selects are shown
+	Enable   string  // the enable and disable parameters, if any
+	Disable  string
+
+	RequestURI string // to return to this page
+
+	// Sources holds the highlighted lines of the files with problems.
+	Sources map[string][]template.HTML
+	Total   int // number of problems before filtering
+
+	Persistent bool // whether state, such as ignored problems and history, is kept
+	Ignored    int  // number of problems not shown because they were ignored
+	Watched    bool // whether the repository is re-checked regularly
+	CanWatch   bool // whether visitors may change that
+	Watch      watch
+	CSRF       string // token for the page's forms; see csrfCookie
+
+	// New holds the fingerprints of problems introduced since the previous
+	// commit checked, if known.
+	New map[string]bool
+
+	// Offenders are the files, packages and types of problem with the most
+	// of the problems the filter selects.
+	Offenders struct {
+		Files, Dirs, Types []fixhub.Tally
+	}
+}
+
+// FilterURL returns the URL of the first page of this page's problems
+// with the filter parameter name set to value.
+func (d Data) FilterURL(name, value string) string {
+	u, err := url.Parse(d.RequestURI)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set(name, value)
+	q.Del("offset")
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
+
+// Check is the state of one of the optional checks in the UI.
+type Check struct {
+	Type    fixhub.ProblemType
+	Enabled bool
+}
+
+// checks returns the UI state of the optional checks for a client.
+func checks(client *fixhub.Client) []Check {
+	var cs []Check
+	for _, t := range fixhub.ProblemTypes {
+		if t == fixhub.Syntax {
+			continue // always on
+		}
+		cs = append(cs, Check{Type: t, Enabled: client.Runs(t)})
+	}
+	return cs
 }
 
+// fixhubHandler serves the problems in a repository, as a page for browsers,
+// or in the format the Accept header asks for, such as application/json or text/plain.
 func fixhubHandler(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path[1:]
-	parts := strings.Split(path[len("github.com/"):], "/")
-	if len(parts) != 2 {
-		errf(w, http.StatusBadRequest, "not a valid github owner/repo: %v", parts)
+	rp, err := fixhub.ParseRepoPath(strings.TrimPrefix(r.URL.Path, "/github.com/"))
+	if err != nil {
+		errf(w, http.StatusBadRequest, "%v", err)
 		return
 	}
-	owner, repo := parts[0], parts[1]
+	// Send forms such as owner/repo.git/ to the one place their results are kept.
+	path := "github.com/" + rp.String()
+	if "/"+path != r.URL.Path {
+		u := url.URL{Path: "/" + path, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return
+	}
+	owner, repo, dir := rp.Owner, rp.Repo, rp.Dir
+	// A revision may be given as owner/repo@rev, where rev may be a tag,
+	// or "latest-release" for the tag of the repository's latest release.
+	// Results for a full SHA-1 are pinned, and can be served from the cache.
+	checkRev := *rev
+	at, hasRev := rp.Rev, rp.Rev != ""
+	if hasRev {
+		checkRev = at
+	}
+	pinned := shaRE.MatchString(at)
 
-	client, err := fixhub.NewClient(owner, repo, accessToken)
+	if !allowed(owner, repo) {
+		errf(w, http.StatusForbidden, "checking %s/%s is not permitted here", owner, repo)
+		return
+	}
+	if dir == "feed.atom" && !hasRev {
+		feedHandler(w, r, owner, repo)
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		errf(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	page, err := parsePage(r, *pageSize)
 	if err != nil {
 		errf(w, http.StatusBadRequest, "%v", err)
 		return
 	}
 
-	ps, err := client.Check(*rev)
+	client, err := fixhub.NewClient(owner, repo, currentAccessToken())
 	if err != nil {
-		errf(w, http.StatusInternalServerError, "checking: %v", err)
+		errf(w, http.StatusBadRequest, "%v", err)
 		return
 	}
 
+	switch {
+	case at == "latest-release":
+		if checkRev, err = client.LatestRelease(); err != nil {
+			errf(w, http.StatusNotFound, "%v", err)
+			return
+		}
+	case checkRev == "":
+		if checkRev, err = client.DefaultBranch(); err != nil {
+			errf(w, http.StatusNotFound, "%v", err)
+			return
+		}
+	}
+
+	l := requestLogger(r).With("owner", owner, "repo", repo)
+	client.Logger = l
+	client.Dir = dir
+	client.KeepSources = true
+	limit(client)
+	defaultChecks := r.FormValue("enable") == "" && r.FormValue("disable") == ""
+	if defaultChecks {
+		def := defaultClient()
+		client.Enabled, client.Disabled = def.Enabled, def.Disabled
+	}
+	if e := r.FormValue("enable"); e != "" {
+		if client.Enabled, err = fixhub.ParseProblemTypes(e); err != nil {
+			errf(w, http.StatusBadRequest, "bad enable parameter: %v", err)
+			return
+		}
+	}
+	if d := r.FormValue("disable"); d != "" {
+		if client.Disabled, err = fixhub.ParseProblemTypes(d); err != nil {
+			errf(w, http.StatusBadRequest, "bad disable parameter: %v", err)
+			return
+		}
+	}
+
+	key := resultKey{owner, repo, at, dir, r.FormValue("enable"), r.FormValue("disable")}
+	cr := cachedResultFor(key)
+	if !pinned || cr == nil {
+		key.sha = checkRev
+		// Only whole-repository checks with the same checks are comparable over time.
+		record := persistent() && dir == "" && defaultChecks
+		j := startCheck(key, l, nil, func() (*cachedResult, error) {
+			return checkRepo(client, key, record, l)
+		})
+		select {
+		case <-j.done:
+		case <-time.After(*checkTimeout):
+			l.Warn("check timed out; continuing in background", "rev", checkRev, "timeout", *checkTimeout)
+			w.Header().Set("Retry-After", "10")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			pendingTmpl.Execute(w, struct{ Owner, Repo, Lang string }{owner, repo, langOf(w)})
+			return
+		}
+		var re *fixhub.RefError
+		if errors.As(j.err, &re) && re.DefaultBranch != "" {
+			def := fixhub.RepoPath{Owner: owner, Repo: repo, Rev: re.DefaultBranch, Dir: dir}
+			renderError(w, errorData{
+				Code:    http.StatusNotFound,
+				Text:    msg(langOf(w), "%s/%s has %v.", owner, repo, j.err),
+				Lang:    langOf(w),
+				Ref:     re,
+				Default: "/github.com/" + def.String(),
+			})
+			return
+		} else if errors.Is(j.err, fixhub.ErrEmptyRepository) {
+			errf(w, http.StatusNotFound, "%s/%s is empty; there is nothing to check yet.", owner, repo)
+			return
+		} else if j.err != nil {
+			errf(w, http.StatusInternalServerError, "checking: %v", j.err)
+			return
+		}
+		cr = j.cr
+	}
+	if !pinned {
+		// Send the user to the permanent page for what was found.
+		u := "/github.com/" + owner + "/" + repo + "@" + cr.res.SHA
+		if dir != "" {
+			u += "/" + dir
+		}
+		if r.URL.RawQuery != "" {
+			u += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, u, http.StatusFound)
+		return
+	}
+	res, ps, added := cr.res, cr.res.Problems, cr.added
+
 	data := Data{
-		Path:     path,
-		Rev:      *rev,
-		Owner:    owner,
-		Repo:     repo,
-		Problems: ps,
+		Path:       path,
+		Rev:        res.SHA,
+		Owner:      owner,
+		Repo:       repo,
+		Dir:        dir,
+		Problems:   ps,
+		Checks:     checks(client),
+		Filter:     filter,
+		Page:       page,
+		RequestURI: r.URL.RequestURI(),
+		Enable:     r.FormValue("enable"),
+		Disable:    r.FormValue("disable"),
+		Persistent: persistent(),
+	}
+	if data.Persistent {
+		data.Problems, data.Ignored = filterIgnored(owner, repo, ps)
+		data.Watch, data.Watched = watchOf(owner, repo)
+		data.CanWatch = watchable(owner, repo)
+		data.CSRF = csrfToken(w, r)
+		data.New = make(map[string]bool)
+		for _, p := range added {
+			data.New[p.Fingerprint()] = true
+		}
+	}
+	data.Total = len(data.Problems)
+	data.Problems = filter.apply(data.Problems)
+	data.Offenders.Files, data.Offenders.Dirs, data.Offenders.Types = fixhub.TopOffenders(data.Problems, 5)
+	lo, hi := data.Page.slice(len(data.Problems))
+	data.Problems = data.Problems[lo:hi]
+
+	w.Header().Set("Vary", "Accept")
+	f, api := negotiate(r)
+	state := []string{cr.checked.String(), r.URL.RequestURI(), f.Name}
+	if data.Persistent {
+		// Ignoring problems and watching the repository change the page too.
+		state = append(state, ignoredState(owner, repo),
+			fmt.Sprint(data.Watched, data.CanWatch, data.Watch.Since, data.Watch.Slack != ""),
+			data.CSRF)
+		// The forms' token is for this browser alone.
+		w.Header().Set("Cache-Control", "private")
+	}
+	tag := etag(res.SHA, state...)
+	w.Header().Set("Last-Modified", cr.checked.UTC().Format(http.TimeFormat))
+	if notModified(w, r, tag) {
+		return
+	}
+	if !data.Persistent && r.Header.Get("If-None-Match") == "" {
+		// Without persistent state, the response depends only on the check.
+		if t, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !cr.checked.Truncate(time.Second).After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if api {
+		data.Page.link(w)
+		w.Header().Set("Content-Type", f.ContentType)
+		if err := f.Encode(w, data.Problems); err != nil {
+			l.Warn("writing problems", "format", f.Name, "err", err)
+		}
+		return
+	}
+	data.Sources = make(map[string][]template.HTML)
+	for _, p := range data.Problems {
+		if _, ok := data.Sources[p.File]; !ok && p.Line > 0 {
+			data.Sources[p.File] = highlight(res.Sources[p.File])
+		}
 	}
 
 	buf := new(bytes.Buffer)
@@ -115,89 +511,218 @@ func fixhubHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func errf(w http.ResponseWriter, code int, format string, a ...interface{}) {
+	lang := langOf(w)
+	d := errorData{Code: code, Text: msg(lang, format, a...), Lang: lang}
+	// Some errors need more explaining, or aren't the server's fault.
+	for _, arg := range a {
+		err, ok := arg.(error)
+		if !ok {
+			continue
+		}
+		if errors.As(err, &d.SSO) {
+			d.Code = http.StatusForbidden
+		}
+		if errors.Is(err, fixhub.ErrEmptyRepository) || errors.As(err, &d.Ref) {
+			d.Code = http.StatusNotFound
+		}
+	}
+	renderError(w, d)
+}
+
+// errorData is what errorTmpl shows.
+type errorData struct {
+	Code       int
+	Text, Lang string
+	SSO        *fixhub.SSOError
+	Ref        *fixhub.RefError
+	Default    string // the page for the default branch, when Ref is set
+}
+
+func renderError(w http.ResponseWriter, d errorData) {
 	buf := new(bytes.Buffer)
-	err := errorTmpl.Execute(buf, struct{ Code, Text string }{
-		Code: strconv.Itoa(code),
-		Text: fmt.Sprintf(format, a...),
-	})
-	if err != nil {
-		log.Printf("error rendering error: %v (%s/%d)", err, format, code)
+	if err := errorTmpl.Execute(buf, d); err != nil {
+		logger.Error("rendering error page", "err", err, "text", d.Text, "code", d.Code)
 		return
 	}
-	w.WriteHeader(code)
+	w.WriteHeader(d.Code)
 	io.Copy(w, buf)
 }
 
-var errorTmpl = template.Must(template.New("error.html").Parse(`<!DOCTYPE html>
-<html>
+// pendingTmpl is the page shown when a check takes too long to wait for.
+// It reloads itself until the check is done.
+var pendingTmpl = template.Must(template.New("pending.html").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html lang="{{or .Lang "en"}}">
 <head>
-<title>golint error {{.Code}}</title>
+<title>{{msg .Lang "fixhub: checking %s/%s" .Owner .Repo}}</title>
+<meta http-equiv="refresh" content="10">
+<link rel="stylesheet" type="text/css" href="{{asset "style.css"}}">
 </head>
 <body>
-{{.Text}}
+<p>{{msg .Lang "%s/%s is taking a while to check." .Owner .Repo}}
+{{msg .Lang "The check carries on without you; this page will reload itself until it is done."}}</p>
 </body>
 </html>
 `))
 
-const styleText = `
-body {
-	font-family: Helvetica, Arial;
-}
-#header #repoText {
-	width: 350px;
-}
-#header {
-	font-size: 18pt;
-	margin: 0 auto;
-	width: 700px;
-}
-#header input {
-	font-family: Helvetica, Arial;
-	font-size: 18pt;
-}
-`
-
-const scriptText = `
-function goproblems() {
-	var path = document.forms[0].repoText.value;
-	window.location = window.location.origin + "/" + path;
-	return false;
-}
-`
+var errorTmpl = template.Must(template.New("error.html").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html lang="{{or .Lang "en"}}">
+<head>
+<title>{{msg .Lang "fixhub error %d" .Code}}</title>
+</head>
+<body>
+{{.Text}}
+{{with .SSO}}{{with .URL}}
+<p>{{msgHTML $.Lang "Whoever runs fixhubd can %s for the organization, then try again." (link . (msg $.Lang "authorize its access token"))}}</p>
+{{end}}{{end}}
+{{with .Default}}
+<p>{{msgHTML $.Lang "Check the %s instead." (link . (msg $.Lang "default branch"))}}</p>
+{{end}}
+</body>
+</html>
+`))
 
 func problemLink(d Data, p fixhub.Problem) string {
-	url := "https://" + d.Path + "/blob/" + d.Rev + "/" + p.File
+	url := "https://github.com/" + d.Owner + "/" + d.Repo + "/blob/" + d.Rev + "/" + p.File
 	if p.Line > 0 {
 		url += fmt.Sprintf("#L%d", p.Line)
+		if p.EndLine > p.Line {
+			url += fmt.Sprintf("-L%d", p.EndLine)
+		}
 	}
 	return url
 }
 
 var problemsTmpl = template.Must(template.New("problems.html").Funcs(template.FuncMap{
-	"problemLink": problemLink,
-}).Parse(`<!DOCTYPE html>
+	"problemLink":     problemLink,
+	"recheckInterval": func() time.Duration { return *recheckInterval },
+	"problemTypes":    func() []fixhub.ProblemType { return fixhub.ProblemTypes },
+	"snippet":         snippet,
+	"join":            strings.Join,
+}).Funcs(pageFuncs).Parse(`<!DOCTYPE html>
 <html>
 <head>
 <title>fixhub</title>
-<link rel="stylesheet" type="text/css" href="/style.css">
-<script src="/script.js" type="text/javascript"></script>
+<link rel="stylesheet" type="text/css" href="{{asset "style.css"}}">
+<script src="{{asset "script.js"}}" type="text/javascript" defer></script>
+{{if and .Persistent .Owner}}<link rel="alternate" type="application/atom+xml" href="/github.com/{{.Owner}}/{{.Repo}}/feed.atom">{{end}}
 </head>
 <body>
 
 <div id="header">
-<form onsubmit="return goproblems();">
-Find problems in <input id="repoText" placeholder="github.com/owner/repo" value="{{.Path}}">
+<form action="/go" method="GET" onsubmit="return goproblems(this);">
+<label for="repoText">Find problems in</label>
+<input id="repoText" name="repo" placeholder="github.com/owner/repo[/dir]" value="{{.Path}}" required>
 <input type="submit" value="Go">
+<fieldset id="checks">
+<legend>Checks</legend>
+{{range .Checks}}
+<label><input type="checkbox" name="check" value="{{.Type}}"{{if .Enabled}} checked{{end}}> {{.Type}}</label>
+{{end}}
+</fieldset>
 </form>
 </div>
 
+{{if .Total}}
+<form id="filter" method="GET">
+{{with .Enable}}<input type="hidden" name="enable" value="{{.}}">{{end}}
+{{with .Disable}}<input type="hidden" name="disable" value="{{.}}">{{end}}
+Show
+<select name="type"{{if gt (len .Filter.Types) 1}} multiple{{end}}>
+<option value="">all types</option>
+{{range problemTypes}}<option value="{{.}}"{{if $.Filter.HasType .}} selected{{end}}>{{.}}</option>
+{{end}}
+</select>
+in files starting with <input name="file" value="{{.Filter.File}}" placeholder="path/">
+<label><input type="checkbox" name="fixable" value="1"{{if .Filter.Fixable}} checked{{end}}> fixable only</label>
+sorted by
+<select name="sort">
+<option value="file"{{if eq .Filter.Sort "file"}} selected{{end}}>file</option>
+<option value="type"{{if eq .Filter.Sort "type"}} selected{{end}}>type</option>
+</select>
+<input type="submit" value="Filter">
+</form>
+{{if gt (len .Offenders.Files) 1}}
+<details id="offenders"><summary>Where to start</summary>
+<table>
+<tr><th>Files with the most problems</th><th>Packages</th><th>Types</th></tr>
+<tr>
+<td>{{range .Offenders.Files}}<a href="{{$.FilterURL "file" .Name}}">{{.Name}}</a> ({{.N}})<br>{{end}}</td>
+<td>{{range .Offenders.Dirs}}{{if eq .Name "."}}the top level{{else}}<a href="{{$.FilterURL "file" (printf "%s/" .Name)}}">{{.Name}}</a>{{end}} ({{.N}})<br>{{end}}</td>
+<td>{{range .Offenders.Types}}<a href="{{$.FilterURL "type" .Name}}">{{.Name}}</a> ({{.N}})<br>{{end}}</td>
+</tr>
+</table>
+</details>
+{{end}}
+{{if .Page.Split}}<p>Showing problems {{.Page.First}}&ndash;{{.Page.Last}} of {{.Page.Total}}{{if .Filter.Active}} selected, of {{.Total}} in all{{end}}.</p>
+{{else if .Filter.Active}}<p>Showing {{len .Problems}} of {{.Total}} problems.</p>{{end}}
+{{end}}
+
 {{if .Problems}}
 <ul>
 {{range .Problems}}
-<li><a href="{{problemLink $ .}}">{{.File}}{{with .Line}}:{{.}}{{end}}</a>: {{.Text}}</li>
+<li{{if index $.New .Fingerprint}} class="new"{{end}}><a href="{{problemLink $ .}}">{{.File}}{{with .Line}}:{{.}}{{end}}</a>: {{.Text}}
+{{with .URL}}<small><a href="{{.}}">why?</a></small>{{end}}
+{{if $.Persistent}}
+<form method="POST" action="/ignore" class="inline">
+<input type="hidden" name="owner" value="{{$.Owner}}">
+<input type="hidden" name="repo" value="{{$.Repo}}">
+<input type="hidden" name="file" value="{{.File}}">
+<input type="hidden" name="type" value="{{.Type}}">
+<input type="hidden" name="text" value="{{.Text}}">
+<input type="hidden" name="code" value="{{.Code}}">
+<input type="hidden" name="line_text" value="{{.LineText}}">
+<input type="hidden" name="return" value="{{$.RequestURI}}">
+<input type="hidden" name="csrf" value="{{$.CSRF}}">
+<input type="submit" value="ignore">
+</form>
+{{end}}
+{{with snippet (index $.Sources .File) .Line}}
+<details><summary>source</summary>
+<pre class="source">{{range .}}<span class="line{{if .Mark}} mark{{end}}"><span class="n">{{.N}}</span>{{.HTML}}</span>{{end}}</pre>
+</details>
+{{end}}
+</li>
 {{end}}
 </ul>
 {{end}}
+{{if .Page.Split}}
+<p>{{with .Page.Prev}}<a href="{{.}}" rel="prev">&larr; previous {{$.Page.Limit}}</a>{{end}}
+{{with .Page.Next}}<a href="{{.}}" rel="next">next {{$.Page.Limit}} &rarr;</a>{{end}}</p>
+{{end}}
+{{if .Persistent}}
+<div><a href="/history/{{.Owner}}/{{.Repo}}">History</a> &middot; <a href="/github.com/{{.Owner}}/{{.Repo}}/feed.atom">Feed</a>
+{{if .CanWatch}}
+<form method="POST" action="/watch" class="inline">
+<input type="hidden" name="owner" value="{{.Owner}}">
+<input type="hidden" name="repo" value="{{.Repo}}">
+<input type="hidden" name="csrf" value="{{.CSRF}}">
+{{if .Watched}}
+<input type="submit" name="unwatch" value="Stop re-checking">
+{{else}}
+<input type="submit" value="Re-check every {{recheckInterval}}">
+{{end}}
+</form>
+{{if .Watched}}
+<form method="POST" action="/watch">
+<input type="hidden" name="owner" value="{{.Owner}}">
+<input type="hidden" name="repo" value="{{.Repo}}">
+<input type="hidden" name="notify" value="1">
+<input type="hidden" name="csrf" value="{{.CSRF}}">
+Notify of new problems
+to Slack webhook
+<input name="slack" type="password" placeholder="{{if .Watch.Slack}}(set; leave blank to keep){{else}}https://hooks.slack.com/...{{end}}">
+{{if .Watch.Slack}}<label><input type="checkbox" name="clear_slack" value="1"> clear</label>{{end}}
+<input type="submit" value="Save">
+</form>
+{{end}}
+{{else if .Watched}}
+&middot; Re-checked every {{recheckInterval}}
+{{end}}
+</div>
+{{end}}
+{{with .Ignored}}
+<p>{{.}} ignored problem{{if ne . 1}}s{{end}} not shown (<a href="/ignored/{{$.Owner}}/{{$.Repo}}">manage</a>).</p>
+{{end}}
 </body>
 </html>
 `))
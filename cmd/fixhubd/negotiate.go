@@ -0,0 +1,48 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dsymonds/fixhub/format"
+)
+
+// negotiate returns the format of problems that r's Accept header prefers,
+// such as application/json or text/plain, or false if it prefers HTML,
+// accepts anything, or names nothing we can serve.
+func negotiate(r *http.Request) (format.Format, bool) {
+	type accept struct {
+		mediaType string
+		q         float64
+	}
+	var accepts []accept
+	for _, s := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(s))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			accepts = append(accepts, accept{mt, q})
+		}
+	}
+	sort.SliceStable(accepts, func(i, j int) bool { return accepts[i].q > accepts[j].q })
+	for _, a := range accepts {
+		switch a.mediaType {
+		case "text/html", "*/*", "text/*":
+			return format.Format{}, false
+		}
+		if f, ok := format.ForContentType(a.mediaType); ok {
+			return f, true
+		}
+	}
+	return format.Format{}, false
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+var (
+	smtpAddr         = flag.String("smtp_addr", "", "SMTP server (host:port) for email notifications; if empty, none are sent")
+	smtpFrom         = flag.String("smtp_from", "fixhub@localhost", "sender address of email notifications")
+	smtpUser         = flag.String("smtp_user", "", "SMTP user name, if the server needs authentication")
+	smtpPasswordFile = flag.String("smtp_password_file", "", "a file containing the SMTP password")
+)
+
+// maxNotified is how many problems a notification lists before summarizing the rest.
+const maxNotified = 20
+
+// notify sends notifications of newly introduced problems to wherever the watch
+// and the configuration say. Failures are logged.
+func notify(l *slog.Logger, owner, repo, sha string, added fixhub.Problems, wt watch) {
+	subject := fmt.Sprintf("fixhub: %d new problem", len(added))
+	if len(added) != 1 {
+		subject += "s"
+	}
+	subject += fmt.Sprintf(" in %s/%s", owner, repo)
+
+	body := new(bytes.Buffer)
+	fmt.Fprintf(body, "Commit https://github.com/%s/%s/commit/%s introduced:\n\n", owner, repo, sha)
+	for i, p := range added {
+		if i == maxNotified {
+			fmt.Fprintf(body, "... and %d more\n", len(added)-i)
+			break
+		}
+		fmt.Fprintf(body, "%s\n", p)
+	}
+
+	if to := emailFor(owner, repo); len(to) > 0 && *smtpAddr != "" {
+		if err := sendEmail(to, subject, body.String()); err != nil {
+			l.Error("sending email notification", "err", err)
+		}
+	}
+	if wt.Slack != "" {
+		// The configuration may have changed since the webhook was set.
+		if err := checkWebhook(string(wt.Slack)); err != nil {
+			l.Error("not sending Slack notification", "err", err)
+		} else if err := postSlack(string(wt.Slack), "*"+subject+"*\n"+body.String()); err != nil {
+			l.Error("sending Slack notification", "err", err)
+		}
+	}
+}
+
+func sendEmail(to []string, subject, body string) error {
+	var auth smtp.Auth
+	if *smtpUser != "" {
+		pw, err := ioutil.ReadFile(*smtpPasswordFile)
+		if err != nil {
+			return fmt.Errorf("reading SMTP password: %v", err)
+		}
+		host, _, err := net.SplitHostPort(*smtpAddr)
+		if err != nil {
+			return fmt.Errorf("bad -smtp_addr: %v", err)
+		}
+		auth = smtp.PlainAuth("", *smtpUser, string(bytes.TrimSpace(pw)), host)
+	}
+	msg := new(bytes.Buffer)
+	fmt.Fprintf(msg, "From: %s\r\n", *smtpFrom)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+	return smtp.SendMail(*smtpAddr, auth, *smtpFrom, to, msg.Bytes())
+}
+
+// postSlack posts a message to a Slack incoming webhook.
+func postSlack(webhook, text string) error {
+	b, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{text})
+	if err != nil {
+		return err
+	}
+	client := fixhub.NewHTTPClient(nil, "")
+	client.Timeout = 30 * time.Second
+	// Don't be redirected past checkWebhook.
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
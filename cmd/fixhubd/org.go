@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+var maxOrgQueue = flag.Int("org_queue", 100, "how many checks for org pages may be queued or running at once; repositories beyond that are left pending")
+
+// orgChecks limits how many checks started for org summaries run at once,
+// so that a large organization doesn't use up the rate limit in a burst.
+var orgChecks = make(chan struct{}, 4)
+
+// orgQueue counts the checks started for org summaries that have not finished,
+// so that there are never more than -org_queue of them.
+var orgQueue struct {
+	sync.Mutex
+	n int
+}
+
+// An orgRepo summarizes the last check of one of an owner's repositories.
+type orgRepo struct {
+	Repo     string                     `json:"repo"`
+	Status   string                     `json:"status"` // "checked", "pending" or "failed"
+	SHA      string                     `json:"sha,omitempty"`
+	Problems int                        `json:"problems"` // not counting ignored problems
+	Counts   map[fixhub.ProblemType]int `json:"counts,omitempty"`
+	Checked  *time.Time                 `json:"checked,omitempty"`
+	Error    string                     `json:"error,omitempty"`
+	Branch   string                     `json:"branch"` // the default branch
+}
+
+// setResult fills in or from the result of a check of owner's repository.
+func (or *orgRepo) setResult(owner, sha string, ps fixhub.Problems, checked time.Time) {
+	ps, _ = filterIgnored(owner, or.Repo, ps)
+	or.Status, or.SHA, or.Problems, or.Checked = "checked", sha, len(ps), &checked
+	or.Counts = make(map[fixhub.ProblemType]int)
+	for _, p := range ps {
+		or.Counts[p.Type]++
+	}
+}
+
+// orgOptions chooses which of an owner's repositories org pages cover,
+// besides those with Go code that may be checked here.
+type orgOptions struct {
+	archived, forks bool // whether to include archived repositories and forks
+}
+
+// orgOptionsFor returns the configured org options, as overridden by
+// the "archived" and "forks" parameters of r (e.g. ?forks=1).
+func orgOptionsFor(r *http.Request) orgOptions {
+	settings.RLock()
+	opts := settings.org
+	settings.RUnlock()
+	if v := r.FormValue("archived"); v != "" {
+		opts.archived = v == "1"
+	}
+	if v := r.FormValue("forks"); v != "" {
+		opts.forks = v == "1"
+	}
+	return opts
+}
+
+// orgRepos returns the repositories of an owner that org pages cover.
+func orgRepos(owner string, opts orgOptions) ([]fixhub.Repository, error) {
+	lister, err := fixhub.NewClient(owner, "", currentAccessToken())
+	if err != nil {
+		return nil, err
+	}
+	repos, err := lister.Repositories()
+	if err != nil {
+		return nil, err
+	}
+	var covered []fixhub.Repository
+	for _, r := range repos {
+		if (r.Archived && !opts.archived) || (r.Fork && !opts.forks) || !allowed(owner, r.Name) {
+			continue
+		}
+		ok, err := hasGo(owner, r)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			covered = append(covered, r)
+		}
+	}
+	return covered, nil
+}
+
+// goRepos caches whether repositories whose primary language is not Go
+// have any Go code, as found from the languages API.
+var goRepos struct {
+	sync.Mutex
+	m map[string]goRepo // "owner/repo" -> what was found
+}
+
+type goRepo struct {
+	pushed time.Time // when the repository was last pushed to, as of finding out
+	hasGo  bool
+}
+
+// hasGo reports whether an owner's repository has any Go code.
+func hasGo(owner string, r fixhub.Repository) (bool, error) {
+	if r.Language == "Go" {
+		return true, nil
+	}
+	if r.Language == "" && r.PushedAt.IsZero() {
+		return false, nil // empty
+	}
+	key := owner + "/" + r.Name
+	goRepos.Lock()
+	gr, ok := goRepos.m[key]
+	goRepos.Unlock()
+	if ok && gr.pushed.Equal(r.PushedAt) {
+		return gr.hasGo, nil
+	}
+
+	client, err := fixhub.NewClient(owner, r.Name, currentAccessToken())
+	if err != nil {
+		return false, err
+	}
+	langs, err := client.Languages()
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", key, err)
+	}
+	gr = goRepo{pushed: r.PushedAt, hasGo: langs["Go"] > 0}
+	goRepos.Lock()
+	if goRepos.m == nil {
+		goRepos.m = make(map[string]goRepo)
+	}
+	goRepos.m[key] = gr
+	goRepos.Unlock()
+	return gr.hasGo, nil
+}
+
+// orgSummary summarizes the last checks of the repositories of an owner
+// that org pages cover. It starts checks of those that have not been checked,
+// which are reported as pending until they finish.
+func orgSummary(owner string, opts orgOptions, l *slog.Logger) ([]orgRepo, error) {
+	repos, err := orgRepos(owner, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary []orgRepo
+	for _, r := range repos {
+		or := orgRepo{Repo: r.Name, Branch: r.DefaultBranch}
+		if cr := latestResultFor(owner, r.Name); cr != nil {
+			or.setResult(owner, cr.res.SHA, cr.res.Problems, cr.checked)
+			summary = append(summary, or)
+			continue
+		}
+		if persistent() {
+			last, err := loadResult(owner, r.Name)
+			if err != nil {
+				return nil, err
+			}
+			if last != nil {
+				or.setResult(owner, last.SHA, last.Problems, last.Time)
+				summary = append(summary, or)
+				continue
+			}
+		}
+
+		or.Status = "pending"
+		j := startOrgCheck(owner, r, l.With("repo", r.Name))
+		if j == nil {
+			summary = append(summary, or) // to be started once there is room
+			continue
+		}
+		select {
+		case <-j.done:
+			if j.err != nil {
+				or.Status, or.Error = "failed", j.err.Error()
+			} else {
+				or.setResult(owner, j.cr.res.SHA, j.cr.res.Problems, j.cr.checked)
+			}
+		default:
+		}
+		summary = append(summary, or)
+	}
+	return summary, nil
+}
+
+// startOrgCheck starts a check of the default branch of an owner's repository
+// with the default checks, unless one is already under way.
+// It returns nil if -org_queue checks are already queued or running.
+func startOrgCheck(owner string, r fixhub.Repository, l *slog.Logger) *job {
+	branch := r.DefaultBranch
+	if branch == "" {
+		branch = *rev
+	}
+	key := resultKey{owner: owner, repo: r.Name, sha: branch}
+	admit := func() bool {
+		orgQueue.Lock()
+		defer orgQueue.Unlock()
+		if orgQueue.n >= *maxOrgQueue {
+			return false
+		}
+		orgQueue.n++
+		return true
+	}
+	return startCheck(key, l, admit, func() (*cachedResult, error) {
+		defer func() {
+			orgQueue.Lock()
+			orgQueue.n--
+			orgQueue.Unlock()
+		}()
+		orgChecks <- struct{}{}
+		defer func() { <-orgChecks }()
+
+		client, err := fixhub.NewClient(owner, r.Name, currentAccessToken())
+		if err != nil {
+			return nil, err
+		}
+		def := defaultClient()
+		client.Enabled, client.Disabled = def.Enabled, def.Disabled
+		client.Logger = l
+		limit(client)
+		return checkRepo(client, key, persistent(), l)
+	})
+}
+
+// orgAPIHandler serves summaries of the last checks of an owner's Go repositories
+// as JSON, for dashboards. Repositories not yet checked are queued for checking,
+// and the response asks to be retried until they are done.
+// The offset and limit parameters page through many repositories,
+// and a Link header points to the previous and next pages.
+func orgAPIHandler(w http.ResponseWriter, r *http.Request) {
+	owner := strings.TrimPrefix(r.URL.Path, "/api/v1/org/")
+	if owner == "" || strings.Contains(owner, "/") {
+		errf(w, http.StatusBadRequest, "not a valid github owner: %q", owner)
+		return
+	}
+	page, err := parsePage(r, 0)
+	if err != nil {
+		errf(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	l := requestLogger(r).With("owner", owner)
+	summary, err := orgSummary(owner, orgOptionsFor(r), l)
+	if err != nil {
+		l.Error("summarizing repositories", "err", err)
+		errf(w, http.StatusBadGateway, "summarizing repositories of %s: %v", owner, err)
+		return
+	}
+	lo, hi := page.slice(len(summary))
+	summary = summary[lo:hi]
+	for _, or := range summary {
+		if or.Status == "pending" {
+			w.Header().Set("Retry-After", "10")
+			break
+		}
+	}
+	page.link(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Owner  string    `json:"owner"`
+		Total  int       `json:"total"` // number of repositories in all pages
+		Offset int       `json:"offset"`
+		Repos  []orgRepo `json:"repos"`
+	}{owner, page.Total, page.Offset, summary})
+}
+
+// orgPageHandler serves the dashboard of an owner's Go repositories,
+// and queues re-checks of them when the dashboard's buttons are pressed.
+func orgPageHandler(w http.ResponseWriter, r *http.Request) {
+	owner := strings.TrimPrefix(r.URL.Path, "/org/github.com/")
+	if owner == "" || strings.Contains(owner, "/") {
+		errf(w, http.StatusBadRequest, "not a valid github owner: %q", owner)
+		return
+	}
+	l := requestLogger(r).With("owner", owner)
+
+	if r.Method == "POST" {
+		if !checkCSRF(w, r) {
+			return
+		}
+		// Re-check the named repository, or all of them.
+		repos := []fixhub.Repository{{Name: r.FormValue("repo"), DefaultBranch: r.FormValue("branch")}}
+		if repos[0].Name == "" {
+			var err error
+			if repos, err = orgRepos(owner, orgOptionsFor(r)); err != nil {
+				errf(w, http.StatusBadGateway, "listing repositories of %s: %v", owner, err)
+				return
+			}
+		} else if !allowed(owner, repos[0].Name) {
+			errf(w, http.StatusForbidden, "checking %s/%s is not permitted here", owner, repos[0].Name)
+			return
+		} else if repos[0].DefaultBranch == "" && *rev == "" {
+			client, err := fixhub.NewClient(owner, repos[0].Name, currentAccessToken())
+			if err == nil {
+				repos[0].DefaultBranch, err = client.DefaultBranch()
+			}
+			if err != nil {
+				errf(w, http.StatusBadGateway, "%v", err)
+				return
+			}
+		}
+		full := 0
+		for _, repo := range repos {
+			if startOrgCheck(owner, repo, l.With("repo", repo.Name)) == nil {
+				full++
+			}
+		}
+		if full > 0 {
+			w.Header().Set("Retry-After", "60")
+			errf(w, http.StatusTooManyRequests, "too many checks are queued here; %d of %d repositories were not queued, so try again later", full, len(repos))
+			return
+		}
+		u := r.URL.Path
+		if r.URL.RawQuery != "" {
+			u += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, u, http.StatusSeeOther)
+		return
+	}
+
+	summary, err := orgSummary(owner, orgOptionsFor(r), l)
+	if err != nil {
+		l.Error("summarizing repositories", "err", err)
+		errf(w, http.StatusBadGateway, "summarizing repositories of %s: %v", owner, err)
+		return
+	}
+	by := r.FormValue("sort")
+	sort.SliceStable(summary, func(i, j int) bool {
+		a, b := summary[i], summary[j]
+		switch by {
+		case "problems":
+			return a.Problems > b.Problems
+		case "checked":
+			return a.Checked != nil && (b.Checked == nil || a.Checked.After(*b.Checked))
+		}
+		return a.Repo < b.Repo
+	})
+	data := struct {
+		Owner   string
+		Sort    string
+		Repos   []orgRepo
+		Pending bool
+		CSRF    string
+	}{Owner: owner, Sort: by, Repos: summary, CSRF: csrfToken(w, r)}
+	for _, or := range summary {
+		data.Pending = data.Pending || or.Status == "pending"
+	}
+
+	buf := new(bytes.Buffer)
+	if err := orgTmpl.Execute(buf, data); err != nil {
+		errf(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	io.Copy(w, buf)
+}
+
+var orgTmpl = template.Must(template.New("org.html").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>fixhub: {{.Owner}}</title>
+<link rel="stylesheet" type="text/css" href="{{asset "style.css"}}">
+{{if .Pending}}<meta http-equiv="refresh" content="10">{{end}}
+</head>
+<body>
+<div id="header">
+Go repositories of <a href="https://github.com/{{.Owner}}">{{.Owner}}</a>
+</div>
+
+{{if .Repos}}
+<table>
+<tr>
+<th>{{if eq .Sort ""}}Repository{{else}}<a href="?">Repository</a>{{end}}</th>
+<th>{{if eq .Sort "problems"}}Problems{{else}}<a href="?sort=problems">Problems</a>{{end}}</th>
+<th>{{if eq .Sort "checked"}}Checked{{else}}<a href="?sort=checked">Checked</a>{{end}}</th>
+<th></th>
+</tr>
+{{range .Repos}}
+<tr>
+<td><a href="/github.com/{{$.Owner}}/{{.Repo}}">{{.Repo}}</a></td>
+{{if eq .Status "checked"}}
+<td><a href="/github.com/{{$.Owner}}/{{.Repo}}@{{.SHA}}">{{.Problems}}</a></td>
+<td>{{.Checked.Format "2006-01-02 15:04"}} at {{printf "%.7s" .SHA}}</td>
+{{else if eq .Status "pending"}}
+<td colspan="2">checking&hellip;</td>
+{{else}}
+<td colspan="2">check failed: {{.Error}}</td>
+{{end}}
+<td><form method="post"><input type="hidden" name="csrf" value="{{$.CSRF}}"><input type="hidden" name="repo" value="{{.Repo}}"><input type="hidden" name="branch" value="{{.Branch}}"><button type="submit">Re-check</button></form></td>
+</tr>
+{{end}}
+</table>
+<form method="post"><input type="hidden" name="csrf" value="{{.CSRF}}"><button type="submit">Re-check all</button></form>
+{{else}}
+<p>{{.Owner}} has no Go repositories that may be checked here.</p>
+{{end}}
+</body>
+</html>
+`))
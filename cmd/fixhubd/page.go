@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+var pageSize = flag.Int("page_size", 500, "how many problems a results page shows at once")
+
+// maxLimit is the most results a page may be asked for.
+const maxLimit = 10000
+
+// A Page is a window onto a long list of results, chosen by the query
+// parameters "offset" and "limit", so that a repository with tens of thousands
+// of problems can be looked through a page at a time,
+// e.g. /github.com/owner/repo?offset=500&limit=100.
+type Page struct {
+	Offset int // index of the first result shown
+	Limit  int // most results shown; 0 means all
+	Total  int // number of results in all pages
+
+	uri *url.URL // the request, for links to other pages
+}
+
+// parsePage returns the page that r asks for, or the first page of def results.
+func parsePage(r *http.Request, def int) (Page, error) {
+	pg := Page{Limit: def, uri: r.URL}
+	if v := r.FormValue("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Page{}, fmt.Errorf("bad offset parameter %q", v)
+		}
+		pg.Offset = n
+	}
+	if v := r.FormValue("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxLimit {
+			return Page{}, fmt.Errorf("bad limit parameter %q; it must be from 1 to %d", v, maxLimit)
+		}
+		pg.Limit = n
+	}
+	return pg, nil
+}
+
+// slice sets the total number of results to n,
+// and returns the range of them that the page shows.
+func (pg *Page) slice(n int) (lo, hi int) {
+	pg.Total = n
+	return pg.window()
+}
+
+func (pg Page) window() (lo, hi int) {
+	lo, hi = pg.Offset, pg.Total
+	if lo > hi {
+		lo = hi
+	}
+	if pg.Limit > 0 && hi-lo > pg.Limit {
+		hi = lo + pg.Limit
+	}
+	return lo, hi
+}
+
+// Split reports whether the results don't all fit on one page.
+func (pg Page) Split() bool {
+	return pg.Offset > 0 || pg.Limit > 0 && pg.Total > pg.Limit
+}
+
+// First and Last are the positions, starting at 1, of the first and last results shown.
+func (pg Page) First() int {
+	lo, _ := pg.window()
+	return lo + 1
+}
+
+func (pg Page) Last() int {
+	_, hi := pg.window()
+	return hi
+}
+
+// Prev and Next return the URLs of the previous and next pages,
+// or "" if there are none.
+func (pg Page) Prev() string {
+	if pg.Offset == 0 {
+		return ""
+	}
+	off := pg.Offset - pg.Limit
+	if off < 0 || pg.Limit == 0 {
+		off = 0
+	}
+	return pg.at(off)
+}
+
+func (pg Page) Next() string {
+	if _, hi := pg.window(); hi >= pg.Total {
+		return ""
+	}
+	return pg.at(pg.Offset + pg.Limit)
+}
+
+// at returns the URL of the page starting at offset.
+func (pg Page) at(offset int) string {
+	if pg.uri == nil {
+		return ""
+	}
+	u := *pg.uri
+	q := u.Query()
+	if offset > 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	} else {
+		q.Del("offset")
+	}
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
+
+// link sets a Link header on w pointing to the previous and next pages,
+// in the style of the GitHub API.
+func (pg Page) link(w http.ResponseWriter) {
+	if u := pg.Prev(); u != "" {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="prev"`, u))
+	}
+	if u := pg.Next(); u != "" {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, u))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	userWriteQuota   = flag.Int("write_quota_per_user", 10, "how many changes to repositories, such as reverts, each requester may make an hour")
+	globalWriteQuota = flag.Int("write_quota", 100, "how many changes to repositories fixhubd may make an hour in all")
+)
+
+// writes records the recent changes to repositories made through fixhubd,
+// for enforcing the write quotas.
+var writes struct {
+	sync.Mutex
+	all    []time.Time
+	byUser map[string][]time.Time // requester -> times of their changes
+}
+
+// A quotaError says that a write quota has been used up.
+type quotaError struct {
+	whose string // "your" or "this server's"
+	limit int
+	retry time.Time // when a change will next be permitted
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("%s quota of %d changes an hour is used up; try again after %s", e.whose, e.limit, e.retry.Format("15:04 MST"))
+}
+
+// takeWriteQuota accounts for a change to a repository requested by r,
+// or returns why not if that would exceed a write quota.
+// Requesters in the configured exempt list are only counted.
+func takeWriteQuota(r *http.Request) *quotaError {
+	user := requester(r)
+	exempt := writeQuotaExempt(user)
+	now := time.Now()
+
+	writes.Lock()
+	defer writes.Unlock()
+	if writes.byUser == nil {
+		writes.byUser = make(map[string][]time.Time)
+	}
+	writes.all = recent(writes.all, now)
+	mine := recent(writes.byUser[user], now)
+	if !exempt {
+		if len(mine) >= *userWriteQuota {
+			writes.byUser[user] = mine
+			return &quotaError{"your", *userWriteQuota, mine[len(mine)-*userWriteQuota].Add(time.Hour)}
+		}
+		if len(writes.all) >= *globalWriteQuota {
+			writes.byUser[user] = mine
+			return &quotaError{"this server's", *globalWriteQuota, writes.all[len(writes.all)-*globalWriteQuota].Add(time.Hour)}
+		}
+	}
+	writes.all = append(writes.all, now)
+	writes.byUser[user] = append(mine, now)
+	return nil
+}
+
+// recent returns the times in ts, which are in order, within the hour before now.
+func recent(ts []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(ts) && now.Sub(ts[i]) >= time.Hour {
+		i++
+	}
+	return ts[i:]
+}
+
+// requester returns the address that r came from, which is who write quotas apply to.
+// If r came through the configuration's trusted_proxies, that is the last address
+// in its X-Forwarded-For header that is not one of them; anything before it
+// could have been made up by the requester.
+func requester(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	settings.RLock()
+	defer settings.RUnlock()
+	if !matchAddr(settings.trustedProxies, host) {
+		return host
+	}
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		host = hop
+		if !matchAddr(settings.trustedProxies, hop) {
+			break
+		}
+	}
+	return host
+}
+
+// writeQuotaExempt reports whether the requester at addr is exempt from write quotas,
+// as the configuration's write_quota.exempt list of addresses and CIDR blocks says.
+func writeQuotaExempt(addr string) bool {
+	settings.RLock()
+	defer settings.RUnlock()
+	return matchAddr(settings.quotaExempt, addr)
+}
+
+// isAdmin reports whether the requester at addr is one of the configuration's admins.
+func isAdmin(addr string) bool {
+	settings.RLock()
+	defer settings.RUnlock()
+	return matchAddr(settings.admins, addr)
+}
+
+// matchAddr reports whether addr is in list, of addresses and CIDR blocks.
+func matchAddr(list []string, addr string) bool {
+	ip := net.ParseIP(addr)
+	for _, e := range list {
+		if strings.Contains(e, "/") {
+			if _, n, err := net.ParseCIDR(e); err == nil && ip != nil && n.Contains(ip) {
+				return true
+			}
+		} else if e == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+var revertBranch = flag.String("revert_branch", "", "if set, the branch that fixhub commits fixes to, on which visitors may revert them in the repositories the configuration's revert.allow lists")
+
+// revertHandler reverts a commit of fixes made by fixhub on -revert_branch,
+// or deletes the branch if it holds only such commits, after asking for
+// confirmation, and records what it did in the audit log.
+// The commit is named by the owner, repo and sha form values;
+// the branch is deleted instead if the delete_branch form value is set,
+// and then sha may be left out.
+func revertHandler(w http.ResponseWriter, r *http.Request) {
+	if !persistent() || currentAccessToken() == "" || *revertBranch == "" {
+		errf(w, http.StatusMethodNotAllowed, "can't revert commits here")
+		return
+	}
+	owner, repo, sha := r.FormValue("owner"), r.FormValue("repo"), r.FormValue("sha")
+	deleteBranch := r.FormValue("delete_branch") != ""
+	if owner == "" || repo == "" || (sha == "" && !deleteBranch) {
+		errf(w, http.StatusBadRequest, "missing owner, repo or sha")
+		return
+	}
+	if !revertible(owner, repo) {
+		errf(w, http.StatusForbidden, "changing %s/%s is not permitted here", owner, repo)
+		return
+	}
+	branch := *revertBranch
+
+	if r.Method != "POST" {
+		buf := new(bytes.Buffer)
+		data := struct{ Owner, Repo, Branch, SHA, Lang, CSRF string }{owner, repo, branch, sha, langOf(w), csrfToken(w, r)}
+		if err := revertTmpl.Execute(buf, data); err != nil {
+			errf(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		io.Copy(w, buf)
+		return
+	}
+	if !checkCSRF(w, r) {
+		return
+	}
+
+	l := requestLogger(r).With("owner", owner, "repo", repo)
+	client, err := fixhub.NewClient(owner, repo, currentAccessToken())
+	if err != nil {
+		errf(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	client.Logger = l
+	// A repository whose default branch is the fix branch gets its fixes
+	// some other way, such as by people, and they aren't ours to undo.
+	def, err := client.DefaultBranch()
+	if err != nil {
+		errf(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	if def == branch {
+		errf(w, http.StatusForbidden, "not reverting on %s, the default branch of %s/%s", branch, owner, repo)
+		return
+	}
+	if qe := takeWriteQuota(r); qe != nil {
+		l.Warn("write quota exceeded", "requester", requester(r), "err", qe)
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(qe.retry).Seconds())+1))
+		if deleteBranch {
+			errf(w, http.StatusTooManyRequests, "Not deleting %s: %v", branch, qe)
+		} else {
+			errf(w, http.StatusTooManyRequests, "Not reverting %.7s: %v", sha, qe)
+		}
+		return
+	}
+	if deleteBranch {
+		auditWrites(r, client, "delete_branch", "")
+		deleted, why, err := client.DeleteFixBranch(branch)
+		if err != nil {
+			l.Error("deleting branch", "branch", branch, "err", err)
+			errf(w, http.StatusBadGateway, "deleting %s: %v", branch, err)
+			return
+		}
+		if !deleted {
+			errf(w, http.StatusConflict, "not deleting %s: %s", branch, why)
+			return
+		}
+		http.Redirect(w, r, "/github.com/"+owner+"/"+repo, http.StatusSeeOther)
+		return
+	}
+	auditWrites(r, client, "revert", sha)
+	rev, err := client.Revert(branch, sha)
+	if err != nil {
+		l.Warn("reverting", "sha", sha, "err", err)
+		errf(w, http.StatusConflict, "reverting %.7s on %s: %v", sha, branch, err)
+		return
+	}
+	http.Redirect(w, r, "/github.com/"+owner+"/"+repo+"@"+rev, http.StatusSeeOther)
+}
+
+var revertTmpl = template.Must(template.New("revert.html").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html lang="{{or .Lang "en"}}">
+<head>
+{{$repo := printf "%s/%s" .Owner .Repo}}
+{{with .SHA}}
+<title>{{msg $.Lang "fixhub: revert %.7s" .}}</title>
+{{else}}
+<title>{{msg .Lang "fixhub: delete %s" .Branch}}</title>
+{{end}}
+<link rel="stylesheet" type="text/css" href="{{asset "style.css"}}">
+</head>
+<body>
+{{with .SHA}}
+<div id="header">
+{{msgHTML $.Lang "Revert %s on %s of %s"
+	(link (printf "https://github.com/%s/commit/%s" $repo .) (printf "%.7s" .))
+	$.Branch
+	(link (printf "https://github.com/%s" $repo) $repo)}}
+</div>
+<p>{{msg $.Lang "This commits a change that undoes the fixes fixhub made in %.7s." .}}
+{{msg $.Lang "It is only done if the files they changed haven't changed since."}}</p>
+<form method="post">
+<input type="hidden" name="owner" value="{{$.Owner}}">
+<input type="hidden" name="repo" value="{{$.Repo}}">
+<input type="hidden" name="sha" value="{{.}}">
+<input type="hidden" name="csrf" value="{{$.CSRF}}">
+<button type="submit">{{msg $.Lang "Revert"}}</button>
+</form>
+{{end}}
+{{$link := link (printf "https://github.com/%s" $repo) $repo}}
+{{if .SHA}}
+<p>{{msgHTML .Lang "Or delete %s from %s instead, undoing all of fixhub's fixes at once." .Branch $link}}
+{{else}}
+<div id="header">
+{{msgHTML .Lang "Delete %s from %s" .Branch $link}}
+</div>
+<p>{{msg .Lang "This undoes all of fixhub's fixes at once."}}
+{{end}}
+{{msg .Lang "It is only done if fixhub made all the commits on it that aren't on the default branch."}}</p>
+<form method="post">
+<input type="hidden" name="owner" value="{{.Owner}}">
+<input type="hidden" name="repo" value="{{.Repo}}">
+<input type="hidden" name="delete_branch" value="1">
+<input type="hidden" name="csrf" value="{{.CSRF}}">
+<button type="submit">{{msg .Lang "Delete branch"}}</button>
+</form>
+</body>
+</html>
+`))
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+var secretKeyFile = flag.String("secret_key_file", "", "a file of hex-encoded 256-bit keys, one per line, with which to encrypt stored secrets; the first encrypts and the rest only decrypt, for rotation")
+
+// A secret is a string, such as a webhook URL, that is encrypted when stored
+// if -secret_key_file is set.
+//
+// Encrypted secrets are stored as "v1:" followed by the ID of the key
+// and the base64 encoding of the AES-GCM nonce and ciphertext.
+// Plaintext secrets stored before a key was configured are still read.
+type secret string
+
+var secretKeys struct {
+	sync.Mutex
+	keys  []secretKey // current key first
+	stale bool        // whether a secret was read that wasn't encrypted with the current key
+}
+
+type secretKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// loadSecretKeys reads -secret_key_file, if set.
+func loadSecretKeys() error {
+	if *secretKeyFile == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(*secretKeyFile)
+	if err != nil {
+		return err
+	}
+	var keys []secretKey
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		raw, err := hex.DecodeString(line)
+		if err != nil || len(raw) != 32 {
+			return fmt.Errorf("%s: keys must be 64 hex digits", *secretKeyFile)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(raw)
+		keys = append(keys, secretKey{id: hex.EncodeToString(sum[:4]), aead: aead})
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%s contains no keys", *secretKeyFile)
+	}
+	secretKeys.Lock()
+	secretKeys.keys = keys
+	secretKeys.Unlock()
+	return nil
+}
+
+// secretsStale reports whether any secret has been read that should be
+// written again, to encrypt it with the current key, and resets the report.
+func secretsStale() bool {
+	secretKeys.Lock()
+	defer secretKeys.Unlock()
+	stale := secretKeys.stale && len(secretKeys.keys) > 0
+	secretKeys.stale = false
+	return stale
+}
+
+func (s secret) MarshalJSON() ([]byte, error) {
+	secretKeys.Lock()
+	defer secretKeys.Unlock()
+	if s == "" || len(secretKeys.keys) == 0 {
+		return json.Marshal(string(s))
+	}
+	k := secretKeys.keys[0]
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := k.aead.Seal(nonce, nonce, []byte(s), []byte(k.id))
+	return json.Marshal("v1:" + k.id + ":" + base64.StdEncoding.EncodeToString(sealed))
+}
+
+func (s *secret) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	secretKeys.Lock()
+	defer secretKeys.Unlock()
+	if !strings.HasPrefix(str, "v1:") {
+		*s = secret(str)
+		secretKeys.stale = secretKeys.stale || str != ""
+		return nil
+	}
+	parts := strings.SplitN(str, ":", 3)
+	if len(parts) != 3 {
+		return errors.New("malformed encrypted secret")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("malformed encrypted secret: %v", err)
+	}
+	for i, k := range secretKeys.keys {
+		if k.id != parts[1] || len(sealed) < k.aead.NonceSize() {
+			continue
+		}
+		n := k.aead.NonceSize()
+		plain, err := k.aead.Open(nil, sealed[:n], sealed[n:], []byte(k.id))
+		if err != nil {
+			return fmt.Errorf("decrypting secret: %v", err)
+		}
+		*s = secret(plain)
+		secretKeys.stale = secretKeys.stale || i > 0
+		return nil
+	}
+	return fmt.Errorf("secret is encrypted with unknown key %s", parts[1])
+}
+
+// String prevents secrets from being logged or shown by accident.
+func (s secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "(secret)"
+}
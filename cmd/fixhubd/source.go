@@ -0,0 +1,108 @@
+package main
+
+import (
+	"go/scanner"
+	"go/token"
+	"html"
+	"html/template"
+	"strings"
+)
+
+// contextLines is how many lines of source are shown either side of a problem.
+const contextLines = 3
+
+// highlight returns the lines of a Go source file as HTML,
+// with keywords, literals and comments marked up for styling.
+// Tokens spanning lines are split so that each line's markup is complete.
+func highlight(src []byte) []template.HTML {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	var buf strings.Builder
+	emit := func(class, text string) {
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			if line == "" {
+				continue
+			}
+			if class == "" {
+				buf.WriteString(html.EscapeString(line))
+				continue
+			}
+			buf.WriteString(`<span class="` + class + `">` + html.EscapeString(line) + `</span>`)
+		}
+	}
+	off := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue // automatically inserted
+		}
+		start := file.Offset(pos)
+		if start < off {
+			continue
+		}
+		end := start + len(tok.String())
+		if lit != "" {
+			end = start + len(lit)
+		}
+		if end > len(src) {
+			end = len(src)
+		}
+		emit("", string(src[off:start]))
+		class := ""
+		switch {
+		case tok.IsKeyword():
+			class = "kw"
+		case tok == token.STRING || tok == token.CHAR:
+			class = "str"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = "num"
+		case tok == token.COMMENT:
+			class = "com"
+		}
+		emit(class, string(src[start:end]))
+		off = end
+	}
+	emit("", string(src[off:]))
+
+	var lines []template.HTML
+	for _, l := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		lines = append(lines, template.HTML(l))
+	}
+	return lines
+}
+
+// A snippetLine is one line of source shown around a problem.
+type snippetLine struct {
+	N    int // line number
+	HTML template.HTML
+	Mark bool // whether it's the line with the problem
+}
+
+// snippet returns the lines of a highlighted file around the given line.
+// It returns nil if the line is unknown.
+func snippet(lines []template.HTML, line int) []snippetLine {
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+	lo, hi := line-contextLines, line+contextLines
+	if lo < 1 {
+		lo = 1
+	}
+	if hi > len(lines) {
+		hi = len(lines)
+	}
+	var sl []snippetLine
+	for n := lo; n <= hi; n++ {
+		sl = append(sl, snippetLine{N: n, HTML: lines[n-1], Mark: n == line})
+	}
+	return sl
+}
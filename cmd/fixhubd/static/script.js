@@ -0,0 +1,24 @@
+// goproblems goes straight to the problems page of the repository that
+// the front page's form names, saving the round trip through /go.
+// It returns false if it did, and true to let the form be submitted,
+// which is also what happens without scripts.
+function goproblems(form) {
+	form = form || document.forms[0];
+	var path = form.repoText.value.trim().replace(/^\/+|\/+$/g, "");
+	if (!/^github\.com\/[^\/]+\/[^\/]+/.test(path)) {
+		return true; // let the server explain what's wrong
+	}
+	var enable = [];
+	for (var i = 0; i < form.elements.length; i++) {
+		var el = form.elements[i];
+		if (el.name == "check" && el.checked) {
+			enable.push(el.value);
+		}
+	}
+	var url = window.location.origin + "/" + path;
+	if (enable.length > 0) {
+		url += "?enable=" + enable.join(",");
+	}
+	window.location = url;
+	return false;
+}
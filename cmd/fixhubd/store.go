@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var dataDir = flag.String("data_dir", "", "directory in which to keep persistent state, such as ignored problems; if empty, none is kept")
+
+// persistent reports whether there is somewhere to keep state.
+// Features that need it, such as ignoring problems, are otherwise disabled.
+func persistent() bool { return *dataDir != "" }
+
+// loadJSON decodes the named file in -data_dir into v.
+// If the file does not exist, v is left alone.
+func loadJSON(name string, v interface{}) error {
+	b, err := ioutil.ReadFile(filepath.Join(*dataDir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// saveJSON encodes v into the named file in -data_dir.
+// The file is replaced atomically, so a crash can't leave it half written.
+func saveJSON(name string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(*dataDir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(*dataDir, name))
+}
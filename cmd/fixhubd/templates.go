@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var templatesDir = flag.String("templates", "", "a directory of templates and static files (e.g. problems.html, style.css) to use instead of the built-in ones; files in its static subdirectory are served under /static/")
+
+// templates are the templates that -templates may replace, by file name.
+// A replacement is executed with the same data, and may use the same functions.
+var templates = map[string]**template.Template{
+	"problems.html": &problemsTmpl,
+	"pending.html":  &pendingTmpl,
+	"error.html":    &errorTmpl,
+	"history.html":  &historyTmpl,
+	"ignored.html":  &ignoredTmpl,
+	"org.html":      &orgTmpl,
+	"revert.html":   &revertTmpl,
+	"audit.html":    &auditTmpl,
+}
+
+// pageFuncs are the template functions that every page template has.
+var pageFuncs = map[string]interface{}{
+	"asset":   assetURL,
+	"msg":     msg,
+	"msgHTML": msgHTML,
+	"link":    link,
+}
+
+// loadTemplates replaces the built-in templates with those in -templates, if set.
+func loadTemplates() error {
+	if *templatesDir == "" {
+		return nil
+	}
+	for name, t := range templates {
+		b, err := ioutil.ReadFile(filepath.Join(*templatesDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		nt, err := (*t).Clone()
+		if err != nil {
+			return err
+		}
+		if _, err := nt.Parse(string(b)); err != nil {
+			return fmt.Errorf("parsing %s: %v", name, err)
+		}
+		*t = nt
+		logger.Info("using template", "name", name, "dir", *templatesDir)
+	}
+	if dir := filepath.Join(*templatesDir, "static"); dirExists(dir) {
+		http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(dir))))
+	}
+	return nil
+}
+
+func dirExists(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+var (
+	httpsAddr       = flag.String("https", "", "HTTPS service address, or unix:/path/to/socket; if set, -http only redirects to HTTPS")
+	autocertDomains = flag.String("autocert_domains", "", "comma-separated domains for which to obtain certificates from Let's Encrypt")
+	autocertCache   = flag.String("autocert_cache", filepath.Join(os.Getenv("HOME"), ".fixhub-autocert"), "directory in which to cache Let's Encrypt certificates")
+	tlsCert         = flag.String("tls_cert", "", "TLS certificate file, if not using -autocert_domains")
+	tlsKey          = flag.String("tls_key", "", "TLS key file, if not using -autocert_domains")
+)
+
+// serveHTTPS serves h over HTTPS on -https, and redirects plain HTTP
+// requests on -http to it. Health checks are answered over plain HTTP too,
+// for the benefit of load balancers.
+func serveHTTPS(h http.Handler) {
+	srv := &http.Server{Addr: *httpsAddr, Handler: h}
+	redirect := http.HandlerFunc(redirectHTTPS)
+	var plain http.Handler = redirect
+	switch {
+	case *autocertDomains != "":
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(strings.Split(*autocertDomains, ",")...),
+			Cache:      autocert.DirCache(*autocertCache),
+		}
+		srv.TLSConfig = m.TLSConfig()
+		// The manager answers ACME HTTP challenges, and passes the rest on.
+		plain = m.HTTPHandler(redirect)
+	case *tlsCert != "" && *tlsKey != "":
+	default:
+		log.Fatal("-https requires either -autocert_domains, or -tls_cert and -tls_key")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.Handle("/", plain)
+	pl, err := listen(*httpAddr, 0)
+	if err != nil {
+		log.Fatalf("Listening on %s: %v", *httpAddr, err)
+	}
+	tl, err := listen(*httpsAddr, 1)
+	if err != nil {
+		log.Fatalf("Listening on %s: %v", *httpsAddr, err)
+	}
+	go func() {
+		log.Fatal(http.Serve(pl, logRequests(mux)))
+	}()
+
+	logger.Info("serving", "addr", tl.Addr().String(), "tls", true)
+	log.Fatal(srv.ServeTLS(tl, *tlsCert, *tlsKey))
+}
+
+func redirectHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h // drop the HTTP port
+	}
+	if _, port, err := net.SplitHostPort(*httpsAddr); err == nil && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	u := *r.URL
+	u.Scheme, u.Host = "https", host
+	http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+}
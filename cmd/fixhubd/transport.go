@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/dsymonds/fixhub"
+)
+
+var (
+	outboundProxy = flag.String("outbound_proxy", "", "if set, the URL of a proxy to reach GitHub and webhooks through, instead of $HTTPS_PROXY")
+	outboundCAs   = flag.String("outbound_ca_file", "", "if set, a PEM file of extra CA certificates to trust for outbound requests, such as a proxy's")
+)
+
+// setupTransport makes fixhub.DefaultTransport, which all outbound requests
+// go through, use the proxy and CAs given by the flags.
+func setupTransport() error {
+	var proxy func(*http.Request) (*url.URL, error)
+	if *outboundProxy != "" {
+		u, err := url.Parse(*outboundProxy)
+		if err != nil {
+			return fmt.Errorf("bad -outbound_proxy: %v", err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	var tlsConfig *tls.Config
+	if *outboundCAs != "" {
+		pem, err := ioutil.ReadFile(*outboundCAs)
+		if err != nil {
+			return err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", *outboundCAs)
+		}
+		tlsConfig = &tls.Config{RootCAs: pool}
+	}
+	if proxy != nil || tlsConfig != nil {
+		fixhub.DefaultTransport = fixhub.NewTransport(proxy, tlsConfig)
+	}
+	return nil
+}
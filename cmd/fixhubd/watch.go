@@ -0,0 +1,250 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsymonds/fixhub"
+)
+
+var (
+	recheckInterval = flag.Duration("recheck_interval", 6*time.Hour, "how often to re-check watched repositories")
+	maxWatches      = flag.Int("max_watches", 100, "how many repositories may be watched at once")
+)
+
+const watchedFile = "watched.json"
+
+// A watch is a repository that is re-checked regularly.
+type watch struct {
+	Since time.Time `json:"since"` // when it started being watched
+
+	// Where to send notifications of new problems,
+	// besides the email addresses the configuration gives.
+	Slack secret `json:"slack,omitempty"` // incoming webhook URL
+}
+
+var watched struct {
+	sync.Mutex
+	m map[string]watch // "owner/repo" -> watch
+}
+
+func loadWatched() error {
+	watched.Lock()
+	defer watched.Unlock()
+	watched.m = make(map[string]watch)
+	if err := loadJSON(watchedFile, &watched.m); err != nil {
+		return err
+	}
+	if secretsStale() {
+		// Encrypt with the current key.
+		return saveJSON(watchedFile, watched.m)
+	}
+	return nil
+}
+
+// watchOf returns the watch of a repository, and whether it is watched.
+func watchOf(owner, repo string) (watch, bool) {
+	watched.Lock()
+	defer watched.Unlock()
+	wt, ok := watched.m[owner+"/"+repo]
+	return wt, ok
+}
+
+// watchHandler starts or stops watching a repository, or changes where
+// notifications for it go, then returns to its page.
+// Without sign-in, anyone who can reach fixhubd can change these settings
+// for the repositories the configuration lets be watched.
+func watchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !persistent() {
+		errf(w, http.StatusMethodNotAllowed, "can't watch repositories here")
+		return
+	}
+	if !checkCSRF(w, r) {
+		return
+	}
+	owner, repo := r.FormValue("owner"), r.FormValue("repo")
+	if owner == "" || repo == "" {
+		errf(w, http.StatusBadRequest, "missing owner or repo")
+		return
+	}
+	slack := strings.TrimSpace(r.FormValue("slack"))
+	if slack != "" {
+		if err := checkWebhook(slack); err != nil {
+			errf(w, http.StatusBadRequest, "bad Slack webhook URL: %v", err)
+			return
+		}
+	}
+	if !watchable(owner, repo) {
+		errf(w, http.StatusForbidden, "watching %s/%s is not permitted here", owner, repo)
+		return
+	}
+	key := owner + "/" + repo
+
+	watched.Lock()
+	if r.FormValue("unwatch") != "" {
+		delete(watched.m, key)
+	} else {
+		wt, ok := watched.m[key]
+		if !ok {
+			if len(watched.m) >= *maxWatches {
+				watched.Unlock()
+				errf(w, http.StatusServiceUnavailable, "no more repositories can be watched here")
+				return
+			}
+			wt.Since = time.Now()
+		}
+		if r.FormValue("notify") != "" {
+			if slack != "" || r.FormValue("clear_slack") != "" {
+				wt.Slack = secret(slack)
+			}
+		}
+		watched.m[key] = wt
+	}
+	err := saveJSON(watchedFile, watched.m)
+	watched.Unlock()
+	if err != nil {
+		requestLogger(r).Error("saving watched repositories", "err", err)
+		errf(w, http.StatusInternalServerError, "saving watched repositories failed")
+		return
+	}
+	http.Redirect(w, r, "/github.com/"+key, http.StatusSeeOther)
+}
+
+// A lastResult is the most recent check of a whole repository,
+// along with the problems at the commit checked before that,
+// so that newly introduced problems can be highlighted.
+type lastResult struct {
+	SHA      string          `json:"sha"`
+	Time     time.Time       `json:"time"`
+	Problems fixhub.Problems `json:"problems"`
+	Prev     fixhub.Problems `json:"prev"`
+}
+
+var results sync.Mutex // serializes access to the result files
+
+func resultFile(owner, repo string) string {
+	return "result-" + url.PathEscape(owner+"/"+repo) + ".json"
+}
+
+// recordResult records the result of checking a whole repository at the given commit
+// in its history and as its last result. It returns the problems
+// that were not present at the previous commit checked. Problems in files
+// that client finds were renamed since then are not new, and ignored ones
+// follow their files.
+func recordResult(client *fixhub.Client, l *slog.Logger, owner, repo, sha string, ps fixhub.Problems) (fixhub.Problems, error) {
+	if err := recordCheck(owner, repo, sha, ps); err != nil {
+		return nil, err
+	}
+
+	// Renames come from GitHub, so they are found before results is locked.
+	prev, err := loadResult(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var renames map[string]string
+	if prev != nil && prev.SHA != sha {
+		if renames, err = client.Renames(prev.SHA, sha); err != nil {
+			l.Warn("finding renamed files", "err", err)
+		}
+	}
+	if len(renames) > 0 {
+		if err := renameIgnored(owner, repo, renames); err != nil {
+			return nil, err
+		}
+	}
+
+	results.Lock()
+	defer results.Unlock()
+	var last lastResult
+	if err := loadJSON(resultFile(owner, repo), &last); err != nil {
+		return nil, err
+	}
+	if last.SHA != sha {
+		last.Prev = last.Problems
+		if len(renames) > 0 && last.SHA == prev.SHA {
+			last.Prev = last.Prev.Renamed(renames)
+		}
+	}
+	last.SHA, last.Time, last.Problems = sha, time.Now(), ps
+
+	var added fixhub.Problems
+	if last.Prev != nil {
+		added, _ = fixhub.Diff(last.Prev, ps)
+	}
+	return added, saveJSON(resultFile(owner, repo), last)
+}
+
+// loadResult returns the last result for a repository, or nil if there is none.
+func loadResult(owner, repo string) (*lastResult, error) {
+	results.Lock()
+	defer results.Unlock()
+	var last lastResult
+	if err := loadJSON(resultFile(owner, repo), &last); err != nil {
+		return nil, err
+	}
+	if last.SHA == "" {
+		return nil, nil
+	}
+	return &last, nil
+}
+
+// recheckWatched re-checks the watched repositories every -recheck_interval.
+func recheckWatched() {
+	for range time.Tick(*recheckInterval) {
+		watched.Lock()
+		var keys []string
+		for key := range watched.m {
+			keys = append(keys, key)
+		}
+		watched.Unlock()
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			owner, repo, _ := strings.Cut(key, "/")
+			wt, ok := watchOf(owner, repo)
+			if !ok || !watchable(owner, repo) {
+				continue // unwatched meanwhile, or no longer permitted
+			}
+			l := logger.With("owner", owner, "repo", repo, "scheduled", true)
+			client, err := fixhub.NewClient(owner, repo, currentAccessToken())
+			if err != nil {
+				l.Error("creating client", "err", err)
+				continue
+			}
+			def := defaultClient()
+			client.Enabled, client.Disabled = def.Enabled, def.Disabled
+			client.Logger = l
+			limit(client)
+
+			t0 := time.Now()
+			rev := *rev
+			if rev == "" {
+				if rev, err = client.DefaultBranch(); err != nil {
+					l.Error("finding default branch", "err", err)
+					continue
+				}
+			}
+			res, err := client.Run(rev)
+			if err != nil {
+				l.Error("check failed", "rev", rev, "err", err)
+				continue
+			}
+			added, err := recordResult(client, l, owner, repo, res.SHA, res.Problems)
+			if err != nil {
+				l.Error("recording result", "err", err)
+				continue
+			}
+			l.Info("checked", "rev", rev, "sha1", res.SHA, "problems", len(res.Problems), "new", len(added), "duration", time.Since(t0))
+			added, _ = filterIgnored(owner, repo, added)
+			if len(added) > 0 {
+				notify(l, owner, repo, res.SHA, added, wt)
+			}
+		}
+	}
+}
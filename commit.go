@@ -0,0 +1,298 @@
+package fixhub
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Commit commits new contents for files, keyed by path, to the named branch
+// as a single commit on top of the commit parent.
+// It returns the SHA-1 ID of the new commit.
+//
+// If the branch has moved on from parent, the changes that files make to
+// parent are rebased onto its head, as by RebaseEdits, and committed on top.
+// Commit fails if they conflict with what else was committed, rather than
+// discard it. Commit also fails if any of the files are protected from fixes,
+// as for Client.ProtectedPaths.
+// Files are committed as regular files, not executables.
+func (c *Client) Commit(branch, parent, message string, files map[string][]byte) (sha1 string, err error) {
+	head, err := c.ResolveRef(branch)
+	if err != nil {
+		return "", fmt.Errorf("resolving %q: %v", branch, err)
+	}
+	if head != parent {
+		if files, err = c.rebaseFiles(parent, head, files); err != nil {
+			return "", fmt.Errorf("%s has moved on from %.7s: %v", branch, parent, err)
+		}
+		c.debug("rebased commit", "branch", branch, "parent", parent, "head", head)
+		parent = head
+	}
+
+	start := time.Now()
+	pc, resp, err := c.gc.Repositories.GetCommit(c.owner, c.repo, parent)
+	c.fetched("commit", start, resp, err)
+	if err != nil {
+		return "", fmt.Errorf("fetching commit %s: %v", parent, err)
+	}
+	if pc.Commit == nil || pc.Commit.Tree == nil || pc.Commit.Tree.SHA == nil {
+		return "", fmt.Errorf("commit %s has no tree", parent)
+	}
+	start = time.Now()
+	top, resp, err := c.gc.Git.GetTree(c.owner, c.repo, *pc.Commit.Tree.SHA, false)
+	c.fetched("tree", start, resp, err)
+	if err != nil {
+		return "", fmt.Errorf("fetching tree of %s: %v", parent, err)
+	}
+	cfg, err := c.loadConfig(top)
+	if err != nil {
+		return "", err
+	}
+
+	var paths []string
+	for path := range files {
+		if c.protected(cfg, path) {
+			return "", fmt.Errorf("%s is protected from fixes", path)
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var entries []github.TreeEntry
+	for _, path := range paths {
+		entries = append(entries, github.TreeEntry{
+			Path:    github.String(path),
+			Mode:    github.String("100644"),
+			Type:    github.String("blob"),
+			Content: github.String(string(files[path])),
+		})
+	}
+	sha1, err = c.commitTree(branch, parent, *pc.Commit.Tree.SHA, message, entries)
+	if err != nil {
+		return "", err
+	}
+	c.debug("committed", "branch", branch, "sha1", sha1, "files", len(files))
+	return sha1, nil
+}
+
+// rebaseFiles returns the new contents for files, keyed by path, that make
+// the same changes to the commit head as files do to the commit parent.
+func (c *Client) rebaseFiles(parent, head string, files map[string][]byte) (map[string][]byte, error) {
+	rebased := make(map[string][]byte)
+	for path, content := range files {
+		base, baseSHA, err := c.GetFileAtRev(path, parent)
+		if err != nil {
+			return nil, err
+		}
+		latest, latestSHA, err := c.GetFileAtRev(path, head)
+		if err != nil {
+			return nil, err
+		}
+		if latestSHA == baseSHA {
+			rebased[path] = content
+			continue
+		}
+		edits, err := RebaseEdits(base, latest, lineEdits(base, content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if rebased[path], err = ApplyEdits(latest, edits); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return rebased, nil
+}
+
+// commitTree commits the tree made by applying entries to baseTree
+// to the named branch, as a commit whose parent is parent.
+// The branch must still point at parent.
+func (c *Client) commitTree(branch, parent, baseTree, message string, entries []github.TreeEntry) (string, error) {
+	tree, _, err := c.gc.Git.CreateTree(c.owner, c.repo, baseTree, entries)
+	if err != nil {
+		return "", fmt.Errorf("creating tree: %v", err)
+	}
+	commit, _, err := c.gc.Git.CreateCommit(c.owner, c.repo, &github.Commit{
+		Message: github.String(message),
+		Tree:    &github.Tree{SHA: tree.SHA},
+		Parents: []github.Commit{{SHA: github.String(parent)}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating commit: %v", err)
+	}
+	ref := &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: commit.SHA},
+	}
+	if _, _, err := c.gc.Git.UpdateRef(c.owner, c.repo, ref, false); err != nil {
+		return "", fmt.Errorf("updating branch %s (has it moved on from %s?): %v", branch, parent, err)
+	}
+	w := Write{Action: "commit", Repo: c.owner + "/" + c.repo, Branch: branch, SHA: *commit.SHA}
+	for _, ent := range entries {
+		w.Files = append(w.Files, *ent.Path)
+	}
+	c.wrote(w)
+	return *commit.SHA, nil
+}
+
+// IsFixCommit reports whether a commit message is that of a commit of fixes
+// made by fixhub, such as by CommitFixes.
+func IsFixCommit(message string) bool {
+	subject, _, _ := strings.Cut(message, "\n")
+	return strings.HasPrefix(subject, "Fix ") && strings.HasSuffix(subject, " found by fixhub")
+}
+
+// isFixRevert reports whether a commit message is that of a commit made by Revert.
+func isFixRevert(message string) bool {
+	subject, _, _ := strings.Cut(message, "\n")
+	rest, ok := strings.CutPrefix(subject, "Revert ")
+	if !ok {
+		return false
+	}
+	reverted, err := strconv.Unquote(rest)
+	return err == nil && IsFixCommit(reverted)
+}
+
+// Revert undoes a commit of fixes made by fixhub on the named branch,
+// with a new commit that restores the files it changed.
+// It fails if sha1 is not such a commit, or if any of the files
+// it changed have changed again since, rather than discard those changes.
+// It returns the SHA-1 ID of the new commit.
+func (c *Client) Revert(branch, sha1 string) (string, error) {
+	start := time.Now()
+	rc, resp, err := c.gc.Repositories.GetCommit(c.owner, c.repo, sha1)
+	c.fetched("commit", start, resp, err)
+	if err != nil {
+		return "", fmt.Errorf("fetching commit %s: %v", sha1, err)
+	}
+	if rc.SHA == nil || rc.Commit == nil || rc.Commit.Message == nil || !IsFixCommit(*rc.Commit.Message) {
+		return "", fmt.Errorf("commit %s was not made by fixhub", sha1)
+	}
+	if len(rc.Parents) != 1 || rc.Parents[0].SHA == nil {
+		return "", fmt.Errorf("commit %s does not have a single parent", sha1)
+	}
+	sha1 = *rc.SHA
+	subject, _, _ := strings.Cut(*rc.Commit.Message, "\n")
+
+	head, err := c.ResolveRef(branch)
+	if err != nil {
+		return "", fmt.Errorf("resolving %q: %v", branch, err)
+	}
+	blobs := make([]map[string]string, 3) // path -> blob SHA-1, before, after and now
+	var headTree string
+	for i, rev := range []string{*rc.Parents[0].SHA, sha1, head} {
+		tree, err := c.GetTree(rev)
+		if err != nil {
+			return "", fmt.Errorf("fetching tree of %s: %v", rev, err)
+		}
+		if tree.Truncated != nil && *tree.Truncated {
+			return "", fmt.Errorf("tree of %s is too large to list", rev)
+		}
+		blobs[i] = make(map[string]string)
+		for _, ent := range tree.Entries {
+			if ent.Path != nil && ent.SHA != nil && ent.Type != nil && *ent.Type == "blob" {
+				blobs[i][*ent.Path] = *ent.SHA
+			}
+		}
+		if tree.SHA != nil {
+			headTree = *tree.SHA
+		}
+	}
+	before, after, now := blobs[0], blobs[1], blobs[2]
+
+	var paths []string
+	for path, sha := range after {
+		if before[path] != sha {
+			paths = append(paths, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	var entries []github.TreeEntry
+	for _, path := range paths {
+		if now[path] != after[path] {
+			return "", fmt.Errorf("%s has changed on %s since %.7s", path, branch, sha1)
+		}
+		if before[path] == "" {
+			return "", fmt.Errorf("%.7s added %s, which fixhub does not do", sha1, path)
+		}
+		entries = append(entries, github.TreeEntry{
+			Path: github.String(path),
+			Mode: github.String("100644"),
+			Type: github.String("blob"),
+			SHA:  github.String(before[path]),
+		})
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("commit %s changed nothing", sha1)
+	}
+	msg := fmt.Sprintf("Revert %q\n\nThis reverts commit %s.\n", subject, sha1)
+	rev, err := c.commitTree(branch, head, headTree, msg, entries)
+	if err != nil {
+		return "", err
+	}
+	c.debug("reverted", "branch", branch, "sha1", sha1, "revert", rev, "files", len(entries))
+	return rev, nil
+}
+
+// DeleteFixBranch deletes the named branch if all the commits on it that are not
+// on the default branch were made by fixhub, as commits of fixes or reverts of them,
+// for backing out all of fixhub's changes at once.
+// It reports whether it deleted the branch, and if not, why not.
+func (c *Client) DeleteFixBranch(branch string) (deleted bool, why string, err error) {
+	def, err := c.DefaultBranch()
+	if err != nil {
+		return false, "", err
+	}
+	if branch == def {
+		return false, fmt.Sprintf("%s is the default branch", branch), nil
+	}
+	start := time.Now()
+	ref, resp, err := c.gc.Git.GetRef(c.owner, c.repo, "heads/"+branch)
+	c.fetched("repos", start, resp, err)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, fmt.Sprintf("there is no branch %s", branch), nil
+		}
+		return false, "", fmt.Errorf("fetching branch %s: %v", branch, err)
+	}
+	if ref.Object == nil || ref.Object.SHA == nil {
+		return false, "", fmt.Errorf("branch %s has no commit", branch)
+	}
+	head := *ref.Object.SHA
+
+	start = time.Now()
+	cmp, resp, err := c.gc.Repositories.CompareCommits(c.owner, c.repo, def, head)
+	c.fetched("compare", start, resp, err)
+	if err != nil {
+		return false, "", fmt.Errorf("comparing %s to %s: %v", branch, def, err)
+	}
+	if cmp.TotalCommits != nil && *cmp.TotalCommits > len(cmp.Commits) {
+		return false, fmt.Sprintf("%s has too many commits to look through", branch), nil
+	}
+	for _, rc := range cmp.Commits {
+		if rc.SHA == nil || rc.Commit == nil || rc.Commit.Message == nil {
+			return false, "", fmt.Errorf("comparing %s to %s: a commit has no message", branch, def)
+		}
+		if msg := *rc.Commit.Message; !IsFixCommit(msg) && !isFixRevert(msg) {
+			return false, fmt.Sprintf("commit %.7s on %s was not made by fixhub", *rc.SHA, branch), nil
+		}
+	}
+
+	start = time.Now()
+	resp, err = c.gc.Git.DeleteRef(c.owner, c.repo, "heads/"+branch)
+	c.fetched("repos", start, resp, err)
+	if err != nil {
+		return false, "", fmt.Errorf("deleting branch %s: %v", branch, err)
+	}
+	c.debug("deleted branch", "branch", branch, "sha1", head, "commits", len(cmp.Commits))
+	c.wrote(Write{Action: "delete", Repo: c.owner + "/" + c.repo, Branch: branch, SHA: head})
+	return true, "", nil
+}
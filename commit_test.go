@@ -0,0 +1,178 @@
+package fixhub
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestCommit(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	base := srv.AddRepo("faker", "commit", map[string][]byte{
+		"a.go":      []byte("package a\n"),
+		"b/b.go":    []byte("package b\n"),
+		"c/keep.go": []byte("package c\n"),
+		"d.go":      []byte("package d\n\nvar x = 1\n\nvar y = 2\n"),
+	})
+	c, err := NewClient("faker", "commit", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	var writes []Write
+	c.Hooks.OnWrite = func(w Write) { writes = append(writes, w) }
+
+	files := map[string][]byte{
+		"a.go":   []byte("package a // fixed\n"),
+		"b/b.go": []byte("package b // fixed\n"),
+	}
+	sha, err := c.Commit("master", base, "Fix things", files)
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	want := []Write{{Action: "commit", Repo: "faker/commit", Branch: "master", SHA: sha, Files: []string{"a.go", "b/b.go"}}}
+	if !reflect.DeepEqual(writes, want) {
+		t.Errorf("writes = %+v, want %+v", writes, want)
+	}
+	for path, want := range files {
+		if got, _ := srv.File("faker", "commit", "master", path); string(got) != string(want) {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+	if got, _ := srv.File("faker", "commit", "master", "c/keep.go"); string(got) != "package c\n" {
+		t.Errorf("c/keep.go = %q, want it unchanged", got)
+	}
+	if got, want := srv.CommitMessages("faker", "commit", "master"), []string{"Fix things", "Initial commit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commits = %q, want %q", got, want)
+	}
+
+	// Vendored files are protected from fixes.
+	if _, err := c.Commit("master", base, "Fix vendor", map[string][]byte{"vendor/v.go": []byte("package v\n")}); err == nil {
+		t.Errorf("Commit to a protected file succeeded")
+	}
+
+	// The branch has moved on from base, so this must not clobber it.
+	if _, err := c.Commit("master", base, "Stale fix", files); err == nil {
+		t.Errorf("Commit on top of a stale parent succeeded")
+	}
+
+	// A change that does not conflict is rebased onto the branch.
+	if _, err := c.Commit("master", sha, "Change x", map[string][]byte{"d.go": []byte("package d\n\nvar x = 10\n\nvar y = 2\n")}); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if _, err := c.Commit("master", sha, "Change y", map[string][]byte{"d.go": []byte("package d\n\nvar x = 1\n\nvar y = 20\n")}); err != nil {
+		t.Fatalf("Commit on top of a moved branch: %v", err)
+	}
+	if got, want := srv.CommitMessages("faker", "commit", "master"), []string{"Change y", "Change x", "Fix things", "Initial commit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commits = %q, want %q", got, want)
+	}
+	if got, _ := srv.File("faker", "commit", "master", "d.go"); string(got) != "package d\n\nvar x = 10\n\nvar y = 20\n" {
+		t.Errorf("d.go = %q, want both changes", got)
+	}
+}
+
+func TestRevert(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	base := srv.AddRepo("faker", "revert", map[string][]byte{
+		"a.go": []byte("package a\n"),
+		"b.go": []byte("package b\n"),
+	})
+	c, err := NewClient("faker", "revert", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	fix, err := c.Commit("master", base, "Fix a problem found by fixhub\n\na.go:1: bad\n", map[string][]byte{
+		"a.go": []byte("package a // fixed\n"),
+	})
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	other, err := c.Commit("master", fix, "Change b", map[string][]byte{
+		"b.go": []byte("package b // changed\n"),
+	})
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if _, err := c.Revert("master", other); err == nil {
+		t.Errorf("Revert of a commit not made by fixhub succeeded")
+	}
+
+	if _, err := c.Revert("master", fix); err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+	for path, want := range map[string]string{"a.go": "package a\n", "b.go": "package b // changed\n"} {
+		if got, _ := srv.File("faker", "revert", "master", path); string(got) != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+	msgs := srv.CommitMessages("faker", "revert", "master")
+	if len(msgs) != 4 || !strings.HasPrefix(msgs[0], `Revert "Fix a problem found by fixhub"`) {
+		t.Errorf("commits = %q, want a revert on top", msgs)
+	}
+
+	// a.go has changed since, so reverting again must fail.
+	if _, err := c.Revert("master", fix); err == nil {
+		t.Errorf("Revert of a commit whose files have changed since succeeded")
+	}
+}
+
+func TestDeleteFixBranch(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "branch", map[string][]byte{
+		"a.go": []byte("package a\n"),
+		"b.go": []byte("package b\n"),
+	})
+	push := func(branch, message, path, content string) {
+		t.Helper()
+		if _, err := srv.Push("faker", "branch", branch, message, map[string][]byte{path: []byte(content)}); err != nil {
+			t.Fatalf("Push: %v", err)
+		}
+	}
+	push("fixhub", "Fix 1 problem found by fixhub", "a.go", "package a // fixed\n")
+	push("fixhub", `Revert "Fix 1 problem found by fixhub"`, "a.go", "package a\n")
+	push("fixhub", "Fix 2 problems found by fixhub", "b.go", "package b // fixed\n")
+	push("mixed", "Fix 1 problem found by fixhub", "a.go", "package a // fixed\n")
+	push("mixed", "Change b", "b.go", "package b // changed\n")
+	push("master", "Change a", "a.go", "package a // changed\n") // leaving fixhub behind
+
+	c, err := NewClient("faker", "branch", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	var writes []Write
+	c.Hooks.OnWrite = func(w Write) { writes = append(writes, w) }
+
+	for _, branch := range []string{"mixed", "master", "missing"} {
+		if deleted, why, err := c.DeleteFixBranch(branch); deleted || why == "" || err != nil {
+			t.Errorf("DeleteFixBranch(%q) = %v, %q, %v; want a reason not to", branch, deleted, why, err)
+		}
+	}
+	if _, ok := srv.File("faker", "branch", "mixed", "a.go"); !ok {
+		t.Errorf("branch mixed was deleted")
+	}
+
+	if deleted, why, err := c.DeleteFixBranch("fixhub"); !deleted || err != nil {
+		t.Fatalf("DeleteFixBranch(%q) = %v, %q, %v; want it deleted", "fixhub", deleted, why, err)
+	}
+	if _, ok := srv.File("faker", "branch", "fixhub", "a.go"); ok {
+		t.Errorf("branch fixhub is still there")
+	}
+	if len(writes) != 1 || writes[0].Action != "delete" || writes[0].Repo != "faker/branch" || writes[0].Branch != "fixhub" || writes[0].SHA == "" {
+		t.Errorf("writes = %+v, want the deletion of fixhub", writes)
+	}
+}
@@ -0,0 +1,151 @@
+package fixhub
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFile is the name of the optional per-repository configuration file,
+// which is read from the top level of the repository being checked.
+const ConfigFile = ".fixhub.yml"
+
+// Config is the per-repository configuration in ConfigFile.
+//
+// An example:
+//
+//	platforms: [linux/amd64, js/wasm]
+//	lint:
+//	  disable: [comments, naming]
+//	fieldalignment:
+//	  reorder: true
+//	  keep: [header]
+//	doc:
+//	  min_coverage: 0.8
+//	unused:
+//	  tests: true
+//	fix:
+//	  protect: [third_party/, "*_string.go"]
+type Config struct {
+	// Platforms lists GOOS/GOARCH combinations to check, overriding Client.Platforms.
+	Platforms []string `yaml:"platforms"`
+
+	Lint struct {
+		// Disable lists golint categories to ignore, from LintCategories.
+		Disable []string `yaml:"disable"`
+	} `yaml:"lint"`
+
+	FieldAlignment struct {
+		// Reorder permits Fix to reorder the fields of unexported struct types.
+		// Exported struct types are part of the package's API, so are never reordered.
+		Reorder bool `yaml:"reorder"`
+		// Keep lists struct types whose fields must not be reordered,
+		// such as those whose layout matters for encoding or cgo.
+		Keep []string `yaml:"keep"`
+	} `yaml:"fieldalignment"`
+
+	Doc struct {
+		// MinCoverage is the fraction of exported identifiers
+		// that each package should document, from 0 to 1.
+		MinCoverage float64 `yaml:"min_coverage"`
+	} `yaml:"doc"`
+
+	Unused struct {
+		// Main and Tests include exported identifiers declared in
+		// main packages and _test.go files, which are otherwise ignored.
+		Main  bool `yaml:"main"`
+		Tests bool `yaml:"tests"`
+	} `yaml:"unused"`
+
+	Fix struct {
+		// Protect lists patterns of paths that fixes must never change,
+		// as for ProtectedPath, in addition to Client.ProtectedPaths.
+		Protect []string `yaml:"protect"`
+	} `yaml:"fix"`
+}
+
+// canReorder reports whether the configuration allows Fix to reorder
+// the fields of the named struct type.
+func (cfg *Config) canReorder(name string) bool {
+	if !cfg.FieldAlignment.Reorder || ast.IsExported(name) {
+		return false
+	}
+	for _, k := range cfg.FieldAlignment.Keep {
+		if k == name {
+			return false
+		}
+	}
+	return true
+}
+
+// LintCategories are the categories of problem that golint reports,
+// for Client.DisabledLintCategories and Config.
+var LintCategories = []string{
+	"arg-order", "comments", "context", "errors", "imports", "indent", "naming",
+	"range-loop", "time", "type-inference", "unary-op", "unexported-type-in-api", "zero-value",
+}
+
+// ParseLintCategories parses a comma-separated list of golint categories,
+// such as "comments,naming". An empty string yields an empty list.
+func ParseLintCategories(s string) ([]string, error) {
+	var cats []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if err := checkLintCategory(f); err != nil {
+			return nil, err
+		}
+		cats = append(cats, f)
+	}
+	return cats, nil
+}
+
+func checkLintCategory(cat string) error {
+	for _, c := range LintCategories {
+		if c == cat {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown lint category %q", cat)
+}
+
+// loadConfig loads the repository configuration from tree.
+// It returns an empty Config if the repository has no ConfigFile.
+func (c *Client) loadConfig(tree *github.Tree) (*Config, error) {
+	for _, ent := range tree.Entries {
+		if ent.Path == nil || *ent.Path != ConfigFile || ent.SHA == nil {
+			continue
+		}
+		data, err := c.GetBlob(*ent.SHA)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %v", ConfigFile, err)
+		}
+		cfg, err := parseConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %v", ConfigFile, err)
+		}
+		return cfg, nil
+	}
+	return new(Config), nil
+}
+
+// parseConfig parses the content of a ConfigFile.
+// Unknown keys and lint categories are errors, rather than silently
+// leaving a misspelt setting with no effect.
+func parseConfig(data []byte) (*Config, error) {
+	cfg := new(Config)
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, err
+	}
+	for _, cat := range cfg.Lint.Disable {
+		if err := checkLintCategory(cat); err != nil {
+			return nil, fmt.Errorf("lint.disable: %v", err)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,106 @@
+package fixhub
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		desc string
+		data string
+		err  string // a substring of the error, if one is expected
+		lint []string
+	}{
+		{desc: "empty", data: ""},
+		{
+			desc: "the documented example",
+			data: `platforms: [linux/amd64, js/wasm]
+lint:
+  disable: [comments, naming]
+fieldalignment:
+  reorder: true
+  keep: [header]
+doc:
+  min_coverage: 0.8
+unused:
+  tests: true
+fix:
+  protect: [third_party/, "*_string.go"]
+`,
+			lint: []string{"comments", "naming"},
+		},
+		{desc: "unknown key", data: "lint:\n  disable: [comments]\nlnit:\n  disable: [naming]\n", err: "lnit"},
+		{desc: "unknown nested key", data: "lint:\n  disabled: [comments]\n", err: "disabled"},
+		{desc: "unknown category", data: "lint:\n  disable: [comments, nameing]\n", err: `unknown lint category "nameing"`},
+		{desc: "not YAML", data: "lint: [\n", err: "yaml"},
+		{desc: "wrong type", data: "lint:\n  disable: comments\n", err: "unmarshal"},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte(tt.data))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one mentioning %q", tt.desc, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.desc, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.Lint.Disable, tt.lint) {
+			t.Errorf("%s: lint.disable = %q, want %q", tt.desc, cfg.Lint.Disable, tt.lint)
+		}
+	}
+}
+
+func TestParseLintCategories(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		ok   bool
+	}{
+		{"", nil, true},
+		{"comments", []string{"comments"}, true},
+		{" comments, naming ,", []string{"comments", "naming"}, true},
+		{"comments,nameing", nil, false},
+		{"Comments", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseLintCategories(tt.in)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLintCategories(%q) = %q, %v; want %q, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDisabledLintCategories(t *testing.T) {
+	files := map[string][]byte{
+		"a.go": []byte("// Package a is for testing.\npackage a\n\nfunc F() {}\n\n// New returns a t.\nfunc New() *t { return nil }\n\ntype t struct{}\n"),
+	}
+	tests := []struct {
+		client, repo []string // categories disabled by the Client and the Config
+		want         []string // categories reported
+	}{
+		{nil, nil, []string{"comments", "unexported-type-in-api"}},
+		{[]string{"comments"}, nil, []string{"unexported-type-in-api"}},
+		{nil, []string{"unexported-type-in-api"}, []string{"comments"}},
+		{[]string{"comments"}, []string{"unexported-type-in-api"}, nil},
+		{[]string{"naming"}, []string{"naming"}, []string{"comments", "unexported-type-in-api"}},
+	}
+	for _, tt := range tests {
+		c := &Client{DisabledLintCategories: tt.client}
+		cfg := new(Config)
+		cfg.Lint.Disable = tt.repo
+		var got []string
+		for _, p := range c.lint(files, cfg) {
+			got = append(got, p.Code)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("disabling %q and %q reported %q, want %q", tt.client, tt.repo, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package fixhub
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A deprecatedPackage records the deprecated package-level identifiers of a package.
+type deprecatedPackage struct {
+	name string            // package name
+	ids  map[string]string // identifier -> deprecation notice
+}
+
+// deprecationNotice returns the text of the "Deprecated:" paragraph of a doc comment,
+// or the empty string if there is none.
+func deprecationNotice(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	for _, para := range strings.Split(doc.Text(), "\n\n") {
+		if strings.HasPrefix(para, "Deprecated: ") {
+			return strings.Join(strings.Fields(strings.TrimPrefix(para, "Deprecated: ")), " ")
+		}
+	}
+	return ""
+}
+
+// findDeprecated adds the deprecated package-level identifiers declared in f to ids.
+func findDeprecated(f *ast.File, ids map[string]string) {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil {
+				continue // methods need type information
+			}
+			if msg := deprecationNotice(decl.Doc); msg != "" {
+				ids[decl.Name.Name] = msg
+			}
+		case *ast.GenDecl:
+			group := deprecationNotice(decl.Doc)
+			for _, spec := range decl.Specs {
+				var names []*ast.Ident
+				var doc *ast.CommentGroup
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names, doc = []*ast.Ident{spec.Name}, spec.Doc
+				case *ast.ValueSpec:
+					names, doc = spec.Names, spec.Doc
+				}
+				msg := deprecationNotice(doc)
+				if msg == "" {
+					msg = group
+				}
+				if msg == "" {
+					continue
+				}
+				for _, name := range names {
+					ids[name.Name] = msg
+				}
+			}
+		}
+	}
+}
+
+var stdDeprecated struct {
+	sync.Mutex
+	m map[string]*deprecatedPackage // import path -> deprecations; nil if not found
+}
+
+// stdDeprecations returns the deprecations in a standard library package,
+// read from the local GOROOT. It returns nil if the package can't be found.
+func stdDeprecations(path string) *deprecatedPackage {
+	stdDeprecated.Lock()
+	defer stdDeprecated.Unlock()
+	if dp, ok := stdDeprecated.m[path]; ok {
+		return dp
+	}
+	if stdDeprecated.m == nil {
+		stdDeprecated.m = make(map[string]*deprecatedPackage)
+	}
+	stdDeprecated.m[path] = nil
+
+	pkg, err := build.Import(path, "", 0)
+	if err != nil || !pkg.Goroot {
+		return nil
+	}
+	dp := &deprecatedPackage{name: pkg.Name, ids: make(map[string]string)}
+	fset := token.NewFileSet()
+	for _, name := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		findDeprecated(f, dp.ids)
+	}
+	stdDeprecated.m[path] = dp
+	return dp
+}
+
+// checkDeprecated reports uses of deprecated identifiers from other packages,
+// either in the repository or in the standard library.
+//
+// Without type information, only references of the form pkg.Name are found.
+func checkDeprecated(fset *token.FileSet, pkgs []*repoPackage) Problems {
+	repo := make(map[string]*deprecatedPackage) // import path -> deprecations
+	for _, rp := range pkgs {
+		if strings.HasSuffix(rp.name, "_test") {
+			continue
+		}
+		dp := &deprecatedPackage{name: rp.name, ids: make(map[string]string)}
+		for filename, f := range rp.files {
+			if !strings.HasSuffix(filename, "_test.go") {
+				findDeprecated(f, dp.ids)
+			}
+		}
+		repo[rp.path] = dp
+	}
+	lookup := func(path string) *deprecatedPackage {
+		if dp := repo[path]; dp != nil {
+			return dp
+		}
+		if isStdPath(path) {
+			return stdDeprecations(path)
+		}
+		return nil
+	}
+	nameOf := func(path string) string {
+		if dp := lookup(path); dp != nil {
+			return dp.name
+		}
+		return ""
+	}
+
+	var ps Problems
+	for _, rp := range pkgs {
+		for filename, f := range rp.files {
+			imported := importNames(f, nameOf)
+			ast.Inspect(f, func(n ast.Node) bool {
+				pkg, name, ok := qualifiedRef(n)
+				if !ok {
+					return true
+				}
+				path, ok := imported[pkg.Name]
+				if !ok {
+					return true
+				}
+				dp := lookup(path)
+				if dp == nil {
+					return true
+				}
+				if msg, ok := dp.ids[name.Name]; ok {
+					ps = append(ps, Problem{
+						File: filename,
+						Type: Deprecated,
+						Text: fmt.Sprintf("%s.%s is deprecated: %s", pkg.Name, name.Name, msg),
+					}.at(fset, pkg.Pos(), name.End()))
+				}
+				return true
+			})
+		}
+	}
+	return ps
+}
@@ -0,0 +1,58 @@
+package fixhub
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestCheckDeprecated(t *testing.T) {
+	packages := map[string]map[string][]byte{
+		"old old": {
+			"old/old.go": []byte(`package old
+
+// F does things.
+//
+// Deprecated: Use G instead.
+func F() {}
+
+// G does things better.
+func G() {}
+`),
+		},
+		". main": {
+			"main.go": []byte(`package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"example.com/repo/old"
+)
+
+func main() {
+	old.F()
+	old.G()
+	ioutil.ReadAll(os.Stdin)
+}
+`),
+		},
+	}
+	fset := token.NewFileSet()
+	ps := checkDeprecated(fset, parsePackages(fset, "example.com/repo", packages))
+	if len(ps) != 2 {
+		t.Fatalf("got %d problems, want 2: %v", len(ps), ps)
+	}
+	if ps[0].Line > ps[1].Line {
+		ps[0], ps[1] = ps[1], ps[0]
+	}
+	if got, want := ps[0].Text, "old.F is deprecated: Use G instead."; got != want {
+		t.Errorf("ps[0].Text = %q, want %q", got, want)
+	}
+	if got, want := ps[0].Line, 11; got != want {
+		t.Errorf("ps[0].Line = %d, want %d", got, want)
+	}
+	if got := ps[1].Text; !strings.HasPrefix(got, "ioutil.ReadAll is deprecated: ") {
+		t.Errorf("ps[1].Text = %q, want it to be about ioutil.ReadAll", got)
+	}
+}
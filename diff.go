@@ -0,0 +1,114 @@
+package fixhub
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fingerprint returns a short string that identifies p without its line number,
+// so that it is unchanged when unrelated edits move the problem.
+// If the text of p's line is known, p is identified by its file, that text
+// and the rule it breaks. Otherwise it is identified by its file and text.
+// To follow problems into renamed files, use Renamed first.
+// Problems that Diff matches have the same fingerprint.
+func (p Problem) Fingerprint() string {
+	var h [sha1.Size]byte
+	if p.LineText != "" {
+		h = sha1.Sum([]byte("line\x00" + p.File + "\x00" + string(p.Type) + "\x00" + p.Code + "\x00" + p.Text + "\x00" + p.LineText))
+	} else {
+		h = sha1.Sum([]byte(p.File + "\x00" + string(p.Type) + "\x00" + p.Text))
+	}
+	return fmt.Sprintf("%x", h[:8])
+}
+
+// lineText returns line n of src, starting at 1, with leading and trailing space
+// removed and other runs of spaces collapsed, or "" if there is no such line.
+func lineText(src []byte, n int) string {
+	if n < 1 {
+		return ""
+	}
+	for i := 1; i < n; i++ {
+		j := bytes.IndexByte(src, '\n')
+		if j < 0 {
+			return ""
+		}
+		src = src[j+1:]
+	}
+	if j := bytes.IndexByte(src, '\n'); j >= 0 {
+		src = src[:j]
+	}
+	return strings.Join(strings.Fields(string(src)), " ")
+}
+
+// Renamed returns a copy of ps with the files that were renamed moved to their new paths.
+// renames maps old paths to new ones, as from Client.Renames.
+func (ps Problems) Renamed(renames map[string]string) Problems {
+	out := make(Problems, len(ps))
+	for i, p := range ps {
+		if to, ok := renames[p.File]; ok {
+			p.File = to
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// Renames returns the files that GitHub found were renamed between two revisions,
+// as a map from their paths at base to those at head.
+func (c *Client) Renames(base, head string) (map[string]string, error) {
+	start := time.Now()
+	cmp, resp, err := c.gc.Repositories.CompareCommits(c.owner, c.repo, base, head)
+	c.fetched("compare", start, resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("comparing %.7s to %.7s: %v", base, head, err)
+	}
+	renames := make(map[string]string)
+	for _, f := range cmp.Files {
+		if f.Status != nil && *f.Status == "renamed" && f.Filename != nil && f.PreviousFilename != nil {
+			renames[*f.PreviousFilename] = *f.Filename
+		}
+	}
+	return renames, nil
+}
+
+// Diff compares two sets of problems for the same repository,
+// such as from checks of successive revisions.
+// It returns the problems in cur that were not in prev,
+// and the problems in prev that are no longer in cur.
+//
+// Problems are matched by fingerprint, so a problem that merely moved
+// due to unrelated edits is not reported. If files were renamed,
+// pass prev through Renamed first so that their problems are matched too.
+func Diff(prev, cur Problems) (added, resolved Problems) {
+	keyOf := Problem.Fingerprint
+
+	// Count each key, so repeated identical problems balance out.
+	count := make(map[string]int)
+	for _, p := range prev {
+		count[keyOf(p)]++
+	}
+	for _, p := range cur {
+		k := keyOf(p)
+		if count[k] > 0 {
+			count[k]--
+			continue
+		}
+		added = append(added, p)
+	}
+	for i := len(prev) - 1; i >= 0; i-- {
+		p := prev[i]
+		k := keyOf(p)
+		if count[k] > 0 {
+			count[k]--
+			resolved = append(resolved, p)
+		}
+	}
+	// Restore order of resolved, which was built backwards.
+	for i, j := 0, len(resolved)-1; i < j; i, j = i+1, j-1 {
+		resolved[i], resolved[j] = resolved[j], resolved[i]
+	}
+	return added, resolved
+}
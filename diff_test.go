@@ -0,0 +1,126 @@
+package fixhub
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestDiff(t *testing.T) {
+	prev := Problems{
+		{File: "a.go", Line: 3, Type: Lint, Text: "exported F should have comment"},
+		{File: "a.go", Line: 9, Type: Vet, Text: "unreachable code"},
+		{File: "b.go", Type: Gofmt, Text: "This file needs formatting with gofmt."},
+	}
+	cur := Problems{
+		{File: "a.go", Line: 5, Type: Lint, Text: "exported F should have comment"}, // moved
+		{File: "a.go", Line: 12, Type: Vet, Text: "unreachable code"},
+		{File: "a.go", Line: 20, Type: Vet, Text: "unreachable code"}, // a second one
+	}
+	added, resolved := Diff(prev, cur)
+	if want := cur[2:]; !reflect.DeepEqual(added, want) {
+		t.Errorf("added = %v, want %v", added, want)
+	}
+	if want := prev[2:]; !reflect.DeepEqual(resolved, want) {
+		t.Errorf("resolved = %v, want %v", resolved, want)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	p := Problem{File: "a.go", Line: 3, Type: Lint, Text: "exported F should have comment"}
+	moved := p
+	moved.Line = 7
+	if p.Fingerprint() != moved.Fingerprint() {
+		t.Errorf("fingerprint changed when problem moved from line %d to %d", p.Line, moved.Line)
+	}
+	other := p
+	other.File = "b.go"
+	if p.Fingerprint() == other.Fingerprint() {
+		t.Errorf("problems in different files have the same fingerprint %s", p.Fingerprint())
+	}
+
+	// Knowing the line's text lets the fingerprint follow edits to the file.
+	p.LineText = "func F() {}"
+	moved = p
+	moved.Line = 9
+	if p.Fingerprint() != moved.Fingerprint() {
+		t.Errorf("fingerprint of a known line changed when it moved from line %d to %d", p.Line, moved.Line)
+	}
+	other = p
+	other.File = "b.go"
+	if p.Fingerprint() == other.Fingerprint() {
+		t.Errorf("the same line in different files has the same fingerprint %s", p.Fingerprint())
+	}
+	edited := p
+	edited.LineText = "func F(x int) {}"
+	if p.Fingerprint() == edited.Fingerprint() {
+		t.Errorf("problems on different lines have the same fingerprint %s", p.Fingerprint())
+	}
+}
+
+func TestRenamed(t *testing.T) {
+	prev := Problems{
+		{File: "a.go", Line: 3, Type: Lint, Text: "exported F should have comment", LineText: "func F() {}"},
+		{File: "b.go", Line: 4, Type: Lint, Text: "exported F should have comment", LineText: "func F() {}"},
+	}
+	cur := Problems{
+		{File: "c.go", Line: 5, Type: Lint, Text: "exported F should have comment", LineText: "func F() {}"},
+		{File: "b.go", Line: 4, Type: Lint, Text: "exported F should have comment", LineText: "func F() {}"},
+	}
+	if added, _ := Diff(prev, cur); len(added) != 1 {
+		t.Errorf("without renames, added = %v, want the problem in c.go", added)
+	}
+	renamed := prev.Renamed(map[string]string{"a.go": "c.go"})
+	if prev[0].File != "a.go" {
+		t.Errorf("Renamed changed its receiver")
+	}
+	if added, resolved := Diff(renamed, cur); len(added) != 0 || len(resolved) != 0 {
+		t.Errorf("after renaming a.go to c.go, added = %v, resolved = %v; want none", added, resolved)
+	}
+}
+
+func TestRenames(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	base := srv.AddRepo("faker", "proj", map[string][]byte{
+		"a.go": []byte("package a\n"),
+		"b.go": []byte("package b\n"),
+	})
+	head, err := srv.Push("faker", "proj", "master", "Move a.go", map[string][]byte{
+		"a.go":     nil,
+		"new/a.go": []byte("package a\n"),
+		"b.go":     []byte("package a\n"),
+	})
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	renames, err := c.Renames(base, head)
+	if want := map[string]string{"a.go": "new/a.go"}; err != nil || !reflect.DeepEqual(renames, want) {
+		t.Errorf("Renames = %v, %v; want %v", renames, err, want)
+	}
+}
+
+func TestLineText(t *testing.T) {
+	src := []byte("package a\n\nfunc  F() {\n\treturn\t}")
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, ""},
+		{1, "package a"},
+		{2, ""},
+		{3, "func F() {"},
+		{4, "return }"},
+		{5, ""},
+	}
+	for _, tt := range tests {
+		if got := lineText(src, tt.n); got != tt.want {
+			t.Errorf("lineText(src, %d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package fixhub
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// defaultMinDocCoverage is the fraction of exported identifiers that a
+// package should document if neither the Client nor the repository say otherwise.
+const defaultMinDocCoverage = 0.5
+
+// docCoverage counts the exported identifiers in a package, and how many are documented.
+func docCoverage(rp *repoPackage) (documented, exported int) {
+	count := func(id *ast.Ident, docs ...*ast.CommentGroup) {
+		if !id.IsExported() {
+			return
+		}
+		exported++
+		for _, doc := range docs {
+			if doc != nil {
+				documented++
+				return
+			}
+		}
+	}
+	for filename, f := range rp.files {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				if d.Recv != nil && !exportedRecv(d.Recv) {
+					continue
+				}
+				count(d.Name, d.Doc)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						count(spec.Name, spec.Doc, d.Doc)
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							count(name, spec.Doc, spec.Comment, d.Doc)
+						}
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// exportedRecv reports whether a method receiver's base type is exported.
+func exportedRecv(recv *ast.FieldList) bool {
+	if len(recv.List) != 1 {
+		return false
+	}
+	t := recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	id, ok := t.(*ast.Ident)
+	return ok && id.IsExported()
+}
+
+// checkDocCoverage reports packages that document less than the minimum
+// fraction of their exported identifiers. Main packages are not checked.
+func checkDocCoverage(pkgs []*repoPackage, min float64) Problems {
+	var ps Problems
+	for _, rp := range pkgs {
+		if rp.name == "main" || strings.HasSuffix(rp.name, "_test") {
+			continue
+		}
+		documented, exported := docCoverage(rp)
+		if exported == 0 {
+			continue
+		}
+		cov := float64(documented) / float64(exported)
+		if cov >= min {
+			continue
+		}
+		// Attribute the problem to the package's first file.
+		var files []string
+		for filename := range rp.files {
+			if !strings.HasSuffix(filename, "_test.go") {
+				files = append(files, filename)
+			}
+		}
+		sort.Strings(files)
+		ps = append(ps, Problem{
+			File: files[0],
+			Type: DocCoverage,
+			Text: fmt.Sprintf("package %s documents %d of %d exported identifiers (%.0f%%), below the minimum of %.0f%%",
+				rp.name, documented, exported, 100*cov, 100*min),
+		})
+	}
+	return ps
+}
@@ -0,0 +1,59 @@
+package fixhub
+
+import (
+	"go/token"
+	"testing"
+)
+
+func TestCheckDocCoverage(t *testing.T) {
+	packages := map[string]map[string][]byte{
+		"lib lib": {
+			"lib/b.go": []byte(`package lib
+
+// F is documented.
+func F() {}
+
+func G() {}
+
+// T is documented.
+type T int
+
+func (T) M() {}
+
+func (t *T) N() {}
+
+func (u unexported) M() {}
+
+type unexported int
+
+const (
+	// A is documented.
+	A = 1
+	B = 2 // so is B
+	C = 3
+)
+`),
+			"lib/a.go": []byte("package lib\n"),
+		},
+		". main": {
+			"main.go": []byte("package main\n\nfunc Undocumented() {}\n"),
+		},
+	}
+	fset := token.NewFileSet()
+	pkgs := parsePackages(fset, "example.com/repo", packages)
+
+	ps := checkDocCoverage(pkgs, 0.6)
+	if len(ps) != 1 {
+		t.Fatalf("got %d problems, want 1: %v", len(ps), ps)
+	}
+	if got, want := ps[0].Text, "package lib documents 4 of 8 exported identifiers (50%), below the minimum of 60%"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+	if got, want := ps[0].File, "lib/a.go"; got != want {
+		t.Errorf("File = %q, want %q", got, want)
+	}
+
+	if ps := checkDocCoverage(pkgs, 0.5); len(ps) != 0 {
+		t.Errorf("with lower minimum, got problems %v", ps)
+	}
+}
@@ -0,0 +1,211 @@
+package fixhub
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A SuggestedFix is a way to fix a problem, as edits to the file it was found in.
+type SuggestedFix struct {
+	Message string     // what the fix does
+	Edits   []TextEdit // in order, and not overlapping
+}
+
+// A TextEdit replaces the bytes of a file from Start up to End with New.
+type TextEdit struct {
+	Start, End int // byte offsets
+	New        string
+}
+
+// Apply returns src with the fix's edits applied.
+// src must be the content of the file that the problem was found in.
+func (sf *SuggestedFix) Apply(src []byte) ([]byte, error) {
+	return ApplyEdits(src, sf.Edits)
+}
+
+// ApplyEdits returns src with edits applied.
+// The edits must be in order, and must not overlap.
+func ApplyEdits(src []byte, edits []TextEdit) ([]byte, error) {
+	var buf bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		if e.Start < last || e.End < e.Start || e.End > len(src) {
+			return nil, fmt.Errorf("bad edit of bytes %d to %d in %d bytes after offset %d", e.Start, e.End, len(src), last)
+		}
+		buf.Write(src[last:e.Start])
+		buf.WriteString(e.New)
+		last = e.End
+	}
+	buf.Write(src[last:])
+	return buf.Bytes(), nil
+}
+
+// RebaseEdits adapts edits to base so that they apply to latest,
+// a later version of the same file, as a three-way merge would.
+// That lets a fix be committed on top of changes made since the
+// file was checked, instead of replacing the whole file.
+// It fails if any edit overlaps lines that changed between base and latest.
+func RebaseEdits(base, latest []byte, edits []TextEdit) ([]TextEdit, error) {
+	changes := lineEdits(base, latest)
+	var out []TextEdit
+	shift, ci := 0, 0 // shift is the growth from changes before changes[ci]
+	for _, e := range edits {
+		for ci < len(changes) && changes[ci].End <= e.Start {
+			c := changes[ci]
+			shift += len(c.New) - (c.End - c.Start)
+			ci++
+		}
+		if ci < len(changes) && changes[ci].Start < e.End {
+			c := changes[ci]
+			return nil, fmt.Errorf("edit of bytes %d to %d conflicts with change to bytes %d to %d", e.Start, e.End, c.Start, c.End)
+		}
+		out = append(out, TextEdit{Start: e.Start + shift, End: e.End + shift, New: e.New})
+	}
+	return out, nil
+}
+
+// suggest returns a fix that turns src into fixed,
+// or nil if that could not be worked out.
+func suggest(msg string, src []byte, fixed []byte, err error) *SuggestedFix {
+	if err != nil || bytes.Equal(src, fixed) {
+		return nil
+	}
+	return &SuggestedFix{Message: msg, Edits: lineEdits(src, fixed)}
+}
+
+// maxDiffCost bounds the work that lineEdits does to find small edits.
+// Beyond it, the changed lines are replaced wholesale.
+const maxDiffCost = 1000
+
+// lineEdits returns edits that turn a into b, changing whole lines.
+func lineEdits(a, b []byte) []TextEdit {
+	al, bl := splitLines(a), splitLines(b)
+	// offset returns the byte offset of line i of a.
+	offsets := make([]int, len(al)+1)
+	for i, l := range al {
+		offsets[i+1] = offsets[i] + len(l)
+	}
+
+	var edits []TextEdit
+	i, j := 0, 0
+	flush := func(i2, j2 int) {
+		if i2 > i || j2 > j {
+			edits = append(edits, TextEdit{
+				Start: offsets[i],
+				End:   offsets[i2],
+				New:   string(bytes.Join(bl[j:j2], nil)),
+			})
+		}
+	}
+	for _, m := range matchLines(al, bl) {
+		flush(m[0], m[1])
+		i, j = m[0]+1, m[1]+1
+	}
+	flush(len(al), len(bl))
+	return edits
+}
+
+// splitLines splits src into lines, each including its trailing newline, if any.
+func splitLines(src []byte) [][]byte {
+	var lines [][]byte
+	for len(src) > 0 {
+		i := bytes.IndexByte(src, '\n') + 1
+		if i == 0 {
+			i = len(src)
+		}
+		lines = append(lines, src[:i])
+		src = src[i:]
+	}
+	return lines
+}
+
+// matchLines returns the indexes of lines of a and b that are the same,
+// as pairs in increasing order. It uses Myers' diff algorithm
+// (http://www.xmailserver.org/diff2.pdf) to find as many as it can, unless
+// that takes more than maxDiffCost steps, in which case it matches only
+// lines common to the start and end of a and b.
+func matchLines(a, b [][]byte) [][2]int {
+	var pre, post [][2]int
+	for len(pre) < len(a) && len(pre) < len(b) && bytes.Equal(a[len(pre)], b[len(pre)]) {
+		pre = append(pre, [2]int{len(pre), len(pre)})
+	}
+	for n := len(pre); len(a)-len(post) > n && len(b)-len(post) > n; {
+		i, j := len(a)-len(post)-1, len(b)-len(post)-1
+		if !bytes.Equal(a[i], b[j]) {
+			break
+		}
+		post = append(post, [2]int{i, j})
+	}
+	// Reverse post into increasing order.
+	for i, j := 0, len(post)-1; i < j; i, j = i+1, j-1 {
+		post[i], post[j] = post[j], post[i]
+	}
+
+	start := len(pre)
+	mid := myers(a[start:len(a)-len(post)], b[start:len(b)-len(post)])
+	for _, m := range mid {
+		pre = append(pre, [2]int{m[0] + start, m[1] + start})
+	}
+	return append(pre, post...)
+}
+
+// myers implements matchLines without the trimming of common lines.
+func myers(a, b [][]byte) [][2]int {
+	n, m := len(a), len(b)
+	// v[k] is the furthest x reached on diagonal k (y = x-k), offset by max.
+	max := n + m
+	if max > 2*maxDiffCost {
+		max = 2 * maxDiffCost
+	}
+	off := max + 1
+	v := make([]int, 2*off+1)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1] // down
+			} else {
+				x = v[off+k-1] + 1 // right
+			}
+			y := x - k
+			for x < n && y < m && bytes.Equal(a[x], b[y]) {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return nil // too different to bother matching
+}
+
+// backtrack recovers the matched lines from the trace of Myers' algorithm.
+// trace[d] holds the furthest x on diagonals -d-1 to d+1 before step d.
+func backtrack(trace [][]int, x, y int) [][2]int {
+	var matches [][2]int
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevX, prevY := 0, 0
+		if d > 0 {
+			prevK := k - 1
+			if k == -d || (k != d && v(k-1) < v(k+1)) {
+				prevK = k + 1
+			}
+			prevX = v(prevK)
+			prevY = prevX - prevK
+		}
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			matches = append(matches, [2]int{x, y})
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return matches
+}
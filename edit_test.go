@@ -0,0 +1,141 @@
+package fixhub
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestLineEdits(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		edits int
+	}{
+		{"", "", 0},
+		{"a\nb\nc\n", "a\nb\nc\n", 0},
+		{"a\nb\nc\n", "a\nB\nc\n", 1},
+		{"a\nb\nc\nd\ne\n", "A\nb\nc\nd\nE\n", 2},
+		{"a\nb\n", "a\nb\nc", 1},
+		{"a\nb\nc\n", "", 1},
+		{"", "x\n", 1},
+		{"a\nb\na\nb\n", "b\na\nb\na\n", 2},
+	}
+	for _, tt := range tests {
+		edits := lineEdits([]byte(tt.a), []byte(tt.b))
+		if len(edits) != tt.edits {
+			t.Errorf("lineEdits(%q, %q) = %+v, want %d edits", tt.a, tt.b, edits, tt.edits)
+		}
+		if got, err := ApplyEdits([]byte(tt.a), edits); err != nil || string(got) != tt.b {
+			t.Errorf("applying lineEdits(%q, %q) gave %q, %v", tt.a, tt.b, got, err)
+		}
+	}
+
+	// Random mutations must round trip, however they are found.
+	r := rand.New(rand.NewSource(1))
+	randText := func(n int) string {
+		var lines []string
+		for i := 0; i < n; i++ {
+			lines = append(lines, string(rune('a'+r.Intn(4))))
+		}
+		return strings.Join(lines, "\n")
+	}
+	for i := 0; i < 200; i++ {
+		a, b := randText(r.Intn(30)), randText(r.Intn(30))
+		got, err := ApplyEdits([]byte(a), lineEdits([]byte(a), []byte(b)))
+		if err != nil || string(got) != b {
+			t.Fatalf("applying lineEdits(%q, %q) gave %q, %v", a, b, got, err)
+		}
+	}
+}
+
+func TestLineEditsLarge(t *testing.T) {
+	// Files too different to diff cheaply are still edited correctly.
+	var a, b strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+	got, err := ApplyEdits([]byte(a.String()), lineEdits([]byte(a.String()), []byte(b.String())))
+	if err != nil || string(got) != b.String() {
+		t.Errorf("applying lineEdits to large files failed: %v", err)
+	}
+}
+
+func TestApplyEditsOverlap(t *testing.T) {
+	edits := []TextEdit{{Start: 2, End: 5}, {Start: 4, End: 6}}
+	if _, err := ApplyEdits([]byte("0123456789"), edits); err == nil {
+		t.Errorf("ApplyEdits with overlapping edits succeeded")
+	}
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	src := []byte("\xef\xbb\xbfpackage p\r\n\r\nfunc F() {}\r\n")
+	for _, p := range checkEncoding("x.go", src) {
+		if p.Fix == nil {
+			t.Errorf("no fix for %v", p)
+			continue
+		}
+		if _, err := p.Fix.Apply(src); err != nil {
+			t.Errorf("applying fix for %v: %v", p, err)
+		}
+	}
+
+	const fa = `package p
+
+type t struct {
+	a bool
+	b int64
+	c bool
+}
+`
+	ps := checkFieldAlignment("p.go", []byte(fa), func(string) bool { return true })
+	if len(ps) != 1 || ps[0].Fix == nil {
+		t.Fatalf("checkFieldAlignment = %+v, want one problem with a fix", ps)
+	}
+	got, err := ps[0].Fix.Apply([]byte(fa))
+	if err != nil {
+		t.Fatalf("applying fix: %v", err)
+	}
+	want, _ := reorderStruct([]byte(fa), "t")
+	if string(got) != string(want) {
+		t.Errorf("fix gave\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRebaseEdits(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	// Change "b" to "B" and "d" to "D".
+	edits := []TextEdit{{Start: 2, End: 3, New: "B"}, {Start: 6, End: 7, New: "D"}}
+
+	tests := []struct {
+		latest string
+		want   string // "" for a conflict
+	}{
+		{base, "a\nB\nc\nD\ne\n"},
+		{"new\na\nb\nc\nd\ne\n", "new\na\nB\nc\nD\ne\n"},
+		{"a\nb\nc\nd\ne\nnew\n", "a\nB\nc\nD\ne\nnew\n"},
+		{"a\nb\nCCC\nCCC\nd\ne\n", "a\nB\nCCC\nCCC\nD\ne\n"},
+		{"a\nb\nd\ne\n", "a\nB\nD\ne\n"},
+		{"a\nbb\nc\nd\ne\n", ""},
+		{"a\nb\nc\nd\n", "a\nB\nc\nD\n"},
+		{"a\nb\nc\ndd\ne\n", ""},
+	}
+	for _, tt := range tests {
+		got, err := RebaseEdits([]byte(base), []byte(tt.latest), edits)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("RebaseEdits onto %q succeeded, want conflict", tt.latest)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RebaseEdits onto %q: %v", tt.latest, err)
+			continue
+		}
+		out, err := ApplyEdits([]byte(tt.latest), got)
+		if err != nil || string(out) != tt.want {
+			t.Errorf("rebased edits onto %q gave %q, %v; want %q", tt.latest, out, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package fixhub
+
+import "bytes"
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// checkEncoding reports a UTF-8 byte order mark or Windows line endings in a file.
+func checkEncoding(filename string, src []byte) Problems {
+	var ps Problems
+	if bytes.HasPrefix(src, utf8BOM) {
+		ps = append(ps, Problem{
+			File:    filename,
+			Line:    1,
+			Col:     1,
+			Type:    Encoding,
+			Text:    "File starts with a UTF-8 byte order mark.",
+			Fixable: true,
+			Fix: &SuggestedFix{
+				Message: "Remove the byte order mark.",
+				Edits:   []TextEdit{{Start: 0, End: len(utf8BOM)}},
+			},
+		})
+	}
+	if i := bytes.Index(src, []byte("\r\n")); i >= 0 {
+		fix := &SuggestedFix{Message: "Convert line endings to LF."}
+		for j := i; j >= 0; j = nextCRLF(src, j) {
+			fix.Edits = append(fix.Edits, TextEdit{Start: j, End: j + 1}) // drop the \r
+		}
+		ps = append(ps, Problem{
+			File:    filename,
+			Line:    bytes.Count(src[:i], []byte("\n")) + 1,
+			Col:     i - bytes.LastIndexByte(src[:i], '\n'),
+			Type:    Encoding,
+			Text:    "File has Windows (CRLF) line endings.",
+			Fixable: true,
+			Fix:     fix,
+		})
+	}
+	return ps
+}
+
+// nextCRLF returns the offset of the next CRLF in src after the one at i, or -1.
+func nextCRLF(src []byte, i int) int {
+	j := bytes.Index(src[i+2:], []byte("\r\n"))
+	if j < 0 {
+		return -1
+	}
+	return i + 2 + j
+}
+
+// fixEncoding strips a byte order mark and converts CRLF line endings to LF.
+func fixEncoding(src []byte) []byte {
+	src = bytes.TrimPrefix(src, utf8BOM)
+	return bytes.Replace(src, []byte("\r\n"), []byte("\n"), -1)
+}
@@ -0,0 +1,19 @@
+package fixhub
+
+import "testing"
+
+func TestCheckEncoding(t *testing.T) {
+	ps := checkEncoding("x.go", []byte("\xef\xbb\xbfpackage p\n\nfunc F() {}\r\n"))
+	if len(ps) != 2 {
+		t.Fatalf("got %d problems, want 2: %v", len(ps), ps)
+	}
+	if got, want := ps[1].Line, 3; got != want {
+		t.Errorf("CRLF reported on line %d, want %d", got, want)
+	}
+	if got, want := ps[1].Col, 12; got != want {
+		t.Errorf("CRLF reported at column %d, want %d", got, want)
+	}
+	if ps := checkEncoding("x.go", []byte("package p\n")); len(ps) != 0 {
+		t.Errorf("got problems for a clean file: %v", ps)
+	}
+}
@@ -0,0 +1,241 @@
+package fixhub
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+)
+
+// Sizes and alignments of basic types, assuming a 64-bit platform.
+var basicLayouts = map[string][2]int64{
+	"bool": {1, 1}, "int8": {1, 1}, "uint8": {1, 1}, "byte": {1, 1},
+	"int16": {2, 2}, "uint16": {2, 2},
+	"int32": {4, 4}, "uint32": {4, 4}, "rune": {4, 4}, "float32": {4, 4},
+	"int": {8, 8}, "uint": {8, 8}, "int64": {8, 8}, "uint64": {8, 8}, "uintptr": {8, 8},
+	"float64": {8, 8}, "complex64": {8, 4}, "complex128": {16, 8},
+	"string": {16, 8}, "error": {16, 8},
+}
+
+// typeLayout returns the size and alignment of the type expressed by e.
+// It only knows about types that can be determined syntactically,
+// so ok is false for named types other than the predeclared ones.
+func typeLayout(e ast.Expr) (size, align int64, ok bool) {
+	switch e := e.(type) {
+	case *ast.Ident:
+		l, ok := basicLayouts[e.Name]
+		return l[0], l[1], ok
+	case *ast.ParenExpr:
+		return typeLayout(e.X)
+	case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType:
+		return 8, 8, true
+	case *ast.InterfaceType:
+		return 16, 8, true
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok && x.Name == "unsafe" && e.Sel.Name == "Pointer" {
+			return 8, 8, true
+		}
+	case *ast.ArrayType:
+		if e.Len == nil {
+			return 24, 8, true // slice
+		}
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return 0, 0, false
+		}
+		n, err := strconv.ParseInt(lit.Value, 0, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		size, align, ok := typeLayout(e.Elt)
+		return n * size, align, ok
+	case *ast.StructType:
+		fields, ok := fieldLayouts(e.Fields.List)
+		if !ok {
+			return 0, 0, false
+		}
+		size, align := structLayout(fields)
+		return size, align, true
+	}
+	return 0, 0, false
+}
+
+// fieldLayout is the layout of one field declaration, which may declare several fields.
+type fieldLayout struct {
+	size, align int64
+}
+
+func fieldLayouts(list []*ast.Field) ([]fieldLayout, bool) {
+	var fls []fieldLayout
+	for _, f := range list {
+		size, align, ok := typeLayout(f.Type)
+		if !ok || size == 0 {
+			// Zero-sized fields have special rules that we don't bother with.
+			return nil, false
+		}
+		n := int64(len(f.Names))
+		if n == 0 {
+			n = 1 // embedded
+		}
+		fls = append(fls, fieldLayout{n * size, align})
+	}
+	return fls, true
+}
+
+func structLayout(fields []fieldLayout) (size, align int64) {
+	align = 1
+	for _, f := range fields {
+		size = roundUp(size, f.align) + f.size
+		if f.align > align {
+			align = f.align
+		}
+	}
+	return roundUp(size, align), align
+}
+
+func roundUp(n, align int64) int64 { return (n + align - 1) / align * align }
+
+// optimalOrder returns the indexes of fields in an order that minimizes padding.
+func optimalOrder(fields []fieldLayout) []int {
+	order := make([]int, len(fields))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := fields[order[i]], fields[order[j]]
+		if a.align != b.align {
+			return a.align > b.align
+		}
+		return a.size > b.size
+	})
+	return order
+}
+
+// checkFieldAlignment reports struct types in a file whose fields could be
+// reordered to use less memory. If reorder is non-nil, problems are marked
+// fixable for the struct types it reports true for, if Fix can reorder them.
+func checkFieldAlignment(filename string, src []byte, reorder func(name string) bool) Problems {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil
+	}
+	var ps Problems
+	ast.Inspect(f, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			return true
+		}
+		fields, ok := fieldLayouts(st.Fields.List)
+		if !ok {
+			return true
+		}
+		cur, _ := structLayout(fields)
+		var opt []fieldLayout
+		for _, i := range optimalOrder(fields) {
+			opt = append(opt, fields[i])
+		}
+		best, _ := structLayout(opt)
+		if best >= cur {
+			return true
+		}
+		p := Problem{
+			File: filename,
+			Type: FieldAlignment,
+			Text: fmt.Sprintf("struct %s is %d bytes but could be %d if its fields were reordered, saving %d bytes", ts.Name.Name, cur, best, cur-best),
+		}.at(fset, ts.Pos(), ts.End())
+		if reorder != nil && reorder(ts.Name.Name) {
+			out, err := reorderStruct(src, ts.Name.Name)
+			p.Fixable = err == nil
+			p.Fix = suggest("Reorder the fields of "+ts.Name.Name+".", src, out, err)
+		}
+		ps = append(ps, p)
+		return true
+	})
+	return ps
+}
+
+// fixFieldAlignment reorders the fields of the struct named in p.
+func fixFieldAlignment(src []byte, p Problem) ([]byte, error) {
+	var name string
+	if _, err := fmt.Sscanf(p.Text, "struct %s is", &name); err != nil {
+		return nil, fmt.Errorf("can't find struct name in %q", p.Text)
+	}
+	return reorderStruct(src, name)
+}
+
+// reorderStruct rewrites the source of the named struct type
+// so that its fields are in the optimal order.
+// Each field declaration must be on its own lines, along with its comments.
+func reorderStruct(src []byte, name string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var st *ast.StructType
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ts, ok := n.(*ast.TypeSpec); ok && ts.Name.Name == name {
+			st, _ = ts.Type.(*ast.StructType)
+		}
+		return st == nil
+	})
+	if st == nil {
+		return nil, fmt.Errorf("struct %s not found", name)
+	}
+	fields, ok := fieldLayouts(st.Fields.List)
+	if !ok {
+		return nil, fmt.Errorf("can't determine layout of struct %s", name)
+	}
+
+	lineStart := func(pos token.Pos) int {
+		off := fset.Position(pos).Offset
+		return bytes.LastIndexByte(src[:off], '\n') + 1
+	}
+	lineEnd := func(pos token.Pos) int {
+		off := fset.Position(pos).Offset
+		if i := bytes.IndexByte(src[off:], '\n'); i >= 0 {
+			return off + i + 1
+		}
+		return len(src)
+	}
+
+	// Work out the source range of each field, including its comments.
+	type span struct{ start, end int }
+	var spans []span
+	prevEnd := lineEnd(st.Fields.Opening)
+	for _, fld := range st.Fields.List {
+		start := fld.Pos()
+		if fld.Doc != nil {
+			start = fld.Doc.Pos()
+		}
+		end := fld.End()
+		if fld.Comment != nil {
+			end = fld.Comment.End()
+		}
+		s := span{lineStart(start), lineEnd(end)}
+		if s.start < prevEnd || len(bytes.TrimSpace(src[prevEnd:s.start])) > 0 {
+			return nil, fmt.Errorf("fields of struct %s are not one per line", name)
+		}
+		spans = append(spans, s)
+		prevEnd = s.end
+	}
+	if closing := lineStart(st.Fields.Closing); closing < prevEnd || len(bytes.TrimSpace(src[prevEnd:closing])) > 0 {
+		return nil, fmt.Errorf("fields of struct %s are not one per line", name)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(src[:spans[0].start])
+	for _, i := range optimalOrder(fields) {
+		buf.Write(src[spans[i].start:spans[i].end])
+	}
+	buf.Write(src[spans[len(spans)-1].end:])
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package fixhub
+
+import "testing"
+
+const paddedSrc = `package p
+
+type padded struct {
+	a bool
+	// b is big.
+	b int64 // and has a comment
+	c bool
+
+	d int32
+}
+
+type Exported struct {
+	a bool
+	b int64
+	c bool
+}
+
+type fine struct {
+	b int64
+	a bool
+}
+`
+
+func TestCheckFieldAlignment(t *testing.T) {
+	ps := checkFieldAlignment("p.go", []byte(paddedSrc), func(name string) bool { return name == "padded" })
+	if len(ps) != 2 {
+		t.Fatalf("got %d problems, want 2: %v", len(ps), ps)
+	}
+	if got, want := ps[0].Text, "struct padded is 24 bytes but could be 16 if its fields were reordered, saving 8 bytes"; got != want {
+		t.Errorf("ps[0].Text = %q, want %q", got, want)
+	}
+	if got, want := ps[0].Line, 3; got != want {
+		t.Errorf("ps[0].Line = %d, want %d", got, want)
+	}
+	if !ps[0].Fixable {
+		t.Errorf("ps[0] should be fixable")
+	}
+	if ps[1].Fixable {
+		t.Errorf("ps[1] should not be fixable")
+	}
+}
+
+func TestReorderStruct(t *testing.T) {
+	out, err := reorderStruct([]byte(paddedSrc), "padded")
+	if err != nil {
+		t.Fatalf("reorderStruct: %v", err)
+	}
+	const want = `package p
+
+type padded struct {
+	// b is big.
+	b int64 // and has a comment
+	d int32
+	a bool
+	c bool
+}
+`
+	if got := string(out[:len(want)]); got != want {
+		t.Errorf("reorderStruct output:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := reorderStruct([]byte("package p\n\ntype x struct{ a bool; b int; c bool }\n"), "x"); err == nil {
+		t.Errorf("reorderStruct of single-line struct succeeded, want error")
+	}
+}
@@ -0,0 +1,628 @@
+package fixhub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// A fixer rewrites a parsed file to fix problem p,
+// reporting whether it changed anything.
+type fixer func(fset *token.FileSet, f *ast.File, p Problem) bool
+
+// A lintFixer fixes a kind of golint problem.
+type lintFixer struct {
+	category string
+	match    func(text string) bool
+	fix      fixer
+	msg      string    // for SuggestedFix
+	rename   *renaming // if the fix renames what the problem is about
+}
+
+// lintFixers are the fixers for golint problems, keyed by golint category.
+var lintFixers = []lintFixer{
+	{category: "indent", match: prefixMatcher("if block ends with a return statement, so drop this else and outdent its block"), fix: fixElse, msg: "Drop the else and outdent its block."},
+	{category: "naming", match: func(text string) bool {
+		return strings.HasPrefix(text, "receiver name ") && strings.Contains(text, " should be consistent with previous receiver name ")
+	}, fix: fixReceiverName, msg: "Rename the receiver."},
+	{category: "naming", match: func(text string) bool {
+		return strings.HasPrefix(text, "error var ") && strings.Contains(text, " should have name of the form ")
+	}, rename: &renaming{ast.Var, errorVarName}, msg: "Rename the error variable."},
+	{category: "naming", match: prefixMatcher("don't use ALL_CAPS in Go names; use CamelCase"), rename: &renaming{ast.Con, camelCase}, msg: "Rename the constant."},
+	// golint gives this problem no category.
+	{category: "", match: prefixMatcher("redundant if ...; err != nil check, just return error instead."), fix: fixIfError, msg: "Return the error directly."},
+}
+
+func prefixMatcher(prefix string) func(string) bool {
+	return func(text string) bool { return strings.HasPrefix(text, prefix) }
+}
+
+// lintFixerFor returns the lintFixer for p, or nil if there isn't one.
+func lintFixerFor(p Problem) *lintFixer {
+	if p.Type != Lint {
+		return nil
+	}
+	for i := range lintFixers {
+		if lf := &lintFixers[i]; lf.category == p.Code && lf.match(p.Text) {
+			return lf
+		}
+	}
+	return nil
+}
+
+// fixerFor returns the fixer for p, or nil if p is not mechanically fixable.
+func fixerFor(p Problem) fixer {
+	lf := lintFixerFor(p)
+	switch {
+	case lf == nil:
+		return nil
+	case lf.rename != nil:
+		return lf.rename.fix
+	}
+	return lf.fix
+}
+
+// fixableIn reports whether p, which fixerFor can fix, can still be fixed
+// given files, the source of the files of its package keyed by path;
+// whole reports whether files are all of the package's files.
+// FixFile fixes one file at a time, so it can only rename a package-level name
+// if no other file uses that name or the new one. Exported names are left alone
+// outside package main, since other packages may use them.
+func fixableIn(files map[string][]byte, whole bool, p Problem) bool {
+	lf := lintFixerFor(p)
+	if lf == nil || lf.rename == nil {
+		return lf != nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, p.File, files[p.File], 0)
+	if err != nil {
+		return false
+	}
+	id := lf.rename.target(fset, f, p)
+	if id == nil {
+		return false
+	}
+	if f.Scope.Lookup(id.Name) != id.Obj {
+		return true // declared in a function
+	}
+	if !whole || (id.IsExported() && f.Name.Name != "main") {
+		return false
+	}
+	to := lf.rename.name(id.Name)
+	for file, src := range files {
+		if file == p.File {
+			continue
+		}
+		of, err := parser.ParseFile(fset, file, src, 0)
+		if err != nil || uses(of, id.Name) || uses(of, to) {
+			return false
+		}
+	}
+	return true
+}
+
+// suggestFix returns the fix for a single fixable golint problem in src,
+// which also formats the file with gofmt, or nil if it can't be fixed.
+func suggestFix(src []byte, p Problem) *SuggestedFix {
+	lf := lintFixerFor(p)
+	if lf == nil {
+		return nil
+	}
+	out, err := FixFile(p.File, src, Problems{p})
+	return suggest(lf.msg, src, out, err)
+}
+
+// FixFile applies fixes for the fixable problems in ps that are in the named file,
+// and formats the result with gofmt. Problems for other files are ignored.
+// It returns the new content of the file.
+func FixFile(filename string, src []byte, ps Problems) ([]byte, error) {
+	// Encoding fixes don't change line numbers, so do them first.
+	for _, p := range ps {
+		if p.File == filename && p.Fixable && p.Type == Encoding {
+			src = fixEncoding(src)
+			break
+		}
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	for _, p := range ps {
+		if p.File != filename || !p.Fixable {
+			continue
+		}
+		if fix := fixerFor(p); fix != nil && fix(fset, f, p) {
+			changed = true
+		}
+	}
+	if changed {
+		var buf bytes.Buffer
+		if err := format.Node(&buf, fset, f); err != nil {
+			return nil, err
+		}
+		src = buf.Bytes()
+	}
+
+	// Some fixes work on the source text,
+	// so they are applied after the AST has been written back out.
+	for _, p := range ps {
+		if p.File != filename || !p.Fixable || p.Type != FieldAlignment {
+			continue
+		}
+		if src, err = fixFieldAlignment(src, p); err != nil {
+			return nil, err
+		}
+	}
+	return format.Source(src)
+}
+
+// Fix checks the Go source files at the named revision and fixes what it can.
+// It returns the new content of each changed file, keyed by path.
+// Files protected from fixes, as for Client.ProtectedPaths, and symlinks are left alone.
+// It fails if any other file can't be fixed.
+func (c *Client) Fix(rev string) (map[string][]byte, error) {
+	ref, err := c.ResolveRef(rev)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q: %v", rev, err)
+	}
+	fixed, _, excluded, err := c.fix(ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, ex := range excluded {
+		if ex.Err != errProtected && ex.Err != errSymlink {
+			return nil, ex
+		}
+	}
+	return fixed, nil
+}
+
+// An ExcludedFix is a file whose fixes were left out of a commit
+// because they could not be made or failed verification.
+type ExcludedFix struct {
+	File string
+	Err  error
+}
+
+func (e ExcludedFix) Error() string { return fmt.Sprintf("fixing %s: %v", e.File, e.Err) }
+
+var (
+	errProtected = errors.New("protected from fixes")
+	errSymlink   = errors.New("a symlink; fix the file it links to instead")
+)
+
+// A FixCommit describes the commit made by CommitFixes.
+type FixCommit struct {
+	SHA      string   // SHA-1 of the commit, or empty if nothing was committed
+	Files    []string // paths of the files fixed, sorted
+	Fixed    Problems // the problems fixed
+	Excluded []ExcludedFix
+}
+
+// CommitFixes checks the Go source files at the head of the named branch,
+// fixes what it can, and commits all the fixes to the branch as a single commit.
+// Files whose fixes fail, and files protected from fixes, as for
+// Client.ProtectedPaths, are excluded from the commit, and listed in the result,
+// rather than stopping the rest; if none are left, nothing is committed.
+// Either every included fix is committed or, if err is non-nil, the branch is untouched.
+// If the branch moves on while the fixes are made, they are rebased onto it, as by Commit.
+// If message is empty, one listing the problems fixed is used.
+func (c *Client) CommitFixes(branch, message string) (*FixCommit, error) {
+	head, err := c.ResolveRef(branch)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q: %v", branch, err)
+	}
+	fixed, ps, excluded, err := c.fix(head)
+	if err != nil {
+		return nil, err
+	}
+	fc := &FixCommit{Fixed: ps, Excluded: excluded}
+	for path := range fixed {
+		fc.Files = append(fc.Files, path)
+	}
+	sort.Strings(fc.Files)
+	if len(fixed) == 0 {
+		return fc, nil
+	}
+	if message == "" {
+		message = fixMessage(ps)
+	}
+	if fc.SHA, err = c.Commit(branch, head, message, fixed); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// fixMessage returns a commit message for fixing ps.
+func fixMessage(ps Problems) string {
+	msg := fmt.Sprintf("Fix %d problems found by fixhub\n\n", len(ps))
+	if len(ps) == 1 {
+		msg = "Fix a problem found by fixhub\n\n"
+	}
+	for _, p := range ps {
+		msg += fmt.Sprintf("%v\n", p)
+	}
+	return msg
+}
+
+// fix checks the Go source files at the commit sha and fixes what it can.
+// It returns the new content of each changed file, keyed by path,
+// and the problems fixed in them. Files whose fixes fail are left out,
+// and returned as excluded.
+func (c *Client) fix(sha string) (fixed map[string][]byte, ps Problems, excluded []ExcludedFix, err error) {
+	all, err := c.Check(sha)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	fixable := make(map[string]bool)
+	for _, p := range all {
+		if p.Fixable {
+			fixable[p.File] = true
+		}
+	}
+	if len(fixable) == 0 {
+		return nil, nil, nil, nil
+	}
+	tree, err := c.GetTree(sha)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("fetching tree %s: %v", sha, err)
+	}
+	cfg, err := c.loadConfig(tree)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fixed = make(map[string][]byte)
+	for _, ent := range tree.Entries {
+		if ent.Path == nil || ent.SHA == nil || !fixable[*ent.Path] {
+			continue
+		}
+		path := *ent.Path
+		if c.protected(cfg, path) {
+			excluded = append(excluded, ExcludedFix{File: path, Err: errProtected})
+			continue
+		}
+		if ent.Mode != nil && *ent.Mode == symlinkMode {
+			excluded = append(excluded, ExcludedFix{File: path, Err: errSymlink})
+			continue
+		}
+		src, err := c.GetBlob(*ent.SHA)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("fetching %s: %v", path, err)
+		}
+		out, err := FixFile(path, src, all)
+		if err == nil {
+			err = verifyFix(path, src, out)
+		}
+		if err != nil {
+			c.debug("excluding fix", "file", path, "err", err)
+			excluded = append(excluded, ExcludedFix{File: path, Err: err})
+			continue
+		}
+		if !bytes.Equal(src, out) {
+			fixed[path] = out
+		}
+	}
+	for _, p := range all {
+		if _, ok := fixed[p.File]; ok && p.Fixable {
+			ps = append(ps, p)
+		}
+	}
+	return fixed, ps, excluded, nil
+}
+
+// verifyFix checks that out, the fixed version of the Go source file src,
+// is still the same file in outline: it must parse, in the same package,
+// with the same top-level declarations.
+func verifyFix(filename string, src, out []byte) error {
+	fset := token.NewFileSet()
+	before, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return err
+	}
+	after, err := parser.ParseFile(fset, filename, out, 0)
+	if err != nil {
+		return fmt.Errorf("fixed file does not parse: %v", err)
+	}
+	if before.Name.Name != after.Name.Name {
+		return fmt.Errorf("fix changed the package from %s to %s", before.Name.Name, after.Name.Name)
+	}
+	if len(before.Decls) != len(after.Decls) {
+		return fmt.Errorf("fix changed the number of declarations from %d to %d", len(before.Decls), len(after.Decls))
+	}
+	return nil
+}
+
+// fixElse fixes golint's complaint about an else block following an if block
+// that ends with a return statement, by outdenting the else block.
+// Else blocks that declare names are left alone, since outdented
+// the names could clash with or shadow those of the enclosing block.
+func fixElse(fset *token.FileSet, f *ast.File, p Problem) bool {
+	fixed := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		if fixed {
+			return false
+		}
+		var list *[]ast.Stmt
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			list = &n.List
+		case *ast.CaseClause:
+			list = &n.Body
+		case *ast.CommClause:
+			list = &n.Body
+		default:
+			return true
+		}
+		for i, stmt := range *list {
+			ifs, ok := stmt.(*ast.IfStmt)
+			if !ok || ifs.Init != nil {
+				continue
+			}
+			els, ok := ifs.Else.(*ast.BlockStmt)
+			if !ok || fset.Position(els.Pos()).Line != p.Line {
+				continue
+			}
+			if declares(els.List) {
+				return false
+			}
+			ifs.Else = nil
+			var out []ast.Stmt
+			out = append(out, (*list)[:i+1]...)
+			out = append(out, els.List...)
+			out = append(out, (*list)[i+1:]...)
+			*list = out
+			fixed = true
+			return false
+		}
+		return true
+	})
+	return fixed
+}
+
+// declares reports whether any of stmts declares a name in their block.
+func declares(stmts []ast.Stmt) bool {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.DeclStmt:
+			return true
+		case *ast.AssignStmt:
+			if s.Tok == token.DEFINE {
+				return true
+			}
+		case *ast.LabeledStmt:
+			if declares([]ast.Stmt{s.Stmt}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fixReceiverName fixes golint's complaint about inconsistent receiver names,
+// by renaming the receiver of the method at the problem's line.
+func fixReceiverName(fset *token.FileSet, f *ast.File, p Problem) bool {
+	var from, to, typ string
+	if _, err := fmt.Sscanf(p.Text, "receiver name %s should be consistent with previous receiver name %s for %s", &from, &to, &typ); err != nil {
+		return false
+	}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 || len(fn.Recv.List[0].Names) != 1 {
+			continue
+		}
+		recv := fn.Recv.List[0].Names[0]
+		if recv.Name != from || fset.Position(fn.Pos()).Line > p.Line || fset.Position(fn.End()).Line < p.Line {
+			continue
+		}
+		// Renaming is only safe if the new name isn't already in use in the method.
+		clash := false
+		ast.Inspect(fn, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == to {
+				clash = true
+			}
+			return !clash
+		})
+		if clash || recv.Obj == nil {
+			return false
+		}
+		obj := recv.Obj
+		ast.Inspect(fn, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && (id == recv || id.Obj == obj) {
+				id.Name = to
+			}
+			return true
+		})
+		return true
+	}
+	return false
+}
+
+// A renaming fixes a golint problem about a declared name by renaming it.
+type renaming struct {
+	kind ast.ObjKind         // what sort of name is renamed
+	name func(string) string // the new name for an old one
+}
+
+// target returns the identifier that p is about in f, where it is declared,
+// or nil if it isn't the declaration of a name of the kind renamed.
+func (r *renaming) target(fset *token.FileSet, f *ast.File, p Problem) *ast.Ident {
+	var id *ast.Ident
+	ast.Inspect(f, func(n ast.Node) bool {
+		if x, ok := n.(*ast.Ident); ok {
+			if pos := fset.Position(x.Pos()); pos.Line == p.Line && pos.Column == p.Col {
+				id = x
+			}
+		}
+		return id == nil
+	})
+	if id == nil || id.Obj == nil || id.Obj.Kind != r.kind {
+		return nil
+	}
+	spec, ok := id.Obj.Decl.(*ast.ValueSpec)
+	if !ok {
+		return nil
+	}
+	for _, name := range spec.Names {
+		if name == id {
+			return id
+		}
+	}
+	return nil
+}
+
+// fix renames the name that p is about, and its uses in f.
+// Names are left alone if the new name is already used in the file.
+func (r *renaming) fix(fset *token.FileSet, f *ast.File, p Problem) bool {
+	id := r.target(fset, f, p)
+	if id == nil {
+		return false
+	}
+	to := r.name(id.Name)
+	if to == id.Name || uses(f, to) {
+		return false
+	}
+	obj := id.Obj
+	ast.Inspect(f, func(n ast.Node) bool {
+		if x, ok := n.(*ast.Ident); ok && (x == id || x.Obj == obj) {
+			x.Name = to
+		}
+		return true
+	})
+	return true
+}
+
+// uses reports whether name is used as an identifier anywhere in f.
+func uses(f *ast.File, name string) bool {
+	found := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// errorVarName returns the name golint wants for an error variable,
+// such as errNotFound for notFound.
+func errorVarName(name string) string {
+	prefix := "err"
+	if ast.IsExported(name) {
+		prefix = "Err"
+	}
+	return prefix + strings.ToUpper(name[:1]) + name[1:]
+}
+
+// camelCase returns the CamelCase form of an ALL_CAPS name,
+// such as MaxHTTPSize for MAX_HTTP_SIZE.
+func camelCase(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		if commonInitialisms[word] {
+			b.WriteString(word)
+			continue
+		}
+		b.WriteString(word[:1])
+		b.WriteString(strings.ToLower(word[1:]))
+	}
+	if b.Len() == 0 {
+		return name
+	}
+	return b.String()
+}
+
+// commonInitialisms are the initialisms golint wants kept in upper case.
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true, "XMPP": true,
+	"XSRF": true, "XSS": true,
+}
+
+// fixIfError fixes golint's complaint about a redundant error check,
+//
+//	if err := f(); err != nil {
+//		return err
+//	}
+//	return nil
+//
+// by replacing both statements with return f().
+// Like golint, it assumes f returns the error interface, not a concrete type.
+// Checks that assign to an existing variable are left alone,
+// since a deferred function may look at it, as are checks with comments.
+func fixIfError(fset *token.FileSet, f *ast.File, p Problem) bool {
+	fixed := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		block, ok := n.(*ast.BlockStmt)
+		if fixed || !ok {
+			return !fixed
+		}
+		for i := 0; i+1 < len(block.List); i++ {
+			ifs, ok := block.List[i].(*ast.IfStmt)
+			if !ok || fset.Position(ifs.Pos()).Line != p.Line || ifs.Else != nil || len(ifs.Body.List) != 1 {
+				continue
+			}
+			assign, ok := ifs.Init.(*ast.AssignStmt)
+			if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+				continue
+			}
+			id, ok := assign.Lhs[0].(*ast.Ident)
+			if !ok || !isReturnOf(ifs.Body.List[0], id.Name) || !isReturnOf(block.List[i+1], "nil") {
+				continue
+			}
+			if cond, ok := ifs.Cond.(*ast.BinaryExpr); !ok || cond.Op != token.NEQ || !isName(cond.X, id.Name) || !isName(cond.Y, "nil") {
+				continue
+			}
+			if hasComments(f, ifs.Body.Lbrace, block.List[i+1].End()) {
+				return false
+			}
+			ret := &ast.ReturnStmt{Return: ifs.Pos(), Results: assign.Rhs}
+			block.List = append(block.List[:i], append([]ast.Stmt{ret}, block.List[i+2:]...)...)
+			if i+1 == len(block.List) {
+				// Don't leave a blank line where the check was.
+				block.Rbrace = ifs.Body.Lbrace
+			}
+			fixed = true
+			return false
+		}
+		return true
+	})
+	return fixed
+}
+
+// hasComments reports whether f has comments between from and to.
+func hasComments(f *ast.File, from, to token.Pos) bool {
+	for _, cg := range f.Comments {
+		if cg.Pos() < to && cg.End() > from {
+			return true
+		}
+	}
+	return false
+}
+
+// isReturnOf reports whether stmt is a return statement of just the named identifier.
+func isReturnOf(stmt ast.Stmt, name string) bool {
+	ret, ok := stmt.(*ast.ReturnStmt)
+	return ok && len(ret.Results) == 1 && isName(ret.Results[0], name)
+}
+
+func isName(x ast.Expr, name string) bool {
+	id, ok := x.(*ast.Ident)
+	return ok && id.Name == name
+}
@@ -0,0 +1,394 @@
+package fixhub
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+var fixFileTests = []struct {
+	desc    string
+	in      string
+	p       Problem
+	want    string
+	fixable bool
+}{
+	{
+		desc: "gofmt",
+		in:   "package p\nfunc  F() {}\n",
+		p:    Problem{Type: Gofmt, Text: "This file needs formatting with gofmt.", Fixable: true},
+		want: "package p\n\nfunc F() {}\n",
+	},
+	{
+		desc: "BOM and CRLF",
+		in:   "\xef\xbb\xbfpackage p\r\n\r\nfunc F() {}\r\n",
+		p:    Problem{Type: Encoding, Text: "File has Windows (CRLF) line endings.", Fixable: true},
+		want: "package p\n\nfunc F() {}\n",
+	},
+	{
+		desc: "else outdent",
+		in: `package p
+
+func f(x int) int {
+	if x > 0 {
+		return 1
+	} else {
+		x++
+	}
+	return x
+}
+`,
+		p: Problem{Line: 6, Type: Lint, Code: "indent", Text: "if block ends with a return statement, so drop this else and outdent its block"},
+		want: `package p
+
+func f(x int) int {
+	if x > 0 {
+		return 1
+	}
+	x++
+
+	return x
+}
+`,
+		fixable: true,
+	},
+	{
+		desc: "else declaring a name",
+		in: `package p
+
+func f(x int) int {
+	if x > 0 {
+		return 1
+	} else {
+		y := x + 1
+		x = y
+	}
+	y := 2
+	return x + y
+}
+`,
+		p: Problem{Line: 6, Type: Lint, Code: "indent", Text: "if block ends with a return statement, so drop this else and outdent its block"},
+		want: `package p
+
+func f(x int) int {
+	if x > 0 {
+		return 1
+	} else {
+		y := x + 1
+		x = y
+	}
+	y := 2
+	return x + y
+}
+`,
+		fixable: true,
+	},
+	{
+		desc: "receiver name",
+		in: `package p
+
+type T int
+
+func (t T) A() int { return int(t) }
+
+func (self T) B() int {
+	x := self + 1
+	return int(x)
+}
+`,
+		p: Problem{Line: 7, Type: Lint, Code: "naming", Text: "receiver name self should be consistent with previous receiver name t for T"},
+		want: `package p
+
+type T int
+
+func (t T) A() int { return int(t) }
+
+func (t T) B() int {
+	x := t + 1
+	return int(x)
+}
+`,
+		fixable: true,
+	},
+	{
+		desc: "receiver name clash",
+		in: `package p
+
+type T int
+
+func (self T) B(t int) int { return int(self) + t }
+`,
+		p: Problem{Line: 5, Type: Lint, Code: "naming", Text: "receiver name self should be consistent with previous receiver name t for T"},
+		want: `package p
+
+type T int
+
+func (self T) B(t int) int { return int(self) + t }
+`,
+		fixable: true,
+	},
+	{
+		desc: "error var name",
+		in: `package p
+
+import "errors"
+
+var notFound = errors.New("not found")
+
+func f() error { return notFound }
+`,
+		p: Problem{Line: 5, Col: 5, Type: Lint, Code: "naming", Text: "error var notFound should have name of the form errFoo"},
+		want: `package p
+
+import "errors"
+
+var errNotFound = errors.New("not found")
+
+func f() error { return errNotFound }
+`,
+		fixable: true,
+	},
+	{
+		desc: "ALL_CAPS constant",
+		in: `package p
+
+func f(n int) bool {
+	const MAX_HTTP_SIZE = 10
+	return n < MAX_HTTP_SIZE
+}
+`,
+		p: Problem{Line: 4, Col: 8, Type: Lint, Code: "naming", Text: "don't use ALL_CAPS in Go names; use CamelCase"},
+		want: `package p
+
+func f(n int) bool {
+	const MaxHTTPSize = 10
+	return n < MaxHTTPSize
+}
+`,
+		fixable: true,
+	},
+	{
+		desc: "ALL_CAPS variable",
+		in: `package p
+
+var MAX_SIZE = 10
+`,
+		p: Problem{Line: 3, Col: 5, Type: Lint, Code: "naming", Text: "don't use ALL_CAPS in Go names; use CamelCase"},
+		want: `package p
+
+var MAX_SIZE = 10
+`,
+		fixable: true,
+	},
+	{
+		desc: "ALL_CAPS name clash",
+		in: `package p
+
+const MAX_SIZE = 10
+
+var MaxSize = MAX_SIZE
+`,
+		p: Problem{Line: 3, Col: 7, Type: Lint, Code: "naming", Text: "don't use ALL_CAPS in Go names; use CamelCase"},
+		want: `package p
+
+const MAX_SIZE = 10
+
+var MaxSize = MAX_SIZE
+`,
+		fixable: true,
+	},
+	{
+		desc: "redundant error check",
+		in: `package p
+
+func f() error { return nil }
+
+func g() error {
+	if err := f(); err != nil {
+		return err
+	}
+	return nil
+}
+`,
+		p: Problem{Line: 6, Type: Lint, Text: "redundant if ...; err != nil check, just return error instead."},
+		want: `package p
+
+func f() error { return nil }
+
+func g() error {
+	return f()
+}
+`,
+		fixable: true,
+	},
+	{
+		desc: "redundant error check assigning",
+		in: `package p
+
+func f() error { return nil }
+
+func g() (err error) {
+	defer func() { println(err) }()
+	if err = f(); err != nil {
+		return err
+	}
+	return nil
+}
+`,
+		p: Problem{Line: 7, Type: Lint, Text: "redundant if ...; err != nil check, just return error instead."},
+		want: `package p
+
+func f() error { return nil }
+
+func g() (err error) {
+	defer func() { println(err) }()
+	if err = f(); err != nil {
+		return err
+	}
+	return nil
+}
+`,
+		fixable: true,
+	},
+	{
+		desc: "redundant error check with a comment",
+		in: `package p
+
+func f() error { return nil }
+
+func g() error {
+	if err := f(); err != nil {
+		return err // f failed
+	}
+	return nil
+}
+`,
+		p: Problem{Line: 6, Type: Lint, Text: "redundant if ...; err != nil check, just return error instead."},
+		want: `package p
+
+func f() error { return nil }
+
+func g() error {
+	if err := f(); err != nil {
+		return err // f failed
+	}
+	return nil
+}
+`,
+		fixable: true,
+	},
+}
+
+func TestFixFile(t *testing.T) {
+	for _, tt := range fixFileTests {
+		tt.p.File = "x.go"
+		if tt.p.Type == Lint {
+			if got := fixerFor(tt.p) != nil; got != tt.fixable {
+				t.Errorf("%s: fixable = %v, want %v", tt.desc, got, tt.fixable)
+				continue
+			}
+			tt.p.Fixable = tt.fixable
+		}
+		out, err := FixFile("x.go", []byte(tt.in), Problems{tt.p})
+		if err != nil {
+			t.Errorf("%s: FixFile: %v", tt.desc, err)
+			continue
+		}
+		if string(out) != tt.want {
+			t.Errorf("%s: FixFile output:\n%s\nwant:\n%s", tt.desc, out, tt.want)
+		}
+	}
+}
+
+func TestFixableIn(t *testing.T) {
+	errVar := Problem{File: "a.go", Line: 5, Col: 5, Type: Lint, Code: "naming", Text: "error var notFound should have name of the form errFoo"}
+	exported := Problem{File: "a.go", Line: 5, Col: 5, Type: Lint, Code: "naming", Text: "error var NotFound should have name of the form ErrFoo"}
+	local := Problem{File: "a.go", Line: 4, Col: 8, Type: Lint, Code: "naming", Text: "don't use ALL_CAPS in Go names; use CamelCase"}
+	const a = "package p\n\nimport \"errors\"\n\nvar notFound = errors.New(\"not found\")\n"
+	const exportedA = "package p\n\nimport \"errors\"\n\nvar NotFound = errors.New(\"not found\")\n"
+	const mainA = "package main\n\nimport \"errors\"\n\nvar NotFound = errors.New(\"not found\")\n"
+	const localA = "package p\n\nfunc f() int {\n\tconst MAX_SIZE = 1\n\treturn MAX_SIZE\n}\n"
+	tests := []struct {
+		desc  string
+		files map[string][]byte
+		whole bool
+		p     Problem
+		want  bool
+	}{
+		{"alone", map[string][]byte{"a.go": []byte(a)}, true, errVar, true},
+		{"used elsewhere", map[string][]byte{"a.go": []byte(a), "b.go": []byte("package p\n\nvar e = notFound\n")}, true, errVar, false},
+		{"new name used elsewhere", map[string][]byte{"a.go": []byte(a), "b.go": []byte("package p\n\nvar errNotFound error\n")}, true, errVar, false},
+		{"unrelated file", map[string][]byte{"a.go": []byte(a), "b.go": []byte("package p\n\nvar e error\n")}, true, errVar, true},
+		{"not the whole package", map[string][]byte{"a.go": []byte(a)}, false, errVar, false},
+		{"exported", map[string][]byte{"a.go": []byte(exportedA)}, true, exported, false},
+		{"exported in main", map[string][]byte{"a.go": []byte(mainA)}, true, exported, true},
+		{"local", map[string][]byte{"a.go": []byte(localA), "b.go": []byte("package p\n\nconst MAX_SIZE = 2\n")}, false, local, true},
+	}
+	for _, tt := range tests {
+		if got := fixableIn(tt.files, tt.whole, tt.p); got != tt.want {
+			t.Errorf("%s: fixableIn = %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestCommitFixes(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "fix", map[string][]byte{
+		"a.go":          []byte("package a\nfunc  A() {}\n"),
+		"b.go":          []byte("package a\n\nfunc B() {}\n"),
+		"c_string.go":   []byte("package a\nfunc  C() {}\n"),
+		"vendor/v/v.go": []byte("package v\nfunc  V() {}\n"),
+	})
+	c, err := NewClient("faker", "fix", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	c.Enabled = []ProblemType{Gofmt}
+	c.ProtectedPaths = append([]string{"*_string.go"}, DefaultProtectedPaths...)
+
+	fc, err := c.CommitFixes("master", "")
+	if err != nil {
+		t.Fatalf("CommitFixes: %v", err)
+	}
+	if fc.SHA == "" || !reflect.DeepEqual(fc.Files, []string{"a.go"}) || len(fc.Fixed) != 1 || len(fc.Excluded) != 2 {
+		t.Errorf("CommitFixes = %+v, want a commit fixing a.go, excluding the protected files", fc)
+	}
+	if got, _ := srv.File("faker", "fix", "master", "a.go"); string(got) != "package a\n\nfunc A() {}\n" {
+		t.Errorf("a.go = %q, want it formatted", got)
+	}
+	if got := srv.CommitMessages("faker", "fix", "master"); len(got) != 2 || !strings.HasPrefix(got[0], "Fix a problem found by fixhub\n\na.go:") {
+		t.Errorf("commits = %q, want a fix on top of the initial commit", got)
+	}
+
+	// Now there is nothing to fix, so nothing is committed.
+	if fc, err = c.CommitFixes("master", ""); err != nil {
+		t.Fatalf("CommitFixes: %v", err)
+	}
+	if fc.SHA != "" || len(srv.CommitMessages("faker", "fix", "master")) != 2 {
+		t.Errorf("CommitFixes with nothing to fix committed %q", fc.SHA)
+	}
+}
+
+func TestVerifyFix(t *testing.T) {
+	src := []byte("package p\n\nfunc F() {}\n")
+	tests := []struct {
+		out string
+		ok  bool
+	}{
+		{"package p\n\nfunc F() {}\n", true},
+		{"package p\n\nfunc F() {\n", false},
+		{"package q\n\nfunc F() {}\n", false},
+		{"package p\n\nfunc F() {}\n\nfunc G() {}\n", false},
+	}
+	for _, tt := range tests {
+		if err := verifyFix("p.go", src, []byte(tt.out)); (err == nil) != tt.ok {
+			t.Errorf("verifyFix(%q) = %v, want ok=%v", tt.out, err, tt.ok)
+		}
+	}
+}
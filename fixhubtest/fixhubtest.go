@@ -0,0 +1,1106 @@
+/*
+Package fixhubtest provides a fake GitHub API server for testing code
+built on package fixhub without network access.
+
+It implements the parts of the API that fixhub uses for reading
+repositories (listing an owner's repositories, and reading their
+languages, commits, trees, blobs and latest release, and comparing commits),
+for writing fixes (creating trees, commits, refs and forks,
+updating and deleting refs, and updating file contents),
+for reviewing pull requests (listing them and their files,
+and making and editing review comments),
+for setting commit statuses,
+and for cleaning up forks (getting the authenticated user,
+listing branches, and deleting repositories).
+Repositories are held in memory.
+*/
+package fixhubtest
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake GitHub API server.
+type Server struct {
+	*httptest.Server
+
+	// BaseURL is the API base URL to give to clients.
+	BaseURL string
+
+	// User is the login of the authenticated user, who owns any forks.
+	// It defaults to "fixhub".
+	User string
+
+	// TruncateTrees, if positive, is the number of entries beyond which
+	// tree listings are truncated, as GitHub does for large trees.
+	TruncateTrees int
+
+	// ForkDelay is how long forks take to be made. Until then,
+	// requests about them are answered as if they did not exist yet.
+	ForkDelay time.Duration
+
+	// RateLimit, if positive, is the number of requests to serve before refusing
+	// more as exceeding GitHub's rate limit, until ResetRateLimit is called.
+	// Otherwise a limit of 5000 is reported but never enforced.
+	RateLimit int
+
+	mu       sync.Mutex
+	requests int                    // number served, for rate limit headers
+	comments int                    // number of review comments made, for their IDs
+	repos    map[string]*repo       // "owner/name" -> repo
+	blobs    map[string][]byte      // SHA-1 -> content
+	trees    map[string][]treeEntry // SHA-1 -> entries, named relative to the tree
+	symlinks map[string]bool        // SHA-1s of blobs that are symlink targets
+}
+
+type repo struct {
+	owner, name   string
+	defaultBranch string
+	refs          map[string]string  // ref name (e.g. "heads/master") -> commit SHA-1
+	commits       map[string]*commit // SHA-1 -> commit
+	parent        *repo              // set for forks
+	ready         time.Time          // for forks, when they finish being made
+	archived      bool
+	releases      []string            // tag names, oldest first
+	pulls         []*pull             // pull request N is pulls[N-1]
+	statuses      map[string][]Status // commit SHA-1 -> statuses, oldest first
+}
+
+// A Status is a commit status.
+type Status struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+type commit struct {
+	sha     string
+	parent  string            // SHA-1; empty for the first commit
+	message string            //
+	files   map[string]string // path -> blob SHA-1
+	tree    string            // SHA-1 of the root tree
+}
+
+// NewServer starts and returns a new Server with no repositories.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		User:     "fixhub",
+		repos:    make(map[string]*repo),
+		blobs:    make(map[string][]byte),
+		trees:    make(map[string][]treeEntry),
+		symlinks: make(map[string]bool),
+	}
+	s.Server = httptest.NewServer(s)
+	s.BaseURL = s.URL + "/gh/"
+	return s
+}
+
+func hash(kind string, data []byte) string {
+	return fmt.Sprintf("%02x", sha1.Sum(append([]byte(kind+"\x00"), data...)))
+}
+
+func (s *Server) addBlob(data []byte) string {
+	sha := hash("blob", data)
+	s.blobs[sha] = data
+	return sha
+}
+
+// addTree adds the tree holding files, keyed by path relative to the tree,
+// along with its subtrees. It returns the SHA-1 of the tree. s.mu must be held.
+func (s *Server) addTree(files map[string]string) string {
+	entries := []treeEntry{}
+	subdirs := make(map[string]map[string]string)
+	for path, sha := range files {
+		if i := strings.Index(path, "/"); i >= 0 {
+			dir := path[:i]
+			if subdirs[dir] == nil {
+				subdirs[dir] = make(map[string]string)
+			}
+			subdirs[dir][path[i+1:]] = sha
+			continue
+		}
+		size := len(s.blobs[sha])
+		mode := "100644"
+		if s.symlinks[sha] {
+			mode = "120000"
+		}
+		entries = append(entries, treeEntry{Path: path, Mode: mode, Type: "blob", SHA: sha, Size: &size})
+	}
+	for dir, files := range subdirs {
+		entries = append(entries, treeEntry{Path: dir, Mode: "040000", Type: "tree", SHA: s.addTree(files)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	var h string
+	for _, e := range entries {
+		h += e.Mode + " " + e.Path + " " + e.SHA + "\n"
+	}
+	sha := hash("tree", []byte(h))
+	s.trees[sha] = entries
+	return sha
+}
+
+// addCommit adds a commit to r. s.mu must be held.
+func (s *Server) addCommit(r *repo, parent, message string, files map[string]string) *commit {
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	h := parent + "\n" + message
+	for _, path := range paths {
+		h += "\n" + path + " " + files[path]
+	}
+	c := &commit{
+		sha:     hash("commit", []byte(h)),
+		parent:  parent,
+		message: message,
+		files:   files,
+		tree:    s.addTree(files),
+	}
+	r.commits[c.sha] = c
+	return c
+}
+
+// AddRepo adds a repository with a single commit on its master branch
+// containing the given files, keyed by slash-separated path.
+// It returns the SHA-1 of the commit.
+func (s *Server) AddRepo(owner, name string, files map[string][]byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &repo{
+		owner:         owner,
+		name:          name,
+		defaultBranch: "master",
+		refs:          make(map[string]string),
+		commits:       make(map[string]*commit),
+	}
+	blobs := make(map[string]string)
+	for path, data := range files {
+		blobs[path] = s.addBlob(data)
+	}
+	c := s.addCommit(r, "", "Initial commit", blobs)
+	r.refs["heads/master"] = c.sha
+	s.repos[owner+"/"+name] = r
+	return c.sha
+}
+
+// AddEmptyRepo adds a repository with no commits, as GitHub makes
+// when it isn't asked to add a README. Its default branch is main.
+func (s *Server) AddEmptyRepo(owner, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos[owner+"/"+name] = &repo{
+		owner:         owner,
+		name:          name,
+		defaultBranch: "main",
+		refs:          make(map[string]string),
+		commits:       make(map[string]*commit),
+	}
+}
+
+// AddSymlink commits a symbolic link at path to target, which need not exist,
+// to the default branch of a repository. It returns the SHA-1 of the new commit.
+func (s *Server) AddSymlink(owner, name, path, target string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return "", fmt.Errorf("no repository %s/%s", owner, name)
+	}
+	head, ok := r.refs["heads/"+r.defaultBranch]
+	if !ok {
+		return "", fmt.Errorf("%s/%s is empty", owner, name)
+	}
+	blobs := make(map[string]string)
+	for p, sha := range r.commits[head].files {
+		blobs[p] = sha
+	}
+	// Git stores a symlink as a blob of its target.
+	sha := s.addBlob([]byte(target))
+	s.symlinks[sha] = true
+	blobs[path] = sha
+	c := s.addCommit(r, head, "Link "+path, blobs)
+	r.refs["heads/"+r.defaultBranch] = c.sha
+	return c.sha, nil
+}
+
+// RenameBranch renames a branch of a repository, as when a repository's
+// default branch is renamed from master to main.
+func (s *Server) RenameBranch(owner, name, from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return fmt.Errorf("no repository %s/%s", owner, name)
+	}
+	sha, ok := r.refs["heads/"+from]
+	if !ok {
+		return fmt.Errorf("no branch %s in %s/%s", from, owner, name)
+	}
+	delete(r.refs, "heads/"+from)
+	r.refs["heads/"+to] = sha
+	if r.defaultBranch == from {
+		r.defaultBranch = to
+	}
+	return nil
+}
+
+// AddRepoFromDir is like AddRepo, but takes the files from a directory tree.
+func (s *Server) AddRepoFromDir(owner, name, dir string) (string, error) {
+	files := make(map[string][]byte)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path) // can't fail
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return s.AddRepo(owner, name, files), nil
+}
+
+// File returns the content of a file at the head of a branch,
+// and whether it exists.
+func (s *Server) File(owner, name, branch, path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return nil, false
+	}
+	c := r.commits[r.refs["heads/"+branch]]
+	if c == nil {
+		return nil, false
+	}
+	sha, ok := c.files[path]
+	if !ok {
+		return nil, false
+	}
+	return s.blobs[sha], true
+}
+
+// CommitMessages returns the messages of the commits on a branch, newest first.
+func (s *Server) CommitMessages(owner, name, branch string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return nil
+	}
+	var msgs []string
+	for c := r.commits[r.refs["heads/"+branch]]; c != nil; c = r.commits[c.parent] {
+		msgs = append(msgs, c.message)
+	}
+	return msgs
+}
+
+// AddRelease tags the head of a branch and publishes a release of the tag,
+// which becomes the repository's latest release.
+func (s *Server) AddRelease(owner, name, branch, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return fmt.Errorf("no repository %s/%s", owner, name)
+	}
+	sha, ok := r.refs["heads/"+branch]
+	if !ok {
+		return fmt.Errorf("no branch %s in %s/%s", branch, owner, name)
+	}
+	r.refs["tags/"+tag] = sha
+	r.releases = append(r.releases, tag)
+	return nil
+}
+
+// Statuses returns the statuses set on a commit, oldest first.
+func (s *Server) Statuses(owner, name, sha string) []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return nil
+	}
+	return append([]Status(nil), r.statuses[sha]...)
+}
+
+// Archive marks a repository as archived.
+func (s *Server) Archive(owner, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return fmt.Errorf("no repository %s/%s", owner, name)
+	}
+	r.archived = true
+	return nil
+}
+
+// ResetRateLimit lets the server serve another RateLimit requests.
+func (s *Server) ResetRateLimit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = 0
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Report a rate limit as GitHub does.
+	limit := s.RateLimit
+	if limit <= 0 {
+		limit = 5000
+	}
+	refuse := s.RateLimit > 0 && s.requests >= limit
+	if !refuse {
+		s.requests++
+	}
+	remaining := limit - s.requests
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	if refuse {
+		http.Error(w, `{"message": "API rate limit exceeded"}`, http.StatusForbidden)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/gh/")
+	if path == req.URL.Path {
+		// didn't have prefix
+		http.Error(w, "bad path", http.StatusForbidden)
+		return
+	}
+	if path == "user" && req.Method == "GET" {
+		writeJSON(w, http.StatusOK, map[string]string{"login": s.User})
+		return
+	}
+	parts := strings.SplitN(path, "/", 4)
+	if len(parts) == 3 && (parts[0] == "orgs" || parts[0] == "users") && parts[2] == "repos" && req.Method == "GET" {
+		s.serveOwnerRepos(w, parts[1])
+		return
+	}
+	if len(parts) < 3 || parts[0] != "repos" {
+		log.Printf("fixhubtest: unhandled request %s %s", req.Method, req.URL)
+		w.WriteHeader(http.StatusTeapot)
+		return
+	}
+	r := s.repos[parts[1]+"/"+parts[2]]
+	if r == nil || time.Now().Before(r.ready) {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	rest := ""
+	if len(parts) == 4 {
+		rest = parts[3]
+	}
+
+	switch {
+	case req.Method == "GET" && rest == "":
+		s.serveRepo(w, r)
+	case req.Method == "DELETE" && rest == "":
+		s.deleteRepo(w, r)
+	case req.Method == "GET" && rest == "branches":
+		s.serveBranches(w, r)
+	case req.Method == "GET" && rest == "languages":
+		writeJSON(w, http.StatusOK, s.languages(r))
+	case req.Method == "GET" && strings.HasPrefix(rest, "commits/"):
+		s.serveCommit(w, r, strings.TrimPrefix(rest, "commits/"))
+	case req.Method == "GET" && strings.HasPrefix(rest, "compare/"):
+		s.serveCompare(w, r, strings.TrimPrefix(rest, "compare/"))
+	case req.Method == "GET" && strings.HasPrefix(rest, "git/trees/"):
+		s.serveTree(w, req, r, strings.TrimPrefix(rest, "git/trees/"))
+	case req.Method == "GET" && strings.HasPrefix(rest, "git/blobs/"):
+		s.serveBlob(w, strings.TrimPrefix(rest, "git/blobs/"))
+	case req.Method == "GET" && strings.HasPrefix(rest, "git/refs/"):
+		s.serveRef(w, r, strings.TrimPrefix(rest, "git/refs/"))
+	case req.Method == "POST" && rest == "git/refs":
+		s.createRef(w, req, r)
+	case req.Method == "PATCH" && strings.HasPrefix(rest, "git/refs/"):
+		s.updateRef(w, req, r, strings.TrimPrefix(rest, "git/refs/"))
+	case req.Method == "DELETE" && strings.HasPrefix(rest, "git/refs/"):
+		s.deleteRef(w, r, strings.TrimPrefix(rest, "git/refs/"))
+	case req.Method == "POST" && rest == "git/trees":
+		s.createTree(w, req)
+	case req.Method == "POST" && rest == "git/commits":
+		s.createCommit(w, req, r)
+	case req.Method == "PUT" && strings.HasPrefix(rest, "contents/"):
+		s.updateFile(w, req, r, strings.TrimPrefix(rest, "contents/"))
+	case req.Method == "GET" && rest == "releases/latest":
+		s.serveLatestRelease(w, r)
+	case req.Method == "POST" && strings.HasPrefix(rest, "statuses/"):
+		s.createStatus(w, req, r, strings.TrimPrefix(rest, "statuses/"))
+	case rest == "pulls" || strings.HasPrefix(rest, "pulls/"):
+		s.servePulls(w, req, r, strings.TrimPrefix(strings.TrimPrefix(rest, "pulls"), "/"))
+	case req.Method == "POST" && rest == "forks":
+		s.createFork(w, r)
+	default:
+		log.Printf("fixhubtest: unhandled request %s %s", req.Method, req.URL)
+		w.WriteHeader(http.StatusTeapot)
+	}
+}
+
+// resolve resolves a branch name, tag or commit SHA-1 to a commit.
+func (r *repo) resolve(ref string) *commit {
+	if c := r.commits[ref]; c != nil {
+		return c
+	}
+	for _, prefix := range []string{"", "heads/", "tags/"} {
+		if sha, ok := r.refs[prefix+ref]; ok {
+			return r.commits[sha]
+		}
+	}
+	return nil
+}
+
+// serveOwnerRepos serves the list of an owner's repositories.
+// Owners are both organizations and users.
+func (s *Server) serveOwnerRepos(w http.ResponseWriter, owner string) {
+	var names []string
+	for key, r := range s.repos {
+		if r.owner == owner {
+			names = append(names, key)
+		}
+	}
+	if len(names) == 0 {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	sort.Strings(names)
+	var v []interface{}
+	for _, name := range names {
+		v = append(v, s.repoJSON(s.repos[name]))
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) serveRepo(w http.ResponseWriter, r *repo) {
+	writeJSON(w, http.StatusOK, s.repoJSON(r))
+}
+
+// repoJSON returns the JSON object describing r.
+func (s *Server) repoJSON(r *repo) map[string]interface{} {
+	v := map[string]interface{}{
+		"name":           r.name,
+		"full_name":      r.owner + "/" + r.name,
+		"owner":          map[string]string{"login": r.owner},
+		"html_url":       "https://github.com/" + r.owner + "/" + r.name,
+		"default_branch": r.defaultBranch,
+		"fork":           r.parent != nil,
+		"archived":       r.archived,
+	}
+	if r.parent != nil {
+		v["parent"] = map[string]interface{}{
+			"name":      r.parent.name,
+			"full_name": r.parent.owner + "/" + r.parent.name,
+			"owner":     map[string]string{"login": r.parent.owner},
+		}
+	}
+	if langs := s.languages(r); len(langs) > 0 {
+		v["language"] = "Go"
+	}
+	return v
+}
+
+// languages returns the number of bytes of code in each language
+// on r's default branch. Only Go is detected.
+func (s *Server) languages(r *repo) map[string]int {
+	langs := make(map[string]int)
+	if c := r.commits[r.refs["heads/"+r.defaultBranch]]; c != nil {
+		for path, sha := range c.files {
+			if strings.HasSuffix(path, ".go") {
+				langs["Go"] += len(s.blobs[sha])
+			}
+		}
+	}
+	return langs
+}
+
+func (s *Server) serveCommit(w http.ResponseWriter, r *repo, ref string) {
+	if len(r.commits) == 0 {
+		http.Error(w, `{"message": "Git Repository is empty."}`, http.StatusConflict)
+		return
+	}
+	c := r.resolve(ref)
+	if c == nil {
+		http.Error(w, `{"message": "No commit found for SHA: `+ref+`"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sha": c.sha,
+		"commit": map[string]interface{}{
+			"message": c.message,
+			"tree":    map[string]string{"sha": c.tree},
+		},
+		"parents": parentsJSON(c),
+	})
+}
+
+// serveCompare compares two commits, named as "base...head".
+// Patches are left out.
+func (s *Server) serveCompare(w http.ResponseWriter, r *repo, spec string) {
+	baseRef, headRef, ok := strings.Cut(spec, "...")
+	base, head := r.resolve(baseRef), r.resolve(headRef)
+	if !ok || base == nil || head == nil {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	ancestors := func(c *commit) map[string]bool {
+		m := make(map[string]bool)
+		for ; c != nil; c = r.commits[c.parent] {
+			m[c.sha] = true
+		}
+		return m
+	}
+	ofBase, ofHead := ancestors(base), ancestors(head)
+	var ahead []*commit
+	for c := head; c != nil && !ofBase[c.sha]; c = r.commits[c.parent] {
+		ahead = append([]*commit{c}, ahead...)
+	}
+	behind := 0
+	for c := base; c != nil && !ofHead[c.sha]; c = r.commits[c.parent] {
+		behind++
+	}
+	status := "identical"
+	switch {
+	case len(ahead) > 0 && behind > 0:
+		status = "diverged"
+	case len(ahead) > 0:
+		status = "ahead"
+	case behind > 0:
+		status = "behind"
+	}
+	commits := []interface{}{}
+	for _, c := range ahead {
+		commits = append(commits, map[string]interface{}{
+			"sha":     c.sha,
+			"commit":  map[string]interface{}{"message": c.message, "tree": map[string]string{"sha": c.tree}},
+			"parents": parentsJSON(c),
+		})
+	}
+
+	// A file that was removed is renamed to one that was added
+	// with the same content or, failing that, mostly the same lines, as git finds them.
+	var removed []string
+	for path := range base.files {
+		if _, ok := head.files[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	var added []string
+	files := []compareFile{}
+	for path, sha := range head.files {
+		switch old, ok := base.files[path]; {
+		case !ok:
+			added = append(added, path)
+		case old != sha:
+			files = append(files, compareFile{Filename: path, SHA: sha, Status: "modified"})
+		}
+	}
+	sort.Strings(added)
+	renamed := make(map[string]string) // new path -> old path
+	for _, exact := range []bool{true, false} {
+		for _, path := range added {
+			for i, from := range removed {
+				if renamed[path] != "" || from == "" {
+					continue
+				}
+				a, b := s.blobs[base.files[from]], s.blobs[head.files[path]]
+				if exact && base.files[from] == head.files[path] || !exact && similar(a, b) {
+					renamed[path], removed[i] = from, ""
+				}
+			}
+		}
+	}
+	for _, path := range added {
+		f := compareFile{Filename: path, SHA: head.files[path], Status: "added"}
+		if from := renamed[path]; from != "" {
+			f.Status, f.PreviousFilename = "renamed", from
+		}
+		files = append(files, f)
+	}
+	for _, path := range removed {
+		if path != "" {
+			files = append(files, compareFile{Filename: path, SHA: base.files[path], Status: "removed"})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Filename < files[j].Filename })
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"base_commit":   map[string]interface{}{"sha": base.sha},
+		"status":        status,
+		"ahead_by":      len(ahead),
+		"behind_by":     behind,
+		"total_commits": len(ahead),
+		"commits":       commits,
+		"files":         files,
+	})
+}
+
+// similar reports whether at least half the lines of a and b are in both.
+func similar(a, b []byte) bool {
+	la, lb := splitLines(a), splitLines(b)
+	count := make(map[string]int)
+	for _, l := range la {
+		count[l]++
+	}
+	common := 0
+	for _, l := range lb {
+		if count[l] > 0 {
+			count[l]--
+			common++
+		}
+	}
+	return common > 0 && 4*common >= len(la)+len(lb)
+}
+
+type compareFile struct {
+	Filename         string `json:"filename"`
+	SHA              string `json:"sha"`
+	Status           string `json:"status"`
+	PreviousFilename string `json:"previous_filename,omitempty"`
+}
+
+func parentsJSON(c *commit) []map[string]string {
+	if c.parent == "" {
+		return []map[string]string{}
+	}
+	return []map[string]string{{"sha": c.parent}}
+}
+
+type treeEntry struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+	Size *int   `json:"size,omitempty"` // only for blobs
+}
+
+// serveTree serves a tree, named by its SHA-1 or that of a commit.
+func (s *Server) serveTree(w http.ResponseWriter, req *http.Request, r *repo, sha string) {
+	root := sha
+	if c := r.commits[sha]; c != nil {
+		root = c.tree
+	}
+	entries, ok := s.trees[root]
+	if !ok {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	if req.URL.Query().Get("recursive") != "" {
+		entries = s.flatten("", root)
+	}
+	truncated := false
+	if s.TruncateTrees > 0 && len(entries) > s.TruncateTrees {
+		entries, truncated = entries[:s.TruncateTrees], true
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sha":       root,
+		"tree":      entries,
+		"truncated": truncated,
+	})
+}
+
+// flatten returns the entries of a tree and all its subtrees,
+// with paths prefixed by prefix.
+func (s *Server) flatten(prefix, sha string) []treeEntry {
+	var all []treeEntry
+	for _, e := range s.trees[sha] {
+		e.Path = path.Join(prefix, e.Path)
+		all = append(all, e)
+		if e.Type == "tree" {
+			all = append(all, s.flatten(e.Path, e.SHA)...)
+		}
+	}
+	return all
+}
+
+func (s *Server) serveBlob(w http.ResponseWriter, sha string) {
+	data, ok := s.blobs[sha]
+	if !ok {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sha":      sha,
+		"content":  base64.StdEncoding.EncodeToString(data),
+		"encoding": "base64",
+		"size":     len(data),
+	})
+}
+
+func refJSON(name, sha string) map[string]interface{} {
+	return map[string]interface{}{
+		"ref":    "refs/" + name,
+		"object": map[string]string{"type": "commit", "sha": sha},
+	}
+}
+
+func (s *Server) serveRef(w http.ResponseWriter, r *repo, name string) {
+	sha, ok := r.refs[name]
+	if !ok {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, refJSON(name, sha))
+}
+
+func (s *Server) serveLatestRelease(w http.ResponseWriter, r *repo) {
+	if len(r.releases) == 0 {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	n := len(r.releases)
+	tag := r.releases[n-1]
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":       n,
+		"tag_name": tag,
+		"name":     tag,
+	})
+}
+
+func (s *Server) createRef(w http.ResponseWriter, req *http.Request, r *repo) {
+	var body struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, `{"message": "Problems parsing JSON"}`, http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimPrefix(body.Ref, "refs/")
+	if name == body.Ref || r.commits[body.SHA] == nil {
+		http.Error(w, `{"message": "Validation Failed"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	if _, ok := r.refs[name]; ok {
+		http.Error(w, `{"message": "Reference already exists"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	r.refs[name] = body.SHA
+	writeJSON(w, http.StatusCreated, refJSON(name, body.SHA))
+}
+
+// updateRef moves a ref, refusing to unless it is a fast-forward or forced.
+func (s *Server) updateRef(w http.ResponseWriter, req *http.Request, r *repo, name string) {
+	var body struct {
+		SHA   string `json:"sha"`
+		Force bool   `json:"force"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, `{"message": "Problems parsing JSON"}`, http.StatusBadRequest)
+		return
+	}
+	old, ok := r.refs[name]
+	if !ok {
+		http.Error(w, `{"message": "Reference does not exist"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	c := r.commits[body.SHA]
+	if c == nil {
+		http.Error(w, `{"message": "Object does not exist"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	if !body.Force {
+		for c != nil && c.sha != old {
+			c = r.commits[c.parent]
+		}
+		if c == nil {
+			http.Error(w, `{"message": "Update is not a fast forward"}`, http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	r.refs[name] = body.SHA
+	writeJSON(w, http.StatusOK, refJSON(name, body.SHA))
+}
+
+func (s *Server) deleteRef(w http.ResponseWriter, r *repo, name string) {
+	if _, ok := r.refs[name]; !ok {
+		http.Error(w, `{"message": "Reference does not exist"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	delete(r.refs, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createTree creates a tree from a base tree and entries that add or
+// replace files by content or blob SHA-1, or remove them with a null SHA-1.
+func (s *Server) createTree(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		BaseTree string `json:"base_tree"`
+		Tree     []struct {
+			Path    string  `json:"path"`
+			SHA     *string `json:"sha"`
+			Content *string `json:"content"`
+		} `json:"tree"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, `{"message": "Problems parsing JSON"}`, http.StatusBadRequest)
+		return
+	}
+	files := make(map[string]string)
+	if body.BaseTree != "" {
+		if _, ok := s.trees[body.BaseTree]; !ok {
+			http.Error(w, `{"message": "Invalid tree info"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		files = s.treeFiles(body.BaseTree)
+	}
+	for _, e := range body.Tree {
+		switch {
+		case e.Content != nil:
+			files[e.Path] = s.addBlob([]byte(*e.Content))
+		case e.SHA != nil:
+			if _, ok := s.blobs[*e.SHA]; !ok {
+				http.Error(w, `{"message": "Invalid tree info"}`, http.StatusUnprocessableEntity)
+				return
+			}
+			files[e.Path] = *e.SHA
+		default:
+			delete(files, e.Path)
+		}
+	}
+	sha := s.addTree(files)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"sha":  sha,
+		"tree": s.trees[sha],
+	})
+}
+
+// treeFiles returns the blobs in a tree and its subtrees, keyed by path.
+func (s *Server) treeFiles(sha string) map[string]string {
+	files := make(map[string]string)
+	for _, e := range s.flatten("", sha) {
+		if e.Type == "blob" {
+			files[e.Path] = e.SHA
+		}
+	}
+	return files
+}
+
+// createCommit creates a commit with a single parent, or none.
+func (s *Server) createCommit(w http.ResponseWriter, req *http.Request, r *repo) {
+	var body struct {
+		Message string   `json:"message"`
+		Tree    string   `json:"tree"`
+		Parents []string `json:"parents"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, `{"message": "Problems parsing JSON"}`, http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.trees[body.Tree]; !ok || len(body.Parents) > 1 {
+		http.Error(w, `{"message": "Invalid commit"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	parent := ""
+	if len(body.Parents) == 1 {
+		if r.commits[body.Parents[0]] == nil {
+			http.Error(w, `{"message": "Invalid parent"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		parent = body.Parents[0]
+	}
+	c := s.addCommit(r, parent, body.Message, s.treeFiles(body.Tree))
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"sha":     c.sha,
+		"message": c.message,
+		"tree":    map[string]string{"sha": c.tree},
+		"parents": parentsJSON(c),
+	})
+}
+
+func (s *Server) updateFile(w http.ResponseWriter, req *http.Request, r *repo, path string) {
+	var body struct {
+		Message string `json:"message"`
+		Content string `json:"content"` // base64
+		SHA     string `json:"sha"`     // of the blob being replaced
+		Branch  string `json:"branch"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, `{"message": "Problems parsing JSON"}`, http.StatusBadRequest)
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(body.Content)
+	if err != nil {
+		http.Error(w, `{"message": "content is not valid Base64"}`, http.StatusBadRequest)
+		return
+	}
+	branch := body.Branch
+	if branch == "" {
+		branch = r.defaultBranch
+	}
+	head := r.commits[r.refs["heads/"+branch]]
+	if head == nil {
+		http.Error(w, `{"message": "Branch not found"}`, http.StatusNotFound)
+		return
+	}
+	if old, ok := head.files[path]; ok && old != body.SHA {
+		http.Error(w, `{"message": "`+path+` does not match `+body.SHA+`"}`, http.StatusConflict)
+		return
+	}
+
+	files := make(map[string]string)
+	for p, sha := range head.files {
+		files[p] = sha
+	}
+	blob := s.addBlob(data)
+	files[path] = blob
+	c := s.addCommit(r, head.sha, body.Message, files)
+	r.refs["heads/"+branch] = c.sha
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"content": map[string]interface{}{"path": path, "sha": blob, "size": len(data)},
+		"commit":  map[string]interface{}{"sha": c.sha, "message": c.message},
+	})
+}
+
+func (s *Server) createStatus(w http.ResponseWriter, req *http.Request, r *repo, sha string) {
+	var st Status
+	if err := json.NewDecoder(req.Body).Decode(&st); err != nil {
+		http.Error(w, `{"message": "Problems parsing JSON"}`, http.StatusBadRequest)
+		return
+	}
+	switch st.State {
+	case "pending", "success", "failure", "error":
+	default:
+		http.Error(w, `{"message": "Validation Failed"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	if r.commits[sha] == nil {
+		http.Error(w, `{"message": "No commit found for SHA: `+sha+`"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	if st.Context == "" {
+		st.Context = "default"
+	}
+	if r.statuses == nil {
+		r.statuses = make(map[string][]Status)
+	}
+	r.statuses[sha] = append(r.statuses[sha], st)
+	writeJSON(w, http.StatusCreated, st)
+}
+
+// Fork forks a repository into the account of User, as the API does,
+// unless it is already forked.
+func (s *Server) Fork(owner, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return fmt.Errorf("no repository %s/%s", owner, name)
+	}
+	s.fork(r)
+	return nil
+}
+
+// Exists reports whether a repository exists, such as after deleting it.
+func (s *Server) Exists(owner, name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repos[owner+"/"+name] != nil
+}
+
+func (s *Server) deleteRepo(w http.ResponseWriter, r *repo) {
+	delete(s.repos, r.owner+"/"+r.name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveBranches serves the list of a repository's branches.
+func (s *Server) serveBranches(w http.ResponseWriter, r *repo) {
+	var names []string
+	for name := range r.refs {
+		if strings.HasPrefix(name, "heads/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	v := []interface{}{}
+	for _, name := range names {
+		v = append(v, map[string]interface{}{
+			"name":   strings.TrimPrefix(name, "heads/"),
+			"commit": map[string]string{"sha": r.refs[name]},
+		})
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// createFork forks r into the account of s.User, or returns the existing fork.
+func (s *Server) createFork(w http.ResponseWriter, r *repo) {
+	fork := s.fork(r)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"name":           fork.name,
+		"full_name":      fork.owner + "/" + fork.name,
+		"owner":          map[string]string{"login": fork.owner},
+		"default_branch": fork.defaultBranch,
+		"fork":           true,
+	})
+}
+
+// fork forks r into the account of s.User, or returns the existing fork.
+func (s *Server) fork(r *repo) *repo {
+	key := s.User + "/" + r.name
+	fork := s.repos[key]
+	if fork == nil {
+		fork = &repo{
+			owner:         s.User,
+			name:          r.name,
+			defaultBranch: r.defaultBranch,
+			refs:          make(map[string]string),
+			commits:       make(map[string]*commit),
+			parent:        r,
+			ready:         time.Now().Add(s.ForkDelay),
+		}
+		for name, sha := range r.refs {
+			fork.refs[name] = sha
+		}
+		for sha, c := range r.commits {
+			fork.commits[sha] = c
+		}
+		s.repos[key] = fork
+	}
+	return fork
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("internal JSON error: %v", err), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.github.v3+json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
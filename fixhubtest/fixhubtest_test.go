@@ -0,0 +1,86 @@
+package fixhubtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func do(t *testing.T, method, url string, body interface{}, v interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encoding request: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decoding response to %s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestWrites(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	master := s.AddRepo("owner", "repo", map[string][]byte{
+		"a.go": []byte("package a\n"),
+	})
+
+	// Fork, branch from master, and update a file on the branch.
+	if code := do(t, "POST", s.BaseURL+"repos/owner/repo/forks", nil, nil); code != http.StatusAccepted {
+		t.Fatalf("CreateFork: status %d", code)
+	}
+	fork := s.BaseURL + "repos/fixhub/repo/"
+	ref := map[string]string{"ref": "refs/heads/fix", "sha": master}
+	if code := do(t, "POST", fork+"git/refs", ref, nil); code != http.StatusCreated {
+		t.Fatalf("CreateRef: status %d", code)
+	}
+	if code := do(t, "POST", fork+"git/refs", ref, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("CreateRef of existing ref: status %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	var tree struct {
+		Tree []struct{ Path, SHA string }
+	}
+	do(t, "GET", fmt.Sprintf("%sgit/trees/%s?recursive=1", fork, master), nil, &tree)
+	if len(tree.Tree) != 1 {
+		t.Fatalf("tree has %d entries, want 1", len(tree.Tree))
+	}
+	update := map[string]string{
+		"message": "Fix a.go",
+		"content": base64.StdEncoding.EncodeToString([]byte("package a // fixed\n")),
+		"sha":     tree.Tree[0].SHA,
+		"branch":  "fix",
+	}
+	if code := do(t, "PUT", fork+"contents/a.go", update, nil); code != http.StatusOK {
+		t.Fatalf("UpdateFile: status %d", code)
+	}
+	if code := do(t, "PUT", fork+"contents/a.go", update, nil); code != http.StatusConflict {
+		t.Errorf("UpdateFile with stale SHA: status %d, want %d", code, http.StatusConflict)
+	}
+
+	if got, _ := s.File("fixhub", "repo", "fix", "a.go"); string(got) != "package a // fixed\n" {
+		t.Errorf("a.go on fork's fix branch = %q", got)
+	}
+	if got, _ := s.File("owner", "repo", "master", "a.go"); string(got) != "package a\n" {
+		t.Errorf("a.go on upstream master = %q, want it unchanged", got)
+	}
+	if got, want := s.CommitMessages("fixhub", "repo", "fix"), []string{"Fix a.go", "Initial commit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commits on fix branch = %q, want %q", got, want)
+	}
+}
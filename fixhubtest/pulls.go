@@ -0,0 +1,298 @@
+package fixhubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A pull is a pull request into a repository's default branch
+// from another of its branches, or a branch of a fork of it.
+type pull struct {
+	number   int
+	title    string
+	from     *repo // the repository the branch is in
+	branch   string
+	base     string // SHA-1 of the commit the branch was compared against when opened
+	closed   bool
+	merged   bool
+	comments []*reviewComment
+}
+
+// head returns the commit at the head of the pull request's branch.
+func (p *pull) head() *commit {
+	return p.from.commits[p.from.refs["heads/"+p.branch]]
+}
+
+type reviewComment struct {
+	ID               int       `json:"id"`
+	Body             string    `json:"body"`
+	Path             string    `json:"path"`
+	Position         int       `json:"position"`
+	CommitID         string    `json:"commit_id"`
+	OriginalCommitID string    `json:"original_commit_id"`
+	User             userJSON  `json:"user"`
+	Created          time.Time `json:"created_at"`
+	Updated          time.Time `json:"updated_at"`
+}
+
+type userJSON struct {
+	Login string `json:"login"`
+}
+
+// Push commits files, keyed by slash-separated path, to a branch,
+// creating the branch from the head of the default branch if it does not exist.
+// A nil file is deleted. It returns the SHA-1 of the new commit.
+func (s *Server) Push(owner, name, branch, message string, files map[string][]byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return "", fmt.Errorf("no repository %s/%s", owner, name)
+	}
+	head, ok := r.refs["heads/"+branch]
+	if !ok {
+		head = r.refs["heads/"+r.defaultBranch]
+	}
+	blobs := make(map[string]string)
+	for path, sha := range r.commits[head].files {
+		blobs[path] = sha
+	}
+	for path, data := range files {
+		if data == nil {
+			delete(blobs, path)
+		} else {
+			blobs[path] = s.addBlob(data)
+		}
+	}
+	c := s.addCommit(r, head, message, blobs)
+	r.refs["heads/"+branch] = c.sha
+	return c.sha, nil
+}
+
+// AddPullRequest opens a pull request from a branch into the default branch,
+// and returns its number. As with GitHub, a branch of a fork of the repository
+// is named by the fork's owner and the branch (e.g. "fixhub:fix").
+func (s *Server) AddPullRequest(owner, name, branch, title string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil {
+		return 0, fmt.Errorf("no repository %s/%s", owner, name)
+	}
+	from := r
+	if user, b, ok := strings.Cut(branch, ":"); ok {
+		if from = s.repos[user+"/"+name]; from == nil || from.parent != r {
+			return 0, fmt.Errorf("no fork of %s/%s owned by %s", owner, name, user)
+		}
+		branch = b
+	}
+	if _, ok := from.refs["heads/"+branch]; !ok {
+		return 0, fmt.Errorf("no branch %s in %s/%s", branch, from.owner, name)
+	}
+	p := &pull{
+		number: len(r.pulls) + 1,
+		title:  title,
+		from:   from,
+		branch: branch,
+		base:   r.refs["heads/"+r.defaultBranch],
+	}
+	r.pulls = append(r.pulls, p)
+	return p.number, nil
+}
+
+// ClosePullRequest closes a pull request, merging it if merged is set.
+// Merging doesn't change the default branch.
+func (s *Server) ClosePullRequest(owner, name string, number int, merged bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil || number < 1 || number > len(r.pulls) {
+		return fmt.Errorf("no pull request %s/%s#%d", owner, name, number)
+	}
+	p := r.pulls[number-1]
+	p.closed, p.merged = true, merged
+	return nil
+}
+
+// ReviewComments returns the bodies of the review comments on a pull request,
+// oldest first.
+func (s *Server) ReviewComments(owner, name string, number int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+name]
+	if r == nil || number < 1 || number > len(r.pulls) {
+		return nil
+	}
+	var bodies []string
+	for _, c := range r.pulls[number-1].comments {
+		bodies = append(bodies, c.Body)
+	}
+	return bodies
+}
+
+// servePulls serves the pull request API below pulls/.
+func (s *Server) servePulls(w http.ResponseWriter, req *http.Request, r *repo, rest string) {
+	if rest == "" {
+		if req.Method != "GET" {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		state, head := req.FormValue("state"), req.FormValue("head")
+		if state == "" {
+			state = "open"
+		}
+		var v []interface{}
+		for _, p := range r.pulls {
+			pState := "open"
+			if p.closed {
+				pState = "closed"
+			}
+			label := p.from.owner + ":" + p.branch
+			if (state != "all" && state != pState) || (head != "" && head != label) {
+				continue
+			}
+			v = append(v, map[string]interface{}{
+				"number": p.number,
+				"state":  pState,
+				"merged": p.merged,
+				"title":  p.title,
+				"head":   map[string]string{"label": label, "ref": p.branch, "sha": p.from.refs["heads/"+p.branch]},
+				"base":   map[string]string{"ref": r.defaultBranch, "sha": p.base},
+			})
+		}
+		writeJSON(w, http.StatusOK, v)
+		return
+	}
+	if id := strings.TrimPrefix(rest, "comments/"); id != rest && req.Method == "PATCH" {
+		s.editComment(w, req, r, id)
+		return
+	}
+
+	num, what, _ := strings.Cut(rest, "/")
+	n, err := strconv.Atoi(num)
+	if err != nil || n < 1 || n > len(r.pulls) {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	p := r.pulls[n-1]
+	switch {
+	case req.Method == "GET" && what == "files":
+		s.servePullFiles(w, r, p)
+	case req.Method == "GET" && what == "comments":
+		writeJSON(w, http.StatusOK, p.comments)
+	case req.Method == "POST" && what == "comments":
+		s.createComment(w, req, p)
+	default:
+		w.WriteHeader(http.StatusTeapot)
+	}
+}
+
+// servePullFiles serves the files that a pull request changes.
+// Each patch replaces all the lines of the old file with all those of the new,
+// rather than being a minimal diff.
+func (s *Server) servePullFiles(w http.ResponseWriter, r *repo, p *pull) {
+	base, head := r.commits[p.base].files, p.head().files
+	paths := make(map[string]bool)
+	for path := range base {
+		paths[path] = true
+	}
+	for path := range head {
+		paths[path] = true
+	}
+	var sorted []string
+	for path := range paths {
+		if base[path] != head[path] {
+			sorted = append(sorted, path)
+		}
+	}
+	sort.Strings(sorted)
+
+	var v []interface{}
+	for _, path := range sorted {
+		status := "modified"
+		switch {
+		case base[path] == "":
+			status = "added"
+		case head[path] == "":
+			status = "removed"
+		}
+		before, after := splitLines(s.blobs[base[path]]), splitLines(s.blobs[head[path]])
+		patch := fmt.Sprintf("@@ -%s +%s @@", hunkRange(len(before)), hunkRange(len(after)))
+		for _, l := range before {
+			patch += "\n-" + l
+		}
+		for _, l := range after {
+			patch += "\n+" + l
+		}
+		v = append(v, map[string]interface{}{
+			"filename":  path,
+			"sha":       head[path],
+			"status":    status,
+			"additions": len(after),
+			"deletions": len(before),
+			"changes":   len(before) + len(after),
+			"patch":     patch,
+		})
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func hunkRange(n int) string {
+	if n == 0 {
+		return "0,0"
+	}
+	return "1," + strconv.Itoa(n)
+}
+
+func (s *Server) createComment(w http.ResponseWriter, req *http.Request, p *pull) {
+	var c reviewComment
+	if err := json.NewDecoder(req.Body).Decode(&c); err != nil {
+		http.Error(w, `{"message": "Problems parsing JSON"}`, http.StatusBadRequest)
+		return
+	}
+	if c.Body == "" || c.Path == "" || c.Position < 1 || c.CommitID == "" {
+		http.Error(w, `{"message": "Validation Failed"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	s.comments++
+	c.ID = s.comments
+	c.OriginalCommitID = c.CommitID
+	c.User = userJSON{Login: s.User}
+	c.Created = time.Now().UTC()
+	c.Updated = c.Created
+	p.comments = append(p.comments, &c)
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (s *Server) editComment(w http.ResponseWriter, req *http.Request, r *repo, id string) {
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Body == "" {
+		http.Error(w, `{"message": "Problems parsing JSON"}`, http.StatusBadRequest)
+		return
+	}
+	for _, p := range r.pulls {
+		for _, c := range p.comments {
+			if strconv.Itoa(c.ID) == id {
+				c.Body = body.Body
+				c.Updated = time.Now().UTC()
+				writeJSON(w, http.StatusOK, c)
+				return
+			}
+		}
+	}
+	http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+}
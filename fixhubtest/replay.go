@@ -0,0 +1,143 @@
+package fixhubtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// An Interaction is a single recorded HTTP request and its response.
+type Interaction struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"` // path and query only
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+func requestURL(req *http.Request) string {
+	return req.URL.RequestURI()
+}
+
+// Recorder is an http.RoundTripper that records the interactions
+// passing through it, so that they may be saved and later replayed.
+type Recorder struct {
+	// Transport is the underlying transport.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mu   sync.Mutex
+	ints []Interaction
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	resp, err := t.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %v", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	r.ints = append(r.ints, Interaction{
+		Method:      req.Method,
+		URL:         requestURL(req),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(body),
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Save writes the recorded interactions to a file.
+// They are sorted by URL so that re-recording gives a readable diff.
+func (r *Recorder) Save(filename string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sort.SliceStable(r.ints, func(i, j int) bool {
+		return r.ints[i].URL < r.ints[j].URL
+	})
+	b, err := json.MarshalIndent(r.ints, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(b, '\n'), 0644)
+}
+
+// Replayer is an http.RoundTripper that serves responses
+// from interactions saved by a Recorder, without using the network.
+// Requests are matched on method, path and query; the host is ignored.
+// A request that was made more than once when recording is answered
+// with each recorded response in turn, then with the last one.
+type Replayer struct {
+	mu   sync.Mutex
+	ints map[string][]Interaction // "method URL" -> interactions
+}
+
+// NewReplayer returns a Replayer for the interactions saved in a file.
+func NewReplayer(filename string) (*Replayer, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var ints []Interaction
+	if err := json.Unmarshal(b, &ints); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", filename, err)
+	}
+	r := &Replayer{ints: make(map[string][]Interaction)}
+	for _, in := range ints {
+		key := in.Method + " " + in.URL
+		r.ints[key] = append(r.ints[key], in)
+	}
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := req.Method + " " + requestURL(req)
+
+	r.mu.Lock()
+	ints := r.ints[key]
+	if len(ints) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("fixhubtest: no recorded response for %s", key)
+	}
+	in := ints[0]
+	if len(ints) > 1 {
+		r.ints[key] = ints[1:]
+	}
+	r.mu.Unlock()
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(in.Body))),
+		ContentLength: int64(len(in.Body)),
+		Request:       req,
+	}
+	if in.ContentType != "" {
+		resp.Header.Set("Content-Type", in.ContentType)
+	}
+	return resp, nil
+}
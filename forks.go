@@ -0,0 +1,198 @@
+package fixhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// ErrForkNotReady is returned by Fork when GitHub has not finished making the fork
+// in time. The fork may become ready later, so the caller may try again.
+var ErrForkNotReady = errors.New("fork is not ready yet")
+
+// forkPollInterval is how long Fork first waits between checks of whether
+// a fork is ready. The wait doubles after each check, up to five seconds.
+var forkPollInterval = 500 * time.Millisecond
+
+// Fork forks the repository into the account of the authenticated user,
+// unless it has already been forked, and waits until the fork is ready for use,
+// which is when its default branch exists. It returns the owner of the fork.
+// If the fork is not ready before ctx is done or Client.ForkTimeout passes,
+// it returns ErrForkNotReady.
+func (c *Client) Fork(ctx context.Context) (owner string, err error) {
+	start := time.Now()
+	fork, resp, err := c.gc.Repositories.CreateFork(c.owner, c.repo, nil)
+	c.fetched("repos", start, resp, err)
+	if err != nil {
+		return "", fmt.Errorf("forking %s/%s: %v", c.owner, c.repo, err)
+	}
+	if fork.Owner == nil || fork.Owner.Login == nil || fork.Name == nil {
+		return "", fmt.Errorf("forking %s/%s: fork has no owner or name", c.owner, c.repo)
+	}
+	owner, name := *fork.Owner.Login, *fork.Name
+	c.wrote(Write{Action: "fork", Repo: owner + "/" + name})
+	var branch string
+	if fork.DefaultBranch != nil {
+		branch = *fork.DefaultBranch
+	}
+	if branch == "" {
+		// A fork starts with the default branch of its parent.
+		if branch, err = c.DefaultBranch(); err != nil {
+			return "", err
+		}
+	}
+
+	timeout := c.ForkTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for wait := forkPollInterval; ; wait *= 2 {
+		start := time.Now()
+		_, resp, err := c.gc.Git.GetRef(owner, name, "heads/"+branch)
+		c.fetched("repos", start, resp, err)
+		if err == nil {
+			return owner, nil
+		}
+		if resp == nil || (resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusConflict) {
+			return "", fmt.Errorf("checking fork %s/%s: %v", owner, name, err)
+		}
+		if wait > 5*time.Second {
+			wait = 5 * time.Second
+		}
+		c.debug("waiting for fork", "fork", owner+"/"+name, "branch", branch, "wait", wait)
+		select {
+		case <-ctx.Done():
+			return "", ErrForkNotReady
+		case <-time.After(wait):
+		}
+	}
+}
+
+// DeleteForkIfClean deletes the authenticated user's fork of the repository,
+// such as one made to propose fixes, if nothing would be lost by doing so.
+// That is the case if the fork has no open pull requests into the repository,
+// and each of its branches either is at the same commit as the repository's
+// branch of the same name, or is the head of a pull request into the repository
+// that has been merged or closed.
+//
+// It reports whether it deleted the fork, and if not, why not.
+// That there is no fork is not an error.
+func (c *Client) DeleteForkIfClean() (deleted bool, why string, err error) {
+	start := time.Now()
+	user, resp, err := c.gc.Users.Get("")
+	c.fetched("user", start, resp, err)
+	if err != nil {
+		return false, "", fmt.Errorf("finding the authenticated user: %v", err)
+	}
+	if user.Login == nil {
+		return false, "", fmt.Errorf("the authenticated user has no login")
+	}
+	login := *user.Login
+
+	start = time.Now()
+	fork, resp, err := c.gc.Repositories.Get(login, c.repo)
+	c.fetched("repos", start, resp, err)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, fmt.Sprintf("%s has no fork of %s/%s", login, c.owner, c.repo), nil
+		}
+		return false, "", fmt.Errorf("fetching %s/%s: %v", login, c.repo, err)
+	}
+	if fork.Fork == nil || !*fork.Fork || fork.Parent == nil || fork.Parent.FullName == nil || *fork.Parent.FullName != c.owner+"/"+c.repo {
+		return false, fmt.Sprintf("%s/%s is not a fork of %s/%s", login, c.repo, c.owner, c.repo), nil
+	}
+
+	upstream, err := c.branches(c.owner)
+	if err != nil {
+		return false, "", err
+	}
+	branches, err := c.branches(login)
+	if err != nil {
+		return false, "", err
+	}
+	for name, sha := range branches {
+		if upstream[name] == sha {
+			continue
+		}
+		prs, err := c.pullRequestsFrom(login + ":" + name)
+		if err != nil {
+			return false, "", err
+		}
+		done := false
+		for _, pr := range prs {
+			if pr.State != nil && *pr.State == "open" {
+				return false, fmt.Sprintf("pull request #%d from branch %s is open", *pr.Number, name), nil
+			}
+			if pr.Head != nil && pr.Head.SHA != nil && *pr.Head.SHA == sha {
+				done = true
+			}
+		}
+		if !done {
+			return false, fmt.Sprintf("branch %s has commits that are in no closed pull request", name), nil
+		}
+	}
+
+	start = time.Now()
+	resp, err = c.gc.Repositories.Delete(login, c.repo)
+	c.fetched("repos", start, resp, err)
+	if err != nil {
+		return false, "", fmt.Errorf("deleting %s/%s: %v", login, c.repo, err)
+	}
+	c.debug("deleted fork", "fork", login+"/"+c.repo)
+	c.wrote(Write{Action: "delete", Repo: login + "/" + c.repo})
+	return true, "", nil
+}
+
+// branches returns the heads of the branches of owner's copy of the repository,
+// keyed by branch name.
+func (c *Client) branches(owner string) (map[string]string, error) {
+	branches := make(map[string]string)
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		start := time.Now()
+		page, resp, err := c.gc.Repositories.ListBranches(owner, c.repo, opt)
+		c.fetched("repos", start, resp, err)
+		if err != nil {
+			return nil, fmt.Errorf("listing branches of %s/%s: %v", owner, c.repo, err)
+		}
+		for _, b := range page {
+			if b.Name != nil && b.Commit != nil && b.Commit.SHA != nil {
+				branches[*b.Name] = *b.Commit.SHA
+			}
+		}
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// pullRequestsFrom returns the pull requests into the repository,
+// open or not, from head, which is a branch named as "owner:branch".
+func (c *Client) pullRequestsFrom(head string) ([]*github.PullRequest, error) {
+	var prs []*github.PullRequest
+	opt := &github.PullRequestListOptions{State: "all", Head: head, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		start := time.Now()
+		page, resp, err := c.gc.PullRequests.List(c.owner, c.repo, opt)
+		c.fetched("pulls", start, resp, err)
+		if err != nil {
+			return nil, fmt.Errorf("listing pull requests from %s: %v", head, err)
+		}
+		for _, pr := range page {
+			if pr.Number != nil {
+				prs = append(prs, pr)
+			}
+		}
+		if resp.NextPage == 0 {
+			return prs, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,93 @@
+package fixhub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestDeleteForkIfClean(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "proj", map[string][]byte{"a.go": []byte("package a\n")})
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	deleteFork := func(want bool) {
+		t.Helper()
+		deleted, why, err := c.DeleteForkIfClean()
+		if err != nil {
+			t.Fatalf("DeleteForkIfClean: %v", err)
+		}
+		if deleted != want {
+			t.Errorf("DeleteForkIfClean deleted = %v (%s), want %v", deleted, why, want)
+		}
+	}
+
+	// There is no fork yet.
+	deleteFork(false)
+
+	if err := srv.Fork("faker", "proj"); err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+	if _, err := srv.Push("fixhub", "proj", "fix", "Fix a problem found by fixhub", map[string][]byte{"a.go": []byte("package a // fixed\n")}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	// The fix branch isn't in a pull request.
+	deleteFork(false)
+
+	n, err := srv.AddPullRequest("faker", "proj", "fixhub:fix", "Fix a problem")
+	if err != nil {
+		t.Fatalf("AddPullRequest: %v", err)
+	}
+	// Its pull request is open.
+	deleteFork(false)
+
+	if err := srv.ClosePullRequest("faker", "proj", n, true); err != nil {
+		t.Fatalf("ClosePullRequest: %v", err)
+	}
+	deleteFork(true)
+	if srv.Exists("fixhub", "proj") {
+		t.Errorf("fork still exists")
+	}
+	if !srv.Exists("faker", "proj") {
+		t.Errorf("upstream repository was deleted")
+	}
+}
+
+func TestFork(t *testing.T) {
+	defer func(d time.Duration) { forkPollInterval = d }(forkPollInterval)
+	forkPollInterval = time.Millisecond
+
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "proj", map[string][]byte{"a.go": []byte("package a\n")})
+	srv.AddRepo("faker", "slow", map[string][]byte{"a.go": []byte("package a\n")})
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	srv.ForkDelay = 20 * time.Millisecond
+	owner, err := c.Fork(context.Background())
+	if err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+	if owner != "fixhub" || !srv.Exists("fixhub", "proj") {
+		t.Errorf("Fork = %q, want a fork owned by fixhub", owner)
+	}
+
+	// This fork takes too long.
+	srv.ForkDelay = time.Hour
+	c = NewClientWithHTTPClient("faker", "slow", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	c.ForkTimeout = 20 * time.Millisecond
+	if _, err := c.Fork(context.Background()); err != ErrForkNotReady {
+		t.Errorf("Fork of a slow fork = %v, want ErrForkNotReady", err)
+	}
+}
@@ -0,0 +1,60 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// GitHubActions writes problems as GitHub Actions workflow commands,
+// so that they appear as annotations of the lines they are on.
+type GitHubActions struct{}
+
+func (GitHubActions) Encode(w io.Writer, ps fixhub.Problems) error {
+	for _, p := range ps {
+		cmd := "warning"
+		switch p.Severity {
+		case fixhub.Error:
+			cmd = "error"
+		case fixhub.Info:
+			cmd = "notice"
+		}
+		props := []string{"file=" + escapeProperty(p.File)}
+		if p.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", p.Line))
+			if p.EndLine > 0 {
+				props = append(props, fmt.Sprintf("endLine=%d", p.EndLine))
+			}
+			// Columns are only allowed within a single line.
+			if p.Col > 0 && (p.EndLine == 0 || p.EndLine == p.Line) {
+				props = append(props, fmt.Sprintf("col=%d", p.Col))
+				if p.EndCol > 0 {
+					props = append(props, fmt.Sprintf("endColumn=%d", p.EndCol))
+				}
+			}
+		}
+		props = append(props, "title="+escapeProperty("fixhub "+string(p.Type)))
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", cmd, strings.Join(props, ","), escapeData(p.Text)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Warning writes a workflow command that shows msg as a warning about the whole run.
+func (GitHubActions) Warning(w io.Writer, msg string) error {
+	_, err := fmt.Fprintf(w, "::warning::%s\n", escapeData(msg))
+	return err
+}
+
+// escapeData escapes s for use as the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes s for use as a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,140 @@
+/*
+Package format encodes the problems found by package fixhub,
+for people to read and for other tools to consume.
+
+Formats are registered by name, so that a command's flags and a server's
+content negotiation can offer the same set:
+
+	f, ok := format.Lookup("json")
+	if !ok {
+		...
+	}
+	err := f.Encode(os.Stdout, problems)
+*/
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"sync"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// An Encoder writes problems in some format.
+type Encoder interface {
+	Encode(w io.Writer, ps fixhub.Problems) error
+}
+
+// A Format is a registered encoding of problems.
+type Format struct {
+	Name        string // short name, as for the fixhub command's -format flag
+	ContentType string // MIME type, as for an HTTP Content-Type header
+	Encoder
+}
+
+var registry struct {
+	sync.RWMutex
+	list []Format // in order of registration
+}
+
+func init() {
+	Register(Format{"text", "text/plain; charset=utf-8", Text{}})
+	Register(Format{"json", "application/json", JSON{}})
+	Register(Format{"junit", "application/xml", JUnit{}})
+	Register(Format{"reviewdog", "application/json", Reviewdog{}})
+	Register(Format{"github-actions", "text/plain; charset=utf-8", GitHubActions{}})
+}
+
+// Register adds f to the formats that Lookup and ForContentType find.
+// It panics if a format with the same name is already registered.
+func Register(f Format) {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, g := range registry.list {
+		if g.Name == f.Name {
+			panic("format: Register called twice for " + f.Name)
+		}
+	}
+	registry.list = append(registry.list, f)
+}
+
+// Lookup returns the format registered as name.
+func Lookup(name string) (Format, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, f := range registry.list {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// ForContentType returns the first format registered with the media type
+// of contentType, ignoring its parameters.
+func ForContentType(contentType string) (Format, bool) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return Format{}, false
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, f := range registry.list {
+		if ft, _, err := mime.ParseMediaType(f.ContentType); err == nil && ft == mt {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// Names returns the names of the registered formats, in the order they were registered.
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	var names []string
+	for _, f := range registry.list {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// Text writes a problem per line, as file:line: text.
+type Text struct {
+	Codes   bool // follow each problem with its type and rule code (e.g. [lint/naming])
+	Explain bool // follow each problem with a line linking to documentation that explains it
+}
+
+func (t Text) Encode(w io.Writer, ps fixhub.Problems) error {
+	for _, p := range ps {
+		var err error
+		if t.Codes {
+			_, err = fmt.Fprintf(w, "%+v\n", p)
+		} else {
+			_, err = fmt.Fprintln(w, p)
+		}
+		if err != nil {
+			return err
+		}
+		if t.Explain && p.URL != "" {
+			if _, err := fmt.Fprintf(w, "\twhy? %s\n", p.URL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// JSON writes problems as a JSON array of fixhub.Problem objects.
+type JSON struct{}
+
+func (JSON) Encode(w io.Writer, ps fixhub.Problems) error {
+	if ps == nil {
+		ps = fixhub.Problems{} // an empty array, not null
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(ps)
+}
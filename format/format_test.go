@@ -0,0 +1,104 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/dsymonds/fixhub"
+)
+
+var problems = fixhub.Problems{
+	{File: "b.go", Line: 7, Col: 2, Type: fixhub.Vet, Text: "unreachable code", URL: "https://pkg.go.dev/cmd/vet"},
+	{File: "a.go", Line: 3, Type: fixhub.Lint, Code: "naming", Text: "don't use underscores", Severity: fixhub.Error},
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range Names() {
+		f, ok := Lookup(name)
+		if !ok || f.Name != name || f.Encoder == nil {
+			t.Errorf("Lookup(%q) = %+v, %v", name, f, ok)
+		}
+	}
+	if _, ok := Lookup("nonesuch"); ok {
+		t.Errorf("Lookup of an unregistered format succeeded")
+	}
+	if f, ok := ForContentType("application/json; charset=utf-8"); !ok || f.Name != "json" {
+		t.Errorf("ForContentType(application/json) = %+v, %v; want the json format", f, ok)
+	}
+	if _, ok := ForContentType("image/png"); ok {
+		t.Errorf("ForContentType(image/png) found a format")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering text again did not panic")
+		}
+	}()
+	Register(Format{"text", "text/plain", Text{}})
+}
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (Text{Explain: true}).Encode(&buf, problems); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want := "b.go:7:2: unreachable code\n\twhy? https://pkg.go.dev/cmd/vet\na.go:3: don't use underscores\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Text output is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (JSON{}).Encode(&buf, nil); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Errorf("JSON of no problems = %s, want []", got)
+	}
+	buf.Reset()
+	if err := (JSON{}).Encode(&buf, problems); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var got fixhub.Problems
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("decoding JSON output: %v", err)
+	}
+	if len(got) != 2 || got[1] != problems[1] {
+		t.Errorf("JSON round trip gave %v, want %v", got, problems)
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (JUnit{}).Encode(&buf, problems); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var ts junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &ts); err != nil {
+		t.Fatalf("decoding XML output: %v", err)
+	}
+	// Suites are per file, in order, even though the problems weren't.
+	if len(ts.Suites) != 2 || ts.Suites[0].Name != "a.go" || ts.Suites[1].Failures != 1 {
+		t.Errorf("JUnit suites = %+v, want one failure each for a.go and b.go", ts.Suites)
+	}
+	if problems[0].File != "b.go" {
+		t.Errorf("Encode reordered its argument")
+	}
+}
+
+func TestGitHubActions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (GitHubActions{}).Encode(&buf, problems[1:]); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want := "::error file=a.go,line=3,title=fixhub lint::don't use underscores\n"
+	if got := buf.String(); got != want {
+		t.Errorf("GitHubActions output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package format
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/dsymonds/fixhub"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string       `xml:"name,attr"`
+	ClassName string       `xml:"classname,attr"`
+	Failure   junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit writes problems as JUnit XML, for CI systems that show test results.
+// Each file gets a test suite, and each problem is a failed test case.
+type JUnit struct{}
+
+func (JUnit) Encode(w io.Writer, ps fixhub.Problems) error {
+	ps = append(fixhub.Problems(nil), ps...)
+	sort.Sort(ps)
+	var ts junitTestSuites
+	for _, p := range ps {
+		if n := len(ts.Suites); n == 0 || ts.Suites[n-1].Name != p.File {
+			ts.Suites = append(ts.Suites, junitTestSuite{Name: p.File})
+		}
+		s := &ts.Suites[len(ts.Suites)-1]
+		name := p.File
+		if p.Line > 0 {
+			name += ":" + strconv.Itoa(p.Line)
+		}
+		s.Cases = append(s.Cases, junitTestCase{
+			Name:      name,
+			ClassName: "fixhub",
+			Failure: junitFailure{
+				Message: p.Text,
+				Text:    p.String(),
+			},
+		})
+		s.Tests++
+		s.Failures++
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(ts); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,73 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/dsymonds/fixhub"
+)
+
+// These types describe reviewdog's Diagnostic JSON format (rdjson).
+// See https://github.com/reviewdog/reviewdog/tree/master/proto/rdf.
+
+type rdResult struct {
+	Source      rdSource       `json:"source"`
+	Diagnostics []rdDiagnostic `json:"diagnostics"`
+}
+
+type rdSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type rdDiagnostic struct {
+	Message  string     `json:"message"`
+	Location rdLocation `json:"location"`
+	Severity string     `json:"severity,omitempty"`
+}
+
+type rdLocation struct {
+	Path  string   `json:"path"`
+	Range *rdRange `json:"range,omitempty"`
+}
+
+type rdRange struct {
+	Start rdPosition  `json:"start"`
+	End   *rdPosition `json:"end,omitempty"`
+}
+
+type rdPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+// Reviewdog writes problems in reviewdog's rdjson format,
+// for posting them as review comments.
+type Reviewdog struct{}
+
+func (Reviewdog) Encode(w io.Writer, ps fixhub.Problems) error {
+	res := rdResult{
+		Source: rdSource{
+			Name: "fixhub",
+			URL:  "https://github.com/dsymonds/fixhub",
+		},
+		Diagnostics: []rdDiagnostic{}, // reviewdog wants a list, not null
+	}
+	for _, p := range ps {
+		d := rdDiagnostic{
+			Message:  p.Text,
+			Location: rdLocation{Path: p.File},
+			Severity: "WARNING",
+		}
+		if p.Line > 0 {
+			d.Location.Range = &rdRange{Start: rdPosition{Line: p.Line, Column: p.Col}}
+			if p.EndLine > 0 {
+				d.Location.Range.End = &rdPosition{Line: p.EndLine, Column: p.EndCol}
+			}
+		}
+		res.Diagnostics = append(res.Diagnostics, d)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(res)
+}
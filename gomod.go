@@ -0,0 +1,200 @@
+package fixhub
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/version"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// modFileDir reports whether path names the given module file (go.mod or go.sum),
+// and if so returns the directory it is in, which is "" for the top level.
+func modFileDir(path, name string) (dir string, ok bool) {
+	if path == name {
+		return "", true
+	}
+	if strings.HasSuffix(path, "/"+name) {
+		return strings.TrimSuffix(path, "/"+name), true
+	}
+	return "", false
+}
+
+// checkModule checks the module rooted at dir.
+// modSHA1 and sumSHA1 identify its go.mod and go.sum blobs;
+// sumSHA1 is empty if the module has no go.sum.
+// imports maps Go file paths to the import paths they use,
+// and skipped lists the files that weren't checked, whose imports aren't known.
+func (c *Client) checkModule(dir, modSHA1, sumSHA1 string, imports map[string][]string, skipped []Skipped) (Problems, error) {
+	mod, err := c.GetBlob(modSHA1)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s/go.mod: %v", dir, err)
+	}
+	var sum []byte
+	if sumSHA1 != "" {
+		if sum, err = c.GetBlob(sumSHA1); err != nil {
+			return nil, fmt.Errorf("fetching %s/go.sum: %v", dir, err)
+		}
+	}
+	used := make(map[string]bool)
+	for file, ips := range imports {
+		if dir != "" && !strings.HasPrefix(file, dir+"/") {
+			continue
+		}
+		for _, ip := range ips {
+			used[ip] = true
+		}
+	}
+	for _, sk := range skipped {
+		if strings.HasSuffix(sk.File, ".go") && (dir == "" || strings.HasPrefix(sk.File, dir+"/")) {
+			used = nil // a requirement may be for a file that wasn't checked
+			break
+		}
+	}
+	filename := "go.mod"
+	if dir != "" {
+		filename = dir + "/go.mod"
+	}
+	return c.checkGoMod(filename, mod, sum, used), nil
+}
+
+// checkGoMod checks a go.mod file.
+// sum is the content of the adjacent go.sum file, or nil if there is none.
+// imports is the set of import paths used by the module's Go files,
+// or nil if that isn't known.
+func (c *Client) checkGoMod(filename string, data, sum []byte, imports map[string]bool) Problems {
+	f, err := modfile.Parse(filename, data, nil)
+	if err != nil {
+		return Problems{{
+			File: filename,
+			Type: GoMod,
+			Text: err.Error(),
+		}}
+	}
+
+	var ps Problems
+	add := func(line *modfile.Line, format string, a ...interface{}) {
+		p := Problem{
+			File: filename,
+			Type: GoMod,
+			Text: fmt.Sprintf(format, a...),
+		}
+		if line != nil {
+			p.Line, p.Col = line.Start.Line, line.Start.LineRune
+			p.EndLine, p.EndCol = line.End.Line, line.End.LineRune
+		}
+		ps = append(ps, p)
+	}
+
+	if min := c.MinGoVersion; min != "" && f.Go != nil {
+		if version.Compare("go"+f.Go.Version, "go"+min) < 0 {
+			add(f.Go.Syntax, "go directive %s is older than the minimum of %s", f.Go.Version, min)
+		}
+	}
+
+	sums := parseGoSum(sum)
+	for _, r := range f.Require {
+		if !sums[r.Mod.Path+" "+r.Mod.Version+"/go.mod"] {
+			add(r.Syntax, "go.sum is missing an entry for %s %s", r.Mod.Path, r.Mod.Version)
+		}
+		if !r.Indirect && imports != nil && !importsModule(imports, r.Mod.Path) {
+			add(r.Syntax, "%s is required but not imported", r.Mod.Path)
+		}
+		if c.ModuleProxy != "" {
+			rationale, retracted, err := c.retracted(r.Mod)
+			if err != nil {
+				continue // best effort
+			}
+			if retracted {
+				msg := fmt.Sprintf("%s %s has been retracted", r.Mod.Path, r.Mod.Version)
+				if rationale != "" {
+					msg += ": " + rationale
+				}
+				add(r.Syntax, "%s", msg)
+			}
+		}
+	}
+	return ps
+}
+
+// parseGoSum returns the set of "path version" keys in a go.sum file.
+func parseGoSum(sum []byte) map[string]bool {
+	m := make(map[string]bool)
+	scan := bufio.NewScanner(bytes.NewReader(sum))
+	for scan.Scan() {
+		// line looks like
+		//	golang.org/x/mod v0.4.2/go.mod h1:...
+		f := strings.Fields(scan.Text())
+		if len(f) != 3 {
+			continue
+		}
+		m[f[0]+" "+f[1]] = true
+	}
+	return m
+}
+
+// importsModule reports whether any of the import paths is within the module.
+func importsModule(imports map[string]bool, modPath string) bool {
+	for imp := range imports {
+		if imp == modPath || strings.HasPrefix(imp, modPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// retracted reports whether a module version has been retracted
+// according to the latest version of the module on the module proxy.
+func (c *Client) retracted(mod module.Version) (rationale string, retracted bool, err error) {
+	path, err := module.EscapePath(mod.Path)
+	if err != nil {
+		return "", false, err
+	}
+	base := strings.TrimSuffix(c.ModuleProxy, "/") + "/" + path + "/"
+
+	var latest struct{ Version string }
+	data, err := httpGet(base + "@latest")
+	if err != nil {
+		return "", false, err
+	}
+	if err := json.Unmarshal(data, &latest); err != nil {
+		return "", false, err
+	}
+	v, err := module.EscapeVersion(latest.Version)
+	if err != nil {
+		return "", false, err
+	}
+	data, err = httpGet(base + "@v/" + v + ".mod")
+	if err != nil {
+		return "", false, err
+	}
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return "", false, err
+	}
+	for _, r := range f.Retract {
+		if semver.Compare(r.Low, mod.Version) <= 0 && semver.Compare(mod.Version, r.High) <= 0 {
+			return r.Rationale, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func httpGet(url string) ([]byte, error) {
+	resp, err := NewHTTPClient(nil, "").Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
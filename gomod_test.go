@@ -0,0 +1,159 @@
+package fixhub
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestModFileDir(t *testing.T) {
+	tests := []struct {
+		path    string
+		dir     string
+		isMod   bool
+		isSumOK bool
+	}{
+		{"go.mod", "", true, false},
+		{"sub/dir/go.mod", "sub/dir", true, false},
+		{"go.sum", "", false, true},
+		{"notgo.mod", "", false, false},
+	}
+	for _, tt := range tests {
+		dir, ok := modFileDir(tt.path, "go.mod")
+		if ok != tt.isMod || (ok && dir != tt.dir) {
+			t.Errorf("modFileDir(%q, go.mod) = %q, %v, want %q, %v", tt.path, dir, ok, tt.dir, tt.isMod)
+		}
+		if _, ok := modFileDir(tt.path, "go.sum"); ok != tt.isSumOK {
+			t.Errorf("modFileDir(%q, go.sum) ok = %v, want %v", tt.path, ok, tt.isSumOK)
+		}
+	}
+}
+
+func TestParseGoSum(t *testing.T) {
+	sums := parseGoSum([]byte(`golang.org/x/mod v0.4.2 h1:Gz96sIWK3OalVv/I/qNygP42zyoKp3xptRVCWRFEBvo=
+golang.org/x/mod v0.4.2/go.mod h1:s0Qsj1ACt9ePp/hMypM3fl4fZqREWJwdYDEqhRiZZUA=
+`))
+	if !sums["golang.org/x/mod v0.4.2/go.mod"] {
+		t.Errorf("go.mod hash entry not found in %v", sums)
+	}
+	if sums["golang.org/x/mod v0.4.3/go.mod"] {
+		t.Errorf("unexpected entry found in %v", sums)
+	}
+}
+
+func TestCheckGoMod(t *testing.T) {
+	// A module proxy whose latest example.com/Bad retracts v1.1.0.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/!bad/@latest":
+			fmt.Fprint(w, `{"Version": "v1.2.0"}`)
+		case "/example.com/!bad/@v/v1.2.0.mod":
+			fmt.Fprint(w, "module example.com/Bad\n\nretract v1.1.0 // it was broken\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer proxy.Close()
+
+	const sum = `example.com/a v1.0.0/go.mod h1:x=
+example.com/Bad v1.1.0/go.mod h1:x=
+`
+	tests := []struct {
+		desc    string
+		mod     string
+		sum     string
+		imports map[string]bool
+		minGo   string
+		want    []string
+	}{
+		{
+			desc:    "tidy",
+			mod:     "module m\n\ngo 1.21\n\nrequire example.com/a v1.0.0\n",
+			sum:     sum,
+			imports: map[string]bool{"example.com/a/pkg": true},
+			minGo:   "1.21",
+		},
+		{
+			desc:  "old go line",
+			mod:   "module m\n\ngo 1.20\n",
+			minGo: "1.21",
+			want:  []string{"go directive 1.20 is older than the minimum of 1.21"},
+		},
+		{
+			desc:  "prerelease go line",
+			mod:   "module m\n\ngo 1.21rc1\n",
+			minGo: "1.21.0",
+			want:  []string{"go directive 1.21rc1 is older than the minimum of 1.21.0"},
+		},
+		{
+			desc:  "release after a prerelease",
+			mod:   "module m\n\ngo 1.21.0\n",
+			minGo: "1.21rc2",
+		},
+		{
+			desc:    "missing go.sum entry",
+			mod:     "module m\n\nrequire example.com/a v1.0.0\n",
+			imports: map[string]bool{"example.com/a": true},
+			want:    []string{"go.sum is missing an entry for example.com/a v1.0.0"},
+		},
+		{
+			desc:    "required but not imported",
+			mod:     "module m\n\nrequire (\n\texample.com/a v1.0.0\n\texample.com/b v1.0.0 // indirect\n)\n",
+			sum:     sum + "example.com/b v1.0.0/go.mod h1:x=\n",
+			imports: map[string]bool{},
+			want:    []string{"example.com/a is required but not imported"},
+		},
+		{
+			desc: "imports not known",
+			mod:  "module m\n\nrequire example.com/a v1.0.0\n",
+			sum:  sum,
+		},
+		{
+			desc:    "retracted",
+			mod:     "module m\n\nrequire example.com/Bad v1.1.0\n",
+			sum:     sum,
+			imports: map[string]bool{"example.com/Bad": true},
+			want:    []string{"example.com/Bad v1.1.0 has been retracted: it was broken"},
+		},
+	}
+	for _, tt := range tests {
+		c := &Client{MinGoVersion: tt.minGo, ModuleProxy: proxy.URL}
+		var got []string
+		for _, p := range c.checkGoMod("go.mod", []byte(tt.mod), []byte(tt.sum), tt.imports) {
+			got = append(got, p.Text)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestGoModSkippedFiles(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "gen", map[string][]byte{
+		"go.mod":    []byte("module example.com/gen\n\ngo 1.21\n\nrequire example.com/proto v1.0.0\n"),
+		"go.sum":    []byte("example.com/proto v1.0.0/go.mod h1:x=\n"),
+		"a.go":      []byte("package gen\n"),
+		"gen.pb.go": []byte("package gen\n\nimport _ \"example.com/proto\"\n"),
+	})
+	c := NewClientWithHTTPClient("faker", "gen", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	c.Enabled = []ProblemType{GoMod}
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, p := range res.Problems {
+		if strings.Contains(p.Text, "not imported") {
+			t.Errorf("reported %q, though the file that imports it was skipped", p.Text)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package fixhub
+
+import (
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Hooks holds optional callbacks through which a Client reports what it is doing,
+// so that embedders can record metrics. Any of them may be nil.
+// They may be called concurrently.
+type Hooks struct {
+	// OnFetch is called after each successful GitHub API call,
+	// with the kind of object fetched ("commit", "compare", "tree", "blob", "release", "pulls", "repos" or "user")
+	// and how long the call took.
+	OnFetch func(kind string, d time.Duration)
+
+	// OnAPIError is called when a GitHub API call fails.
+	OnAPIError func(kind string, err error)
+
+	// OnResponse is called with the response to each GitHub API call,
+	// whether or not it succeeded, for its rate limit and pagination details.
+	OnResponse func(kind string, resp *github.Response)
+
+	// OnCheckFile is called after the per-file checks have run on a file,
+	// with how long they took.
+	OnCheckFile func(file string, d time.Duration)
+
+	// OnProblem is called for each problem found, once all checks have finished.
+	OnProblem func(p Problem)
+
+	// OnWrite is called after each change the client makes to GitHub,
+	// such as a commit or a fork, for keeping an audit log.
+	OnWrite func(w Write)
+}
+
+// A Write is a change to GitHub made by a Client.
+type Write struct {
+	Action string   // "commit", "fork" or "delete" (of Branch if set, or else of Repo)
+	Repo   string   // "owner/repo" of the repository changed or made
+	Branch string   // the branch committed to or deleted
+	SHA    string   // the commit made, or the head of the branch deleted
+	Files  []string // the files the commit changed
+}
+
+func (c *Client) wrote(w Write) {
+	if c.Hooks.OnWrite != nil {
+		c.Hooks.OnWrite(w)
+	}
+}
+
+// fetched reports the outcome of a GitHub API call that began at start.
+func (c *Client) fetched(kind string, start time.Time, resp *github.Response, err error) {
+	if resp != nil && resp.Limit > 0 {
+		c.rate.Lock()
+		c.rate.limit = RateLimit{Limit: resp.Limit, Remaining: resp.Remaining, Reset: resp.Reset.Time}
+		c.rate.Unlock()
+	}
+	if resp != nil && c.Hooks.OnResponse != nil {
+		c.Hooks.OnResponse(kind, resp)
+	}
+	if err != nil {
+		if c.Hooks.OnAPIError != nil {
+			c.Hooks.OnAPIError(kind, err)
+		}
+		return
+	}
+	if c.Hooks.OnFetch != nil {
+		c.Hooks.OnFetch(kind, time.Since(start))
+	}
+}
+
+func (c *Client) checkedFile(file string, start time.Time) {
+	if c.Hooks.OnCheckFile != nil {
+		c.Hooks.OnCheckFile(file, time.Since(start))
+	}
+}
+
+// RateLimit is the state of a GitHub API rate limit.
+type RateLimit struct {
+	Limit     int // requests permitted per hour
+	Remaining int // requests remaining in the current hour
+	Reset     time.Time
+}
+
+// RateLimit returns the state of the client's rate limit as of its most recent
+// GitHub API call, and false if it has not made one yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.rate.Lock()
+	defer c.rate.Unlock()
+	return c.rate.limit, c.rate.limit.Limit > 0
+}
@@ -0,0 +1,21 @@
+package fixhub
+
+// A Logger records structured diagnostic messages.
+// The args alternate between keys and values, as with package log/slog;
+// a *slog.Logger satisfies this interface.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+func (c *Client) debug(msg string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Debug(msg, args...)
+	}
+}
+
+func (c *Client) warn(msg string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Warn(msg, args...)
+	}
+}
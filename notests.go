@@ -0,0 +1,60 @@
+package fixhub
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// checkNoTests reports packages that have functions but no _test.go files.
+// Packages of only types and constants are not worth testing by themselves.
+func checkNoTests(pkgs []*repoPackage) Problems {
+	type dirInfo struct {
+		name     string
+		files    []string // non-test files with functions
+		hasTests bool
+	}
+	dirs := make(map[string]*dirInfo)
+	for _, rp := range pkgs {
+		d := dirs[rp.dir]
+		if d == nil {
+			d = new(dirInfo)
+			dirs[rp.dir] = d
+		}
+		for filename, f := range rp.files {
+			if strings.HasSuffix(filename, "_test.go") {
+				d.hasTests = true
+				continue
+			}
+			d.name = rp.name
+			if hasFuncs(f) {
+				d.files = append(d.files, filename)
+			}
+		}
+	}
+
+	var ps Problems
+	for _, d := range dirs {
+		if d.hasTests || len(d.files) == 0 {
+			continue
+		}
+		sort.Strings(d.files)
+		ps = append(ps, Problem{
+			File:     d.files[0],
+			Type:     NoTests,
+			Text:     fmt.Sprintf("package %s has no tests", d.name),
+			Severity: Info,
+		})
+	}
+	return ps
+}
+
+func hasFuncs(f *ast.File) bool {
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok && fd.Body != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package fixhub
+
+import (
+	"go/token"
+	"testing"
+)
+
+func TestCheckNoTests(t *testing.T) {
+	packages := map[string]map[string][]byte{
+		"tested tested": {
+			"tested/a.go":      []byte("package tested\n\nfunc F() {}\n"),
+			"tested/a_test.go": []byte("package tested\n"),
+		},
+		"xtested xtested": {
+			"xtested/a.go": []byte("package xtested\n\nfunc F() {}\n"),
+		},
+		"xtested xtested_test": {
+			"xtested/a_test.go": []byte("package xtested_test\n"),
+		},
+		"types types": {
+			"types/a.go": []byte("package types\n\ntype T int\n"),
+		},
+		"untested untested": {
+			"untested/b.go": []byte("package untested\n\nfunc F() {}\n"),
+			"untested/a.go": []byte("package untested\n\nfunc G() {}\n"),
+		},
+	}
+	fset := token.NewFileSet()
+	ps := checkNoTests(parsePackages(fset, "example.com/repo", packages))
+	if len(ps) != 1 {
+		t.Fatalf("got %d problems, want 1: %v", len(ps), ps)
+	}
+	want := Problem{File: "untested/a.go", Type: NoTests, Text: "package untested has no tests", Severity: Info}
+	if ps[0] != want {
+		t.Errorf("got %+v, want %+v", ps[0], want)
+	}
+}
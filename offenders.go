@@ -0,0 +1,49 @@
+package fixhub
+
+import (
+	"path"
+	"sort"
+)
+
+// A Tally is how many problems something has.
+type Tally struct {
+	Name string // a file, a package directory, or a problem type
+	N    int
+}
+
+// TopOffenders returns up to n each of the files, the package directories
+// and the types of problem with the most problems in ps, most first,
+// so that those fixing them know where to start.
+// The top-level directory is ".".
+func TopOffenders(ps Problems, n int) (files, dirs, types []Tally) {
+	fileCount := make(map[string]int)
+	dirCount := make(map[string]int)
+	typeCount := make(map[string]int)
+	for _, p := range ps {
+		if p.File != "" {
+			fileCount[p.File]++
+			dirCount[path.Dir(p.File)]++
+		}
+		typeCount[string(p.Type)]++
+	}
+	return top(fileCount, n), top(dirCount, n), top(typeCount, n)
+}
+
+// top returns up to n of the entries of counts with the largest counts,
+// most first, breaking ties by name.
+func top(counts map[string]int, n int) []Tally {
+	var ts []Tally
+	for name, c := range counts {
+		ts = append(ts, Tally{name, c})
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].N != ts[j].N {
+			return ts[i].N > ts[j].N
+		}
+		return ts[i].Name < ts[j].Name
+	})
+	if len(ts) > n {
+		ts = ts[:n]
+	}
+	return ts
+}
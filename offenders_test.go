@@ -0,0 +1,28 @@
+package fixhub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopOffenders(t *testing.T) {
+	ps := Problems{
+		{File: "a.go", Type: Lint},
+		{File: "a.go", Type: Vet},
+		{File: "b/b.go", Type: Lint},
+		{File: "b/c.go", Type: Lint},
+		{File: "b/c.go", Type: Gofmt},
+		{File: "b/c.go", Type: Lint},
+		{Type: Internal},
+	}
+	files, dirs, types := TopOffenders(ps, 2)
+	if want := []Tally{{"b/c.go", 3}, {"a.go", 2}}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	if want := []Tally{{"b", 4}, {".", 2}}; !reflect.DeepEqual(dirs, want) {
+		t.Errorf("dirs = %v, want %v", dirs, want)
+	}
+	if want := []Tally{{"lint", 4}, {"gofmt", 1}}; !reflect.DeepEqual(types, want) {
+		t.Errorf("types = %v, want %v", types, want)
+	}
+}
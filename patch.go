@@ -0,0 +1,98 @@
+package fixhub
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// diffContext is the number of unchanged lines around each hunk of a diff.
+const diffContext = 3
+
+// UnifiedDiff returns a diff of two versions of the file at path, in the
+// unified format of git diff, which git apply and patch -p1 accept.
+// It returns nil if they are the same.
+func UnifiedDiff(path string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	al, bl := splitLines(old), splitLines(new)
+	type op struct {
+		kind byte // ' ', '-' or '+'
+		line []byte
+	}
+	var ops []op
+	i, j := 0, 0
+	for _, m := range append(matchLines(al, bl), [2]int{len(al), len(bl)}) {
+		for ; i < m[0]; i++ {
+			ops = append(ops, op{'-', al[i]})
+		}
+		for ; j < m[1]; j++ {
+			ops = append(ops, op{'+', bl[j]})
+		}
+		if i < len(al) {
+			ops = append(ops, op{' ', al[i]})
+			i, j = i+1, j+1
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+	oldLine, newLine := 1, 1 // at the start of ops[k]
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			oldLine, newLine = oldLine+1, newLine+1
+			k++
+			continue
+		}
+		// Start a hunk with context before the change at k,
+		// and extend it while changes are close enough to share context.
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for n := k; n < len(ops) && n < end+2*diffContext+1; n++ {
+			if ops[n].kind != ' ' {
+				end = n + 1
+			}
+		}
+		end += diffContext
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		oldStart, newStart := oldLine-(k-start), newLine-(k-start)
+		var oldCount, newCount int
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				oldCount++
+			}
+			if o.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, o := range ops[start:end] {
+			buf.WriteByte(o.kind)
+			buf.Write(o.line)
+			if !bytes.HasSuffix(o.line, []byte("\n")) {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		oldLine += oldCount - (k - start)
+		newLine += newCount - (k - start)
+		k = end
+	}
+	return buf.Bytes()
+}
+
+// hunkRange formats the start and length of one side of a hunk.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,33 @@
+package fixhub
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n"
+	new := "1\n2\n3\n4\n5\nsix\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n21"
+	want := `diff --git a/x.go b/x.go
+--- a/x.go
++++ b/x.go
+@@ -3,7 +3,7 @@
+ 3
+ 4
+ 5
+-6
++six
+ 7
+ 8
+ 9
+@@ -18,3 +18,4 @@
+ 18
+ 19
+ 20
++21
+\ No newline at end of file
+`
+	if got := string(UnifiedDiff("x.go", []byte(old), []byte(new))); got != want {
+		t.Errorf("UnifiedDiff gave\n%s\nwant\n%s", got, want)
+	}
+	if got := UnifiedDiff("x.go", []byte(old), []byte(old)); got != nil {
+		t.Errorf("UnifiedDiff of identical files = %q, want nil", got)
+	}
+}
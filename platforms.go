@@ -0,0 +1,81 @@
+package fixhub
+
+import (
+	"bytes"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultPlatforms are the GOOS/GOARCH combinations that Check considers
+// when deciding which files of a package belong together.
+var DefaultPlatforms = []string{"linux/amd64", "darwin/arm64", "windows/amd64"}
+
+// platformFileSets splits the files of a package into the distinct sets of
+// files that are built together on each of the platforms (GOOS/GOARCH pairs),
+// according to their build constraints and file names.
+// Files that don't match any of the platforms, such as those
+// constrained by "ignore", are put in sets of their own.
+func platformFileSets(files map[string][]byte, platforms []string) []map[string][]byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sets []map[string][]byte
+	seen := make(map[string]bool) // joined file names of sets so far
+	matched := make(map[string]bool)
+	for _, plat := range platforms {
+		i := strings.Index(plat, "/")
+		if i < 0 {
+			continue
+		}
+		ctx := memContext(files, plat[:i], plat[i+1:])
+		var set []string
+		for _, name := range names {
+			dir, file := path.Split(name)
+			if ok, err := ctx.MatchFile(dir, file); err == nil && ok {
+				set = append(set, name)
+				matched[name] = true
+			}
+		}
+		key := strings.Join(set, "\x00")
+		if len(set) == 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		m := make(map[string][]byte)
+		for _, name := range set {
+			m[name] = files[name]
+		}
+		sets = append(sets, m)
+	}
+	for _, name := range names {
+		if !matched[name] {
+			sets = append(sets, map[string][]byte{name: files[name]})
+		}
+	}
+	return sets
+}
+
+// memContext returns a build context for the given platform
+// that reads files from memory instead of the file system.
+func memContext(files map[string][]byte, goos, goarch string) *build.Context {
+	ctx := build.Default
+	ctx.GOOS, ctx.GOARCH = goos, goarch
+	ctx.CgoEnabled = true
+	ctx.JoinPath = path.Join
+	ctx.OpenFile = func(name string) (io.ReadCloser, error) {
+		src, ok := files[name]
+		if !ok {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		return ioutil.NopCloser(bytes.NewReader(src)), nil
+	}
+	return &ctx
+}
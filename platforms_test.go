@@ -0,0 +1,36 @@
+package fixhub
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPlatformFileSets(t *testing.T) {
+	files := map[string][]byte{
+		"p/p.go":         []byte("package p\n"),
+		"p/p_linux.go":   []byte("package p\n"),
+		"p/p_windows.go": []byte("package p\n"),
+		"p/unix.go":      []byte("//go:build unix\n\npackage p\n"),
+		"p/gen.go":       []byte("//go:build ignore\n\npackage main\n"),
+	}
+	sets := platformFileSets(files, []string{"linux/amd64", "darwin/arm64", "windows/amd64", "freebsd/amd64"})
+	var got [][]string
+	for _, set := range sets {
+		var names []string
+		for name := range set {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		got = append(got, names)
+	}
+	want := [][]string{
+		{"p/p.go", "p/p_linux.go", "p/unix.go"},
+		{"p/p.go", "p/unix.go"}, // darwin and freebsd are the same
+		{"p/p.go", "p/p_windows.go"},
+		{"p/gen.go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("platformFileSets = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package fixhub
+
+import (
+	"path"
+	"strings"
+)
+
+// DefaultProtectedPaths are the paths that fixes never change
+// if Client.ProtectedPaths is empty: vendored and test data,
+// and generated code, which would be overwritten when regenerated.
+var DefaultProtectedPaths = []string{"vendor/", "testdata/", "*_generated.go", "*.pb.go"}
+
+// ProtectedPath reports whether the slash-separated path p matches any of patterns,
+// which use path.Match syntax. A pattern ending in a slash matches everything
+// in any directory it matches (e.g. "vendor/" matches "vendor/a.go" and "x/vendor/b/c.go").
+// Other patterns match the whole path or, if they contain no slash,
+// the last element of it (e.g. "*_generated.go" matches "a/b_generated.go").
+func ProtectedPath(patterns []string, p string) bool {
+	elems := strings.Split(p, "/")
+	for _, pat := range patterns {
+		if dir := strings.TrimSuffix(pat, "/"); dir != pat {
+			for i := range elems[:len(elems)-1] {
+				if matchPath(dir, strings.Join(elems[:i+1], "/")) || (!strings.Contains(dir, "/") && matchPath(dir, elems[i])) {
+					return true
+				}
+			}
+			continue
+		}
+		if matchPath(pat, p) || (!strings.Contains(pat, "/") && matchPath(pat, elems[len(elems)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPath(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// protected reports whether fixes must not change the file at path p,
+// according to c.ProtectedPaths and the repository's configuration cfg.
+func (c *Client) protected(cfg *Config, p string) bool {
+	patterns := c.ProtectedPaths
+	if len(patterns) == 0 {
+		patterns = DefaultProtectedPaths
+	}
+	return ProtectedPath(patterns, p) || (cfg != nil && ProtectedPath(cfg.Fix.Protect, p))
+}
@@ -0,0 +1,46 @@
+package fixhub
+
+import "testing"
+
+func TestProtectedPath(t *testing.T) {
+	patterns := []string{"vendor/", "third_party/x/", "*_generated.go", "cmd/main.go"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"a.go", false},
+		{"vendor/a.go", true},
+		{"x/vendor/b/c.go", true},
+		{"vendored.go", false},
+		{"third_party/x/a.go", true},
+		{"third_party/y/a.go", false},
+		{"a/b_generated.go", true},
+		{"cmd/main.go", true},
+		{"x/cmd/main.go", false},
+	}
+	for _, tt := range tests {
+		if got := ProtectedPath(patterns, tt.path); got != tt.want {
+			t.Errorf("ProtectedPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestProtected(t *testing.T) {
+	c := &Client{}
+	cfg := new(Config)
+	cfg.Fix.Protect = []string{"gen/"}
+	for path, want := range map[string]bool{
+		"a.go":        false,
+		"vendor/a.go": true, // by default
+		"gen/a.go":    true, // by the repository's configuration
+	} {
+		if got := c.protected(cfg, path); got != want {
+			t.Errorf("protected(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	c.ProtectedPaths = []string{"*.go"}
+	if c.protected(nil, "vendor/a.txt") {
+		t.Errorf("protected(vendor/a.txt) with ProtectedPaths set, want false")
+	}
+}
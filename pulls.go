@@ -0,0 +1,339 @@
+package fixhub
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// A PullRequest is an open pull request on a repository.
+type PullRequest struct {
+	Number int
+	Title  string
+	Head   string // SHA-1 of the commit at its head
+}
+
+// PullRequests returns the open pull requests of the repository.
+func (c *Client) PullRequests() ([]PullRequest, error) {
+	var prs []PullRequest
+	opt := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		start := time.Now()
+		page, resp, err := c.gc.PullRequests.List(c.owner, c.repo, opt)
+		c.fetched("pulls", start, resp, err)
+		if err != nil {
+			return nil, fmt.Errorf("listing pull requests: %v", err)
+		}
+		for _, pr := range page {
+			if pr.Number == nil || pr.Head == nil || pr.Head.SHA == nil {
+				continue
+			}
+			p := PullRequest{Number: *pr.Number, Head: *pr.Head.SHA}
+			if pr.Title != nil {
+				p.Title = *pr.Title
+			}
+			prs = append(prs, p)
+		}
+		if resp.NextPage == 0 {
+			return prs, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// changedFile is a file that a pull request adds or modifies.
+type changedFile struct {
+	path  string
+	added map[int]int // line number -> position in the diff, for lines the change adds
+}
+
+// pullRequestFiles returns the files that a pull request adds or modifies.
+func (c *Client) pullRequestFiles(number int) ([]changedFile, error) {
+	var files []changedFile
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		start := time.Now()
+		page, resp, err := c.gc.PullRequests.ListFiles(c.owner, c.repo, number, opt)
+		c.fetched("pulls", start, resp, err)
+		if err != nil {
+			return nil, fmt.Errorf("listing files of pull request #%d: %v", number, err)
+		}
+		for _, f := range page {
+			if f.Filename == nil || (f.Status != nil && *f.Status == "removed") {
+				continue
+			}
+			cf := changedFile{path: *f.Filename}
+			if f.Patch != nil {
+				cf.added = addedLines(*f.Patch)
+			}
+			files = append(files, cf)
+		}
+		if resp.NextPage == 0 {
+			return files, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// addedLines returns the positions in a unified diff of the lines it adds,
+// keyed by their line numbers in the new file. As GitHub's review comments
+// expect, positions count lines from the first hunk header, which is position 0.
+func addedLines(patch string) map[int]int {
+	added := make(map[int]int)
+	line := 0
+	for pos, l := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(l, "@@"):
+			// @@ -l,s +l,s @@
+			if i := strings.Index(l, " +"); i >= 0 {
+				r := l[i+2:]
+				if j := strings.IndexAny(r, ", "); j >= 0 {
+					r = r[:j]
+				}
+				line, _ = strconv.Atoi(r)
+			}
+		case strings.HasPrefix(l, "+"):
+			added[line] = pos
+			line++
+		case strings.HasPrefix(l, "-"), strings.HasPrefix(l, `\`):
+			// Not in the new file.
+		default:
+			line++
+		}
+	}
+	return added
+}
+
+// A ReviewComment is a comment on a line of a pull request's changes.
+type ReviewComment struct {
+	ID       int
+	Author   string // login of the user who made it
+	Path     string
+	Position int    // in the diff of the file, as for addedLines
+	CommitID string // the commit it was made on
+	Body     string
+}
+
+// ReviewComments returns the review comments on a pull request.
+func (c *Client) ReviewComments(number int) ([]ReviewComment, error) {
+	var comments []ReviewComment
+	opt := &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		start := time.Now()
+		page, resp, err := c.gc.PullRequests.ListComments(c.owner, c.repo, number, opt)
+		c.fetched("pulls", start, resp, err)
+		if err != nil {
+			return nil, fmt.Errorf("listing comments on pull request #%d: %v", number, err)
+		}
+		for _, pc := range page {
+			if pc.ID == nil || pc.Path == nil || pc.Body == nil {
+				continue
+			}
+			rc := ReviewComment{ID: *pc.ID, Path: *pc.Path, Body: *pc.Body}
+			if pc.Position != nil {
+				rc.Position = *pc.Position
+			}
+			if pc.OriginalCommitID != nil {
+				rc.CommitID = *pc.OriginalCommitID
+			} else if pc.CommitID != nil {
+				rc.CommitID = *pc.CommitID
+			}
+			if pc.User != nil && pc.User.Login != nil {
+				rc.Author = *pc.User.Login
+			}
+			comments = append(comments, rc)
+		}
+		if resp.NextPage == 0 {
+			return comments, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// CheckPullRequest checks the files that a pull request adds or modifies,
+// at its head. The problems it returns are limited to those on lines that
+// the pull request adds.
+func (c *Client) CheckPullRequest(pr PullRequest) (*CheckResult, error) {
+	res, added, err := c.checkPullRequest(pr)
+	if err != nil {
+		return nil, err
+	}
+	var ps Problems
+	for _, p := range res.Problems {
+		if _, ok := added[p.File][p.Line]; ok {
+			ps = append(ps, p)
+		}
+	}
+	res.Problems = ps
+	return res, nil
+}
+
+// checkPullRequest checks the files that a pull request adds or modifies,
+// at its head, returning all the problems found in them. It also returns
+// the lines that the pull request adds, as for changedFile.
+func (c *Client) checkPullRequest(pr PullRequest) (*CheckResult, map[string]map[int]int, error) {
+	files, err := c.pullRequestFiles(pr.Number)
+	if err != nil {
+		return nil, nil, err
+	}
+	added := make(map[string]map[int]int)
+	var paths []string
+	for _, f := range files {
+		added[f.path] = f.added
+		paths = append(paths, f.path)
+	}
+	if len(paths) == 0 {
+		return &CheckResult{SHA: pr.Head}, added, nil
+	}
+	res, err := c.CheckFiles(pr.Head, paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, added, nil
+}
+
+// ReviewPullRequest checks a pull request like CheckPullRequest,
+// and comments on the problems found on the lines it adds.
+// It can be called again after each push to the pull request:
+// it doesn't repeat a comment on a problem that is still there,
+// and it edits comments on problems that have gone to say that they are fixed.
+// Comments are matched to problems by fingerprint, which each comment
+// carries in a hidden marker, following files renamed since they were made.
+// It returns the result of the check, as from CheckPullRequest,
+// and how many comments it made and marked as fixed.
+func (c *Client) ReviewPullRequest(pr PullRequest) (res *CheckResult, posted, fixed int, err error) {
+	res, added, err := c.checkPullRequest(pr)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	existing, err := c.ReviewComments(pr.Number)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	open := make(map[string][]ReviewComment) // fingerprint -> comments on problems not yet fixed
+	oldPaths := make(map[string][]string)    // path at pr.Head -> paths it had when commented on
+	compared := make(map[string]bool)        // commits whose renames are in oldPaths
+	for _, rc := range existing {
+		fp, done := commentFingerprint(rc.Body)
+		if fp == "" || done {
+			continue
+		}
+		open[fp] = append(open[fp], rc)
+		if rc.CommitID == "" || rc.CommitID == pr.Head || compared[rc.CommitID] {
+			continue
+		}
+		compared[rc.CommitID] = true
+		renames, err := c.Renames(rc.CommitID, pr.Head)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("finding files renamed in pull request #%d: %v", pr.Number, err)
+		}
+		for from, to := range renames {
+			oldPaths[to] = append(oldPaths[to], from)
+		}
+	}
+
+	// Each problem still present accounts for one comment about it,
+	// wherever it is. Others on added lines need commenting on.
+	var ps, uncommented Problems
+	for _, p := range res.Problems {
+		fp := p.Fingerprint()
+		for _, from := range oldPaths[p.File] {
+			if len(open[fp]) > 0 {
+				break
+			}
+			// It may have been commented on before its file was renamed.
+			old := p
+			old.File = from
+			fp = old.Fingerprint()
+		}
+		if len(open[fp]) == 0 {
+			// It may have been commented on before fingerprints used the line's text.
+			fp = Problem{File: p.File, Type: p.Type, Text: p.Text}.Fingerprint()
+		}
+		_, onAdded := added[p.File][p.Line]
+		if onAdded {
+			ps = append(ps, p)
+		}
+		if rcs := open[fp]; len(rcs) > 0 {
+			open[fp] = rcs[1:]
+		} else if onAdded {
+			uncommented = append(uncommented, p)
+		}
+	}
+	res.Problems = ps
+
+	for _, p := range uncommented {
+		_, _, err := c.gc.PullRequests.CreateComment(c.owner, c.repo, pr.Number, &github.PullRequestComment{
+			Body:     github.String(reviewCommentBody(p)),
+			Path:     github.String(p.File),
+			Position: github.Int(added[p.File][p.Line]),
+			CommitID: github.String(pr.Head),
+		})
+		if err != nil {
+			return res, posted, fixed, fmt.Errorf("commenting on %s:%d of pull request #%d: %v", p.File, p.Line, pr.Number, err)
+		}
+		posted++
+	}
+
+	// The comments left over are about problems that have been fixed.
+	var stale []ReviewComment
+	for _, rcs := range open {
+		stale = append(stale, rcs...)
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].ID < stale[j].ID })
+	for _, rc := range stale {
+		_, _, err := c.gc.PullRequests.EditComment(c.owner, c.repo, rc.ID, &github.PullRequestComment{
+			Body: github.String(fixedCommentBody(rc.Body, pr.Head)),
+		})
+		if err != nil {
+			return res, posted, fixed, fmt.Errorf("marking comment %d on pull request #%d as fixed: %v", rc.ID, pr.Number, err)
+		}
+		fixed++
+	}
+	return res, posted, fixed, nil
+}
+
+// commentMarkerRE matches the hidden marker at the end of a review comment made by
+// ReviewPullRequest, which holds the fingerprint of the problem it is about,
+// and says whether the problem has since been fixed.
+var commentMarkerRE = regexp.MustCompile(`\n\n<!-- fixhub:([0-9a-f]+)( fixed)? -->$`)
+
+// reviewCommentBody returns the text of a review comment about p.
+func reviewCommentBody(p Problem) string {
+	body := fmt.Sprintf("**fixhub** (%s): %s", p.Type, p.Text)
+	if p.URL != "" {
+		body += fmt.Sprintf(" ([why?](%s))", p.URL)
+	}
+	return body + fmt.Sprintf("\n\n<!-- fixhub:%s -->", p.Fingerprint())
+}
+
+// fixedCommentBody returns body, the text of a review comment, struck out
+// and marked as fixed as of the commit head.
+func fixedCommentBody(body, head string) string {
+	m := commentMarkerRE.FindStringSubmatchIndex(body)
+	if m == nil {
+		return body
+	}
+	fp := body[m[2]:m[3]]
+	if len(head) > 7 {
+		head = head[:7]
+	}
+	return fmt.Sprintf("✅ Fixed as of %s: ~~%s~~\n\n<!-- fixhub:%s fixed -->", head, body[:m[0]], fp)
+}
+
+// commentFingerprint returns the fingerprint of the problem that a review comment
+// made by ReviewPullRequest is about, and whether it has been fixed.
+// The fingerprint is empty if the comment wasn't made by ReviewPullRequest.
+func commentFingerprint(body string) (fp string, fixed bool) {
+	m := commentMarkerRE.FindStringSubmatch(body)
+	if m == nil {
+		return "", false
+	}
+	return m[1], m[2] != ""
+}
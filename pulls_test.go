@@ -0,0 +1,111 @@
+package fixhub
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestAddedLines(t *testing.T) {
+	patch := `@@ -1,3 +1,4 @@
+ package foo
++
++import "fmt"
+ 
+@@ -10,2 +11,2 @@ func f() {
+-	println("hi")
++	fmt.Println("hi")
+ }
+\ No newline at end of file`
+	want := map[int]int{2: 2, 3: 3, 11: 7}
+	if got := addedLines(patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("addedLines = %v, want %v", got, want)
+	}
+}
+
+func TestReviewPullRequest(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "proj", map[string][]byte{
+		"a.go": []byte("package a\n\n// A is documented.\nfunc A() {}\n"),
+	})
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	c.Enabled = []ProblemType{Lint}
+
+	review := func(wantPosted, wantFixed int) {
+		t.Helper()
+		prs, err := c.PullRequests()
+		if err != nil || len(prs) != 1 {
+			t.Fatalf("PullRequests = %v, %v; want one", prs, err)
+		}
+		_, posted, fixed, err := c.ReviewPullRequest(prs[0])
+		if err != nil {
+			t.Fatalf("ReviewPullRequest: %v", err)
+		}
+		if posted != wantPosted || fixed != wantFixed {
+			t.Errorf("ReviewPullRequest posted %d comments and marked %d fixed, want %d and %d", posted, fixed, wantPosted, wantFixed)
+		}
+	}
+
+	if _, err := srv.Push("faker", "proj", "feature", "Add B", map[string][]byte{
+		"b.go": []byte("package a\n\nfunc B() {}\n"),
+	}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	n, err := srv.AddPullRequest("faker", "proj", "feature", "Add B")
+	if err != nil {
+		t.Fatalf("AddPullRequest: %v", err)
+	}
+	review(1, 0)
+	review(0, 0) // nothing new
+
+	if _, err := srv.Push("faker", "proj", "feature", "Add C", map[string][]byte{
+		"c.go": []byte("package a\n\nfunc C() {}\n"),
+	}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	review(1, 0)
+
+	// Fix B, moving C's problem down a line.
+	if _, err := srv.Push("faker", "proj", "feature", "Fix B", map[string][]byte{
+		"b.go": []byte("package a\n\n// B is documented.\nfunc B() {}\n"),
+		"c.go": []byte("package a\n\n\nfunc C() {}\n"),
+	}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	review(0, 1)
+	review(0, 0)
+
+	// Renaming c.go leaves its comment as it is.
+	if _, err := srv.Push("faker", "proj", "feature", "Rename c.go", map[string][]byte{
+		"c.go": nil,
+		"d.go": []byte("package a\n\n\nfunc C() {}\n"),
+	}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	review(0, 0)
+	got := srv.ReviewComments("faker", "proj", n)
+	if len(got) != 2 || !strings.HasPrefix(got[0], "✅ Fixed as of ") || strings.HasPrefix(got[1], "✅") {
+		t.Errorf("pull request has comments %q, want the first marked fixed", got)
+	}
+}
+
+func TestCommentFingerprint(t *testing.T) {
+	p := Problem{File: "a.go", Line: 3, Type: Lint, Text: "exported function A should have comment or be unexported"}
+	body := reviewCommentBody(p)
+	if fp, fixed := commentFingerprint(body); fp != p.Fingerprint() || fixed {
+		t.Errorf("commentFingerprint(%q) = %q, %v; want %q, false", body, fp, fixed, p.Fingerprint())
+	}
+	body = fixedCommentBody(body, "0123456789abcdef")
+	if fp, fixed := commentFingerprint(body); fp != p.Fingerprint() || !fixed {
+		t.Errorf("commentFingerprint(%q) = %q, %v; want %q, true", body, fp, fixed, p.Fingerprint())
+	}
+	if fp, _ := commentFingerprint("LGTM"); fp != "" {
+		t.Errorf("commentFingerprint of someone else's comment = %q, want none", fp)
+	}
+}
@@ -0,0 +1,60 @@
+package fixhub
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rateLimitTransport is an http.RoundTripper that, when its client's
+// WaitForRateLimit is set, waits out exhausted GitHub rate limits and retries.
+type rateLimitTransport struct {
+	c    *Client
+	base http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || t.c.WaitForRateLimit == nil {
+			return resp, err
+		}
+		reset, ok := rateLimitReset(resp)
+		if !ok || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+
+		// Only one request waits; the others find the wait over when they get the lock.
+		t.c.rateWait.Lock()
+		retry := !time.Now().Before(reset) || t.c.WaitForRateLimit(reset)
+		t.c.rateWait.Unlock()
+		if !retry {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// rateLimitReset reports whether resp was refused because the GitHub API rate limit
+// was exhausted, and if so, when it resets.
+func rateLimitReset(resp *http.Response) (time.Time, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return time.Time{}, false
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
@@ -0,0 +1,265 @@
+package fixhub
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// These checks are cheap approximations of unconvert and ineffassign.
+// Without type information they only report cases that can be
+// decided from the syntax of a single file.
+
+// checkUnconvert reports conversions of a value to the type it already has.
+func checkUnconvert(filename string, src []byte) Problems {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil
+	}
+	var ps Problems
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 || call.Ellipsis.IsValid() {
+			return true
+		}
+		typ := conversionType(call)
+		if typ == "" || exprType(call.Args[0]) != typ {
+			return true
+		}
+		ps = append(ps, Problem{
+			File: filename,
+			Type: Unconvert,
+			Text: fmt.Sprintf("unnecessary conversion to %s", typ),
+		}.at(fset, call.Pos(), call.End()))
+		return true
+	})
+	return ps
+}
+
+// untypedDefaults maps literal kinds to the type they have when used as a value.
+var untypedDefaults = map[token.Token]string{
+	token.INT:    "int",
+	token.FLOAT:  "float64",
+	token.CHAR:   "rune",
+	token.STRING: "string",
+}
+
+// conversionType returns the name of the type that call converts to,
+// or "" if call is not a conversion to a named type.
+func conversionType(call *ast.CallExpr) string {
+	id, ok := call.Fun.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if id.Obj != nil {
+		if id.Obj.Kind == ast.Typ {
+			return id.Name
+		}
+		return ""
+	}
+	if _, ok := basicLayouts[id.Name]; ok {
+		return id.Name // predeclared
+	}
+	return ""
+}
+
+// exprType returns the name of the type of e if it is evident from the syntax,
+// or "" otherwise.
+func exprType(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return exprType(e.X)
+	case *ast.BasicLit:
+		return untypedDefaults[e.Kind]
+	case *ast.CallExpr:
+		if len(e.Args) == 1 {
+			return conversionType(e)
+		}
+	case *ast.Ident:
+		if e.Obj == nil || e.Obj.Kind != ast.Var {
+			return ""
+		}
+		var typ ast.Expr
+		switch decl := e.Obj.Decl.(type) {
+		case *ast.Field:
+			typ = decl.Type
+		case *ast.ValueSpec:
+			typ = decl.Type
+		}
+		if id, ok := typ.(*ast.Ident); ok {
+			return id.Name
+		}
+	}
+	return ""
+}
+
+// checkIneffAssign reports assignments to local variables whose values are
+// overwritten, or the function returns, before they are ever read.
+// Only straight-line code within a single block is considered.
+func checkIneffAssign(filename string, src []byte) Problems {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil
+	}
+	var ps Problems
+	ast.Inspect(f, func(n ast.Node) bool {
+		var typ *ast.FuncType
+		var body *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			typ, body = n.Type, n.Body
+		case *ast.FuncLit:
+			typ, body = n.Type, n.Body
+		default:
+			return true
+		}
+		if body == nil {
+			return false
+		}
+		for _, p := range ineffAssigns(fset, typ, body) {
+			p.File = filename
+			ps = append(ps, p)
+		}
+		return true
+	})
+	return ps
+}
+
+func ineffAssigns(fset *token.FileSet, typ *ast.FuncType, body *ast.BlockStmt) Problems {
+	// Work out which variables are unsafe to consider: named results,
+	// which a bare return reads, and variables that are captured by
+	// function literals or have their address taken.
+	unsafe := make(map[*ast.Object]bool)
+	if typ.Results != nil {
+		for _, fld := range typ.Results.List {
+			for _, name := range fld.Names {
+				unsafe[name.Obj] = true
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			ast.Inspect(n.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && id.Obj != nil {
+					unsafe[id.Obj] = true
+				}
+				return true
+			})
+			return false
+		case *ast.UnaryExpr:
+			if id, ok := n.X.(*ast.Ident); ok && n.Op == token.AND && id.Obj != nil {
+				unsafe[id.Obj] = true
+			}
+		}
+		return true
+	})
+	local := func(id *ast.Ident) bool {
+		return id.Obj != nil && id.Obj.Kind == ast.Var && !unsafe[id.Obj] &&
+			body.Pos() <= id.Obj.Pos() && id.Obj.Pos() < body.End()
+	}
+
+	var ps Problems
+	check := func(list []ast.Stmt) {
+		for i, stmt := range list {
+			as, ok := stmt.(*ast.AssignStmt)
+			if !ok {
+				continue
+			}
+			for j, lhs := range as.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				if !ok || id.Name == "_" || !local(id) {
+					continue
+				}
+				if len(as.Rhs) == len(as.Lhs) && isZero(as.Rhs[j]) {
+					continue
+				}
+				if overwritten(id.Obj, list[i+1:]) {
+					ps = append(ps, Problem{
+						Type: IneffAssign,
+						Text: fmt.Sprintf("ineffectual assignment to %s", id.Name),
+					}.at(fset, id.Pos(), id.End()))
+				}
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false // handled separately
+		case *ast.BlockStmt:
+			check(n.List)
+		case *ast.CaseClause:
+			check(n.Body)
+		case *ast.CommClause:
+			check(n.Body)
+		}
+		return true
+	})
+	return ps
+}
+
+// overwritten reports whether the variable obj is assigned to or goes out of
+// use by returning in stmts before it is read.
+func overwritten(obj *ast.Object, stmts []ast.Stmt) bool {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.AssignStmt:
+			if stmt.Tok == token.ASSIGN || stmt.Tok == token.DEFINE {
+				if mentionsAny(obj, stmt.Rhs) {
+					return false
+				}
+				for _, lhs := range stmt.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok && id.Obj == obj {
+						return true
+					}
+				}
+			}
+		case *ast.ReturnStmt:
+			return !mentionsAny(obj, stmt.Results)
+		case *ast.BranchStmt, *ast.LabeledStmt:
+			return false // control flow we don't follow
+		}
+		if mentions(obj, stmt) {
+			return false
+		}
+	}
+	return false
+}
+
+// mentions reports whether n refers to obj.
+func mentions(obj *ast.Object, n ast.Node) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Obj == obj {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func mentionsAny(obj *ast.Object, exprs []ast.Expr) bool {
+	for _, e := range exprs {
+		if mentions(obj, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// isZero reports whether e is a literal zero value, such as 0, "" or nil.
+// Assigning such values is a common way of declaring a variable,
+// so they are never reported as ineffectual.
+func isZero(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		return e.Value == "0" || e.Value == `""` || e.Value == "``"
+	case *ast.Ident:
+		return e.Name == "nil" || e.Name == "false"
+	}
+	return false
+}
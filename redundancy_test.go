@@ -0,0 +1,72 @@
+package fixhub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func problemLines(ps Problems) []int {
+	var lines []int
+	for _, p := range ps {
+		lines = append(lines, p.Line)
+	}
+	return lines
+}
+
+func TestCheckUnconvert(t *testing.T) {
+	const src = `package p
+
+type T int
+
+func f(s string, t T, n int64) {
+	_ = string(s)
+	_ = int64(n)
+	_ = T(t)
+	_ = int(n)
+	_ = string("x")
+	_ = float64(1)
+	_ = T(T(n))
+	var u uint8 = 3
+	_ = uint8(u)
+}
+`
+	ps := checkUnconvert("p.go", []byte(src))
+	if got, want := problemLines(ps), []int{6, 7, 8, 10, 12, 14}; !reflect.DeepEqual(got, want) {
+		t.Errorf("problems on lines %v, want %v", got, want)
+	}
+	// string(s) spans columns 6 to 14.
+	if p := ps[0]; p.Col != 6 || p.EndLine != 6 || p.EndCol != 15 {
+		t.Errorf("first problem at %d:%d-%d:%d, want 6:6-6:15", p.Line, p.Col, p.EndLine, p.EndCol)
+	}
+}
+
+func TestCheckIneffAssign(t *testing.T) {
+	const src = `package p
+
+func f() int {
+	x := g()
+	x = g()
+	y := g()
+	y = y + 1
+	z := 0
+	z = g()
+	w := g()
+	if w > 0 {
+		w = 1
+		return 2
+	}
+	return x + y + z + w
+}
+
+func h() (n int) {
+	n = 1
+	return
+}
+
+func g() int { return 1 }
+`
+	ps := checkIneffAssign("p.go", []byte(src))
+	if got, want := problemLines(ps), []int{4, 12}; !reflect.DeepEqual(got, want) {
+		t.Errorf("problems on lines %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package fixhub
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"code.google.com/p/goauth2/oauth"
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+var (
+	record     = flag.Bool("record", false, "record HTTP fixtures from the live GitHub API (using $GITHUB_TOKEN) instead of replaying them")
+	recordFake = flag.Bool("record_fake", false, "record HTTP fixtures from a fixhubtest.Server holding each test's files instead of replaying them")
+)
+
+// replayTests are checked against the HTTP fixtures in testdata/replay.
+//
+// The fixtures are synthetic: the repositories they name don't exist on
+// GitHub, and they were recorded with -record_fake from a fixhubtest.Server
+// holding the files that each test lists, so they only show how that fake
+// behaves. If such repositories are made on GitHub, recording them with
+// -record instead checks fixhub against the real API.
+var replayTests = []struct {
+	owner, repo, rev string
+	files            map[string][]byte // for -record_fake
+	truncate         int               // for -record_fake; see fixhubtest.Server.TruncateTrees
+	want             []Problem         // compared on File, Line and Type
+	fixed            []string          // if not nil, the files that Fix changes, sorted
+}{
+	// Non-ASCII file names and content, and a tree with over 100 files.
+	{"faker", "unicode", "master", unicodeFiles(), 0, []Problem{
+		{File: "emoji/broken.go", Line: 3, Type: Syntax},
+		{File: "héllo/ñandú.go", Line: 0, Type: Gofmt},
+	}, nil},
+	// The same, fixed, without the syntax error that Fix would fail on.
+	{"faker", "fixable", "master", fixableFiles(), 0, []Problem{
+		{File: "héllo/ñandú.go", Line: 0, Type: Gofmt},
+		{File: "z/z.go", Line: 0, Type: Gofmt},
+	}, []string{"héllo/ñandú.go", "z/z.go"}},
+	// A recursive listing that GitHub truncates, so the tree is walked instead.
+	{"faker", "truncated", "master", truncatedFiles(), 50, []Problem{
+		{File: "c/f19.go", Line: 0, Type: Gofmt},
+	}, nil},
+}
+
+func unicodeFiles() map[string][]byte {
+	files := map[string][]byte{
+		"README.md":       []byte("# unicode\n\nA repository of Go files with non-ASCII names.\n"),
+		"emoji/broken.go": []byte("package emoji\n\nfunc Σ( {\n"),
+		"emoji/🦀.go":      []byte("package emoji\n\n// Crab is 🦀.\nconst Crab = \"🦀\"\n"),
+		"héllo/ñandú.go":  []byte("package héllo\n\n// Ñandú is a bird.\ntype Ñandú struct{\nName string\n}\n"),
+		"héllo/世界.go":     []byte("package héllo\n\n// Grüße returns a greeting.\nfunc Grüße() string { return \"こんにちは\" }\n"),
+	}
+	for i := 0; i < 120; i++ {
+		files[fmt.Sprintf("big/f%03d.go", i)] = []byte(fmt.Sprintf("package big\n\n// F%03d is a constant.\nconst F%03d = %d\n", i, i, i))
+	}
+	return files
+}
+
+func fixableFiles() map[string][]byte {
+	files := unicodeFiles()
+	delete(files, "emoji/broken.go")
+	files["z/z.go"] = []byte("package z\nvar  Z = 1\n")
+	return files
+}
+
+func truncatedFiles() map[string][]byte {
+	files := make(map[string][]byte)
+	for _, dir := range []string{"a", "b", "c"} {
+		for i := 0; i < 20; i++ {
+			files[fmt.Sprintf("%s/f%02d.go", dir, i)] = []byte(fmt.Sprintf("package %s\n\n// F%02d is a constant.\nconst F%02d = %d\n", dir, i, i, i))
+		}
+	}
+	// Beyond the truncated listing.
+	files["c/f19.go"] = []byte("package c\nconst  F19 = 19\n")
+	return files
+}
+
+// fakeGitHub is an http.RoundTripper that sends requests for the GitHub API
+// to a fixhubtest.Server instead.
+type fakeGitHub struct {
+	srv *fixhubtest.Server
+}
+
+func (f fakeGitHub) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host, req.Host = "http", f.srv.Listener.Addr().String(), ""
+	req.URL.Path = "/gh" + req.URL.Path
+	if req.URL.RawPath != "" {
+		req.URL.RawPath = "/gh" + req.URL.RawPath
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestReplay(t *testing.T) {
+	for _, tt := range replayTests {
+		fixture := filepath.Join("testdata", "replay", tt.owner+"_"+tt.repo+".json")
+
+		var c *Client
+		var rec *fixhubtest.Recorder
+		switch {
+		case *recordFake:
+			srv := fixhubtest.NewServer()
+			defer srv.Close()
+			srv.TruncateTrees = tt.truncate
+			srv.AddRepo(tt.owner, tt.repo, tt.files)
+			rec = &fixhubtest.Recorder{Transport: fakeGitHub{srv}}
+			c = NewClientWithHTTPClient(tt.owner, tt.repo, &http.Client{Transport: rec})
+		case *record:
+			rec = &fixhubtest.Recorder{}
+			if tok := os.Getenv("GITHUB_TOKEN"); tok != "" {
+				rec.Transport = &oauth.Transport{Token: &oauth.Token{AccessToken: tok}}
+			}
+			c = NewClientWithHTTPClient(tt.owner, tt.repo, &http.Client{Transport: rec})
+		default:
+			rp, err := fixhubtest.NewReplayer(fixture)
+			if err != nil {
+				t.Fatalf("NewReplayer: %v", err)
+			}
+			c = NewClientWithHTTPClient(tt.owner, tt.repo, &http.Client{Transport: rp})
+		}
+		c.Enabled = []ProblemType{Gofmt}
+
+		ps, err := c.Check(tt.rev)
+		if err != nil {
+			t.Errorf("%s/%s: Check: %v", tt.owner, tt.repo, err)
+			continue
+		}
+		var fixed []string
+		if tt.fixed != nil {
+			files, err := c.Fix(tt.rev)
+			if err != nil {
+				t.Errorf("%s/%s: Fix: %v", tt.owner, tt.repo, err)
+				continue
+			}
+			for path := range files {
+				fixed = append(fixed, path)
+			}
+			sort.Strings(fixed)
+		}
+		if rec != nil {
+			if err := rec.Save(fixture); err != nil {
+				t.Fatalf("Saving %s: %v", fixture, err)
+			}
+		}
+
+		if len(ps) != len(tt.want) {
+			t.Errorf("%s/%s: got %d problems, want %d:\n%v", tt.owner, tt.repo, len(ps), len(tt.want), ps)
+			continue
+		}
+		for i, p := range ps {
+			w := tt.want[i]
+			if p.File != w.File || p.Line != w.Line || p.Type != w.Type {
+				t.Errorf("%s/%s: problem %d is %s:%d (%s), want %s:%d (%s)", tt.owner, tt.repo, i, p.File, p.Line, p.Type, w.File, w.Line, w.Type)
+			}
+		}
+		if tt.fixed != nil && !reflect.DeepEqual(fixed, tt.fixed) {
+			t.Errorf("%s/%s: Fix changed %q, want %q", tt.owner, tt.repo, fixed, tt.fixed)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package fixhub
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// A repoPackage is a parsed Go package in the repository being checked.
+type repoPackage struct {
+	dir   string               // slash-separated, "." for the top level
+	name  string               // package name
+	path  string               // import path
+	files map[string]*ast.File // keyed by path in the repository
+}
+
+// parsePackages parses the repository's packages for analyses that need to see all of them.
+// modPath is the import path corresponding to the top of the repository.
+// packages maps a directory and package name (separated by a space) to the
+// files of that package, keyed by path. Files that fail to parse are omitted.
+func parsePackages(fset *token.FileSet, modPath string, packages map[string]map[string][]byte) []*repoPackage {
+	var pkgs []*repoPackage
+	for key, files := range packages {
+		i := strings.LastIndex(key, " ")
+		rp := &repoPackage{
+			dir:   key[:i],
+			name:  key[i+1:],
+			path:  modPath,
+			files: make(map[string]*ast.File),
+		}
+		if rp.dir != "." {
+			rp.path += "/" + rp.dir
+		}
+		for filename, src := range files {
+			f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+			if err != nil {
+				continue
+			}
+			rp.files[filename] = f
+		}
+		pkgs = append(pkgs, rp)
+	}
+	return pkgs
+}
+
+// importNames returns the local names of f's imports, mapped to their import paths.
+// nameOf returns the package name for an import path, or "" if it is unknown,
+// in which case the import is omitted unless it is explicitly named.
+// Blank and dot imports are always omitted.
+func importNames(f *ast.File, nameOf func(path string) string) map[string]string {
+	m := make(map[string]string)
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := ""
+		if imp.Name != nil {
+			name = imp.Name.Name
+		} else {
+			name = nameOf(path)
+		}
+		if name == "" || name == "_" || name == "." {
+			continue
+		}
+		m[name] = path
+	}
+	return m
+}
+
+// qualifiedRef returns the package name and identifier if n is a
+// qualified identifier like pkg.Name, where pkg is not a local variable.
+func qualifiedRef(n ast.Node) (pkg, name *ast.Ident, ok bool) {
+	sel, ok := n.(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil, false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok || x.Obj != nil { // x.Obj is set for local variables
+		return nil, nil, false
+	}
+	return x, sel.Sel, true
+}
+
+// isStdPath reports whether an import path looks like it is in the standard library.
+func isStdPath(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
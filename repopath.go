@@ -0,0 +1,88 @@
+package fixhub
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// A RepoPath names a directory at a revision of a repository on GitHub.
+type RepoPath struct {
+	Owner, Repo string
+	Rev         string // empty for the default branch
+	Dir         string // slash-separated; empty for the whole repository
+}
+
+// String returns rp in the form that ParseRepoPath parses: owner/repo[@rev][/dir].
+func (rp RepoPath) String() string {
+	s := rp.Owner + "/" + rp.Repo
+	if rp.Rev != "" {
+		s += "@" + rp.Rev
+	}
+	if rp.Dir != "" {
+		s += "/" + rp.Dir
+	}
+	return s
+}
+
+var (
+	// GitHub user and organization names are alphanumeric with hyphens;
+	// some old ones break the rules about where hyphens may go.
+	ownerRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,38}$`)
+	repoRE  = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+)
+
+// ParseRepoPath parses s, which names a GitHub repository and optionally
+// a revision and a directory in it, as owner/repo[@rev][/dir].
+// It also accepts the forms people tend to copy from elsewhere:
+// with a github.com/ or https://github.com/ prefix or a git@github.com: one,
+// with a .git suffix on the repository, and with a trailing slash.
+func ParseRepoPath(s string) (RepoPath, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	if rest, ok := strings.CutPrefix(s, "git@github.com:"); ok {
+		s = rest
+	} else if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return RepoPath{}, fmt.Errorf("%q is not a URL of a GitHub repository: %v", orig, err)
+		}
+		if h := strings.TrimPrefix(u.Host, "www."); h != "github.com" {
+			return RepoPath{}, fmt.Errorf("%q is not on GitHub; only GitHub repositories can be checked", orig)
+		}
+		s = u.Path
+	} else {
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "www."), "github.com/")
+	}
+	s = strings.Trim(s, "/")
+
+	parts := strings.SplitN(s, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return RepoPath{}, fmt.Errorf("%q does not name a repository; want owner/repo", orig)
+	}
+	rp := RepoPath{Owner: parts[0]}
+	rp.Repo, rp.Rev, _ = strings.Cut(parts[1], "@")
+	rp.Repo = strings.TrimSuffix(rp.Repo, ".git")
+	if len(parts) == 3 {
+		rp.Dir = path.Clean(parts[2])
+		if rp.Dir == "." {
+			rp.Dir = ""
+		}
+	}
+
+	if !ownerRE.MatchString(rp.Owner) {
+		return RepoPath{}, fmt.Errorf("%q is not a valid GitHub user or organization name", rp.Owner)
+	}
+	if !repoRE.MatchString(rp.Repo) || rp.Repo == "." || rp.Repo == ".." {
+		return RepoPath{}, fmt.Errorf("%q is not a valid GitHub repository name", rp.Repo)
+	}
+	if strings.Contains(parts[1], "@") && rp.Rev == "" {
+		return RepoPath{}, fmt.Errorf("%q has an empty revision after @", orig)
+	}
+	if rp.Dir == ".." || strings.HasPrefix(rp.Dir, "../") {
+		return RepoPath{}, fmt.Errorf("directory %q is outside the repository", parts[2])
+	}
+	return rp, nil
+}
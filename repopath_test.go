@@ -0,0 +1,55 @@
+package fixhub
+
+import "testing"
+
+func TestParseRepoPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RepoPath // zero if in is invalid
+	}{
+		{"dsymonds/fixhub", RepoPath{Owner: "dsymonds", Repo: "fixhub"}},
+		{"github.com/dsymonds/fixhub/", RepoPath{Owner: "dsymonds", Repo: "fixhub"}},
+		{" https://github.com/dsymonds/fixhub.git ", RepoPath{Owner: "dsymonds", Repo: "fixhub"}},
+		{"https://www.github.com/dsymonds/fixhub?tab=readme", RepoPath{Owner: "dsymonds", Repo: "fixhub"}},
+		{"git@github.com:dsymonds/fixhub.git", RepoPath{Owner: "dsymonds", Repo: "fixhub"}},
+		{"dsymonds/fixhub@v1.2/cmd//fixhubd/", RepoPath{Owner: "dsymonds", Repo: "fixhub", Rev: "v1.2", Dir: "cmd/fixhubd"}},
+		{"a-b/x.y_z", RepoPath{Owner: "a-b", Repo: "x.y_z"}},
+
+		{"", RepoPath{}},
+		{"dsymonds", RepoPath{}},
+		{"dsymonds/", RepoPath{}},
+		{"https://gitlab.com/dsymonds/fixhub", RepoPath{}},
+		{"gitlab.com/dsymonds/fixhub", RepoPath{}},
+		{"-ds/fixhub", RepoPath{}},
+		{"dsymonds/fix hub", RepoPath{}},
+		{"dsymonds/..", RepoPath{}},
+		{"dsymonds/fixhub@", RepoPath{}},
+		{"dsymonds/fixhub/a/../../b", RepoPath{}},
+	}
+	for _, test := range tests {
+		got, err := ParseRepoPath(test.in)
+		if test.want == (RepoPath{}) {
+			if err == nil {
+				t.Errorf("ParseRepoPath(%q) = %+v, want error", test.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRepoPath(%q): %v", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseRepoPath(%q) = %+v, want %+v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestRepoPathString(t *testing.T) {
+	rp := RepoPath{Owner: "o", Repo: "r", Rev: "main", Dir: "a/b"}
+	if got, want := rp.String(), "o/r@main/a/b"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if back, err := ParseRepoPath(rp.String()); err != nil || back != rp {
+		t.Errorf("ParseRepoPath(%q) = %+v, %v; want %+v", rp.String(), back, err, rp)
+	}
+}
@@ -0,0 +1,154 @@
+package fixhub
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// A Repository is one of the repositories of an owner.
+type Repository struct {
+	Name          string
+	DefaultBranch string
+	Language      string // the primary language, as GitHub detects it; may be empty
+	Fork          bool
+	Archived      bool
+	PushedAt      time.Time
+}
+
+// Repositories returns the repositories of the client's owner,
+// which may be an organization or a user. The client's repository is ignored,
+// so the client may be made with an empty one.
+func (c *Client) Repositories() ([]Repository, error) {
+	var repos []Repository
+	add := func(page []*github.Repository) {
+		for _, gr := range page {
+			if gr.Name == nil {
+				continue
+			}
+			r := Repository{Name: *gr.Name}
+			if gr.DefaultBranch != nil {
+				r.DefaultBranch = *gr.DefaultBranch
+			}
+			if gr.Language != nil {
+				r.Language = *gr.Language
+			}
+			r.Fork = gr.Fork != nil && *gr.Fork
+			r.Archived = gr.Archived != nil && *gr.Archived
+			if gr.PushedAt != nil {
+				r.PushedAt = gr.PushedAt.Time
+			}
+			repos = append(repos, r)
+		}
+	}
+
+	opt := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		start := time.Now()
+		page, resp, err := c.gc.Repositories.ListByOrg(c.owner, opt)
+		c.fetched("repos", start, resp, err)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound && opt.Page == 0 {
+				break // not an organization
+			}
+			return nil, fmt.Errorf("listing repositories of %s: %w", c.owner, err)
+		}
+		add(page)
+		if resp.NextPage == 0 {
+			return repos, nil
+		}
+		opt.Page = resp.NextPage
+	}
+
+	uopt := &github.RepositoryListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		start := time.Now()
+		page, resp, err := c.gc.Repositories.List(c.owner, uopt)
+		c.fetched("repos", start, resp, err)
+		if err != nil {
+			return nil, fmt.Errorf("listing repositories of %s: %w", c.owner, err)
+		}
+		add(page)
+		if resp.NextPage == 0 {
+			return repos, nil
+		}
+		uopt.Page = resp.NextPage
+	}
+}
+
+// Languages returns the number of bytes of code in each language
+// in the repository, as GitHub detects them.
+func (c *Client) Languages() (map[string]int, error) {
+	start := time.Now()
+	langs, resp, err := c.gc.Repositories.ListLanguages(c.owner, c.repo)
+	c.fetched("repos", start, resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("fetching languages: %v", err)
+	}
+	return langs, nil
+}
+
+// repository fetches the client's repository.
+func (c *Client) repository() (*github.Repository, error) {
+	start := time.Now()
+	r, resp, err := c.gc.Repositories.Get(c.owner, c.repo)
+	c.fetched("repos", start, resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s/%s: %w", c.owner, c.repo, err)
+	}
+	return r, nil
+}
+
+// ErrEmptyRepository is returned when a repository has no commits to check.
+// Client methods wrap it; use errors.Is.
+var ErrEmptyRepository = errors.New("repository is empty")
+
+// A RefError is returned when a ref names no branch, tag or commit
+// of a repository, as when asking for master of a repository whose
+// default branch is main. Client methods wrap it; use errors.As.
+type RefError struct {
+	Ref           string
+	DefaultBranch string // the repository's, if it could be found
+}
+
+func (e *RefError) Error() string {
+	s := fmt.Sprintf("no branch, tag or commit named %q", e.Ref)
+	if e.DefaultBranch != "" {
+		s += fmt.Sprintf("; the default branch is %q", e.DefaultBranch)
+	}
+	return s
+}
+
+// DefaultBranch returns the name of the repository's default branch,
+// which need not be master.
+func (c *Client) DefaultBranch() (string, error) {
+	r, err := c.repository()
+	if err != nil {
+		return "", err
+	}
+	if r.DefaultBranch == nil || *r.DefaultBranch == "" {
+		return "", fmt.Errorf("%s/%s has no default branch", c.owner, c.repo)
+	}
+	return *r.DefaultBranch, nil
+}
+
+// CompareURL returns the URL of the page comparing head with the repository's
+// default branch, from which a pull request can be opened. head is a branch,
+// or a branch of a fork named as "owner:branch".
+func (c *Client) CompareURL(head string) (string, error) {
+	r, err := c.repository()
+	if err != nil {
+		return "", err
+	}
+	if r.DefaultBranch == nil || *r.DefaultBranch == "" {
+		return "", fmt.Errorf("%s/%s has no default branch", c.owner, c.repo)
+	}
+	base := "https://github.com/" + c.owner + "/" + c.repo
+	if r.HTMLURL != nil {
+		base = *r.HTMLURL
+	}
+	return base + "/compare/" + *r.DefaultBranch + "..." + head, nil
+}
@@ -0,0 +1,103 @@
+package fixhub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestRepositories(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "code", map[string][]byte{"a.go": []byte("package a\n")})
+	srv.AddRepo("faker", "docs", map[string][]byte{"README": []byte("Hello.\n")})
+	srv.AddRepo("other", "code", map[string][]byte{"a.go": []byte("package a\n")})
+	c := NewClientWithHTTPClient("faker", "", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	repos, err := c.Repositories()
+	if err != nil {
+		t.Fatalf("Repositories: %v", err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("Repositories = %+v, want two", repos)
+	}
+	if r := repos[0]; r.Name != "code" || r.Language != "Go" || r.DefaultBranch != "master" {
+		t.Errorf("repos[0] = %+v, want code, in Go, on master", r)
+	}
+	if r := repos[1]; r.Name != "docs" || r.Language != "" {
+		t.Errorf("repos[1] = %+v, want docs, not in Go", r)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "proj", map[string][]byte{
+		"a.go":   []byte("package a\n"),
+		"README": []byte("Hello.\n"),
+	})
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	langs, err := c.Languages()
+	if err != nil {
+		t.Fatalf("Languages: %v", err)
+	}
+	if len(langs) != 1 || langs["Go"] != len("package a\n") {
+		t.Errorf("Languages = %v, want only Go, of %d bytes", langs, len("package a\n"))
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "proj", map[string][]byte{"a.go": []byte("package a\n")})
+	if err := srv.RenameBranch("faker", "proj", "master", "main"); err != nil {
+		t.Fatalf("RenameBranch: %v", err)
+	}
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	if b, err := c.DefaultBranch(); err != nil || b != "main" {
+		t.Errorf("DefaultBranch = %q, %v; want main", b, err)
+	}
+	u, err := c.CompareURL("fixhub:fix")
+	if want := "https://github.com/faker/proj/compare/main...fixhub:fix"; err != nil || u != want {
+		t.Errorf("CompareURL = %q, %v; want %q", u, err, want)
+	}
+}
+
+func TestMissingRefs(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "proj", map[string][]byte{"a.go": []byte("package a\n")})
+	if err := srv.RenameBranch("faker", "proj", "master", "main"); err != nil {
+		t.Fatalf("RenameBranch: %v", err)
+	}
+	srv.AddEmptyRepo("faker", "empty")
+
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	_, err := c.Run("master")
+	var re *RefError
+	if !errors.As(err, &re) || re.Ref != "master" || re.DefaultBranch != "main" {
+		t.Errorf("Run(master) error = %v; want a RefError naming main", err)
+	}
+
+	c = NewClientWithHTTPClient("faker", "empty", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	if _, err := c.Run("main"); !errors.Is(err, ErrEmptyRepository) {
+		t.Errorf("Run(main) of an empty repository: error = %v; want ErrEmptyRepository", err)
+	}
+}
@@ -0,0 +1,289 @@
+package fixhub
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// A sandbox is a copy of a repository's Go modules in a scratch directory,
+// for checks that need full type information, and so the modules' dependencies.
+type sandbox struct {
+	dir string   // where the repository is copied to
+	env []string // for the go command
+}
+
+// goBinary returns the go command to use for sandboxes.
+func (c *Client) goBinary() (string, error) {
+	if c.GoBinary != "" {
+		return c.GoBinary, nil
+	}
+	gobin := filepath.Join(build.Default.GOROOT, "bin", "go")
+	if _, err := os.Stat(gobin); err != nil {
+		return exec.LookPath("go")
+	}
+	return gobin, nil
+}
+
+// newSandbox writes files, which map slash-separated repository paths to content,
+// into a new scratch directory. The caller must remove it with close.
+func (c *Client) newSandbox(files map[string][]byte) (*sandbox, error) {
+	dir, err := ioutil.TempDir(c.tempDir(), "fixhub-sandbox")
+	if err != nil {
+		return nil, err
+	}
+	s := &sandbox{dir: dir}
+	for path, src := range files {
+		fn := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fn), 0770); err != nil {
+			s.close()
+			return nil, err
+		}
+		if err := ioutil.WriteFile(fn, src, 0660); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+
+	modCache := c.ModCache
+	if modCache == "" {
+		modCache = filepath.Join(c.tempDir(), "fixhub-modcache")
+	}
+	proxy := c.ModuleProxy
+	if proxy == "" {
+		proxy = "off" // only use what is already in the module cache
+	}
+	s.env = append(os.Environ(),
+		"GOPROXY="+proxy,
+		"GOMODCACHE="+modCache,
+		"GOFLAGS=-mod=mod", // the copy's go.mod may be updated, and vendor directories are ignored
+		"GOTOOLCHAIN=local",
+		"GOWORK=off",
+		"GO111MODULE=on",
+		"CGO_ENABLED=0", // don't build C code from the repository
+	)
+	return s, nil
+}
+
+func (s *sandbox) close() error { return os.RemoveAll(s.dir) }
+
+// path returns the repository path of filename in the sandbox,
+// or "" if it is not in the sandbox.
+func (s *sandbox) path(filename string) string {
+	rel, err := filepath.Rel(s.dir, filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// download downloads the dependencies of the module in the slash-separated directory dir.
+func (s *sandbox) download(ctx context.Context, gobin, dir string) error {
+	cmd := exec.CommandContext(ctx, gobin, "mod", "download")
+	cmd.Dir = filepath.Join(s.dir, filepath.FromSlash(dir))
+	cmd.Env = s.env
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// vet runs go vet on the packages of the module in the slash-separated directory dir.
+func (s *sandbox) vet(ctx context.Context, gobin, dir string) (Problems, error) {
+	cmd := exec.CommandContext(ctx, gobin, "vet", "-json",
+		"-printfuncs=Debug:0,Debugf:0,Info:0,Infof:0,Warning:0,Warningf:0",
+		"./...")
+	cmd.Dir = filepath.Join(s.dir, filepath.FromSlash(dir))
+	cmd.Env = s.env
+	// As for the vet tool, the exit status doesn't say whether vet found problems.
+	out, err := cmd.CombinedOutput()
+	if len(out) == 0 && err != nil {
+		return nil, fmt.Errorf("running go vet: %v", err)
+	}
+
+	// The output is a JSON object per package, each after a comment line
+	// naming it, interleaved with messages about packages vet couldn't check:
+	//	# example.com/m
+	//	{
+	//		"example.com/m": {
+	//			"printf": [
+	//				{
+	//					"posn": "/tmp/fixhub-sandbox1/a.go:5:2",
+	//					"message": "fmt.Printf format %d has arg s of wrong type string"
+	//				}
+	//			]
+	//		}
+	//	}
+	var buf bytes.Buffer
+	scan := bufio.NewScanner(bytes.NewReader(out))
+	for scan.Scan() {
+		if line := scan.Bytes(); len(line) > 0 && (line[0] == '{' || line[0] == '}' || line[0] == '\t' || line[0] == ' ') {
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	var ps Problems
+	dec := json.NewDecoder(&buf)
+	for {
+		var pkgs map[string]map[string][]struct {
+			Posn    string `json:"posn"`
+			Message string `json:"message"`
+		}
+		if err := dec.Decode(&pkgs); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing go vet output: %v", err)
+		}
+		for _, analyzers := range pkgs {
+			for analyzer, diags := range analyzers {
+				for _, d := range diags {
+					file, line, col := splitPosition(d.Posn)
+					if path := s.path(file); path != "" {
+						ps = append(ps, Problem{
+							File: path,
+							Line: line,
+							Col:  col,
+							Type: Vet,
+							Code: analyzer,
+							Text: d.Message,
+						})
+					}
+				}
+			}
+		}
+	}
+	return ps, nil
+}
+
+// typeCheck type-checks the packages of the module in the slash-separated directory dir,
+// with their tests. It returns the type errors, and messages about packages that
+// could not be loaded, such as because a dependency is missing.
+func (s *sandbox) typeCheck(ctx context.Context, dir string) (ps Problems, failed []string, err error) {
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Context: ctx,
+		Dir:     filepath.Join(s.dir, filepath.FromSlash(dir)),
+		Env:     s.env,
+		Tests:   true,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, nil, err
+	}
+	// With tests, a package's errors may be reported for each variant of it.
+	seen := make(map[string]bool)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, e := range pkg.Errors {
+			if seen[e.Error()] {
+				continue
+			}
+			seen[e.Error()] = true
+			switch e.Kind {
+			case packages.TypeError:
+				file, line, col := splitPosition(e.Pos)
+				if path := s.path(file); path != "" {
+					ps = append(ps, Problem{
+						File:     path,
+						Line:     line,
+						Col:      col,
+						Type:     Types,
+						Text:     e.Msg,
+						Severity: Error,
+					})
+				}
+			case packages.ParseError:
+				// Reported as syntax errors by the per-file checks.
+			default:
+				failed = append(failed, fmt.Sprintf("%s: %s", pkg.PkgPath, e.Msg))
+			}
+		}
+	})
+	sort.Strings(failed)
+	return ps, failed, nil
+}
+
+// splitPosition splits a position such as "a.go:3:14" or "a.go:3"
+// into its file name, line and column.
+func splitPosition(pos string) (file string, line, col int) {
+	file = pos
+	for i := 0; i < 2; i++ {
+		j := strings.LastIndex(file, ":")
+		if j < 0 {
+			break
+		}
+		n, err := strconv.Atoi(file[j+1:])
+		if err != nil {
+			break
+		}
+		file, line, col = file[:j], n, line
+	}
+	return file, line, col
+}
+
+// checkSandbox copies the repository's Go files and modules into a sandbox,
+// downloads the modules' dependencies, and vets and type-checks each module
+// as a whole. modDirs lists the slash-separated directories of the modules;
+// if it is empty, the repository is checked as a single module, modPath.
+// It returns warnings about modules that could not be checked fully.
+func (c *Client) checkSandbox(ctx context.Context, files map[string][]byte, modDirs []string, modPath string) (Problems, []string, error) {
+	gobin, err := c.goBinary()
+	if err != nil {
+		return nil, nil, fmt.Errorf("finding the go command: %v", err)
+	}
+	if len(modDirs) == 0 {
+		files["go.mod"] = []byte("module " + modPath + "\n")
+		modDirs = []string{""}
+	}
+	s, err := c.newSandbox(files)
+	if err != nil {
+		return nil, nil, fmt.Errorf("making sandbox: %v", err)
+	}
+	defer s.close()
+
+	var (
+		ps       Problems
+		warnings []string
+	)
+	sort.Strings(modDirs)
+	for _, dir := range modDirs {
+		name := "the top-level module"
+		if dir != "" {
+			name = "the module in " + dir
+		}
+		if err := s.download(ctx, gobin, dir); err != nil {
+			warnings = append(warnings, fmt.Sprintf("downloading the dependencies of %s failed, so it was not checked with full type information: %v", name, err))
+			continue
+		}
+		if c.Runs(Vet) {
+			vps, err := s.vet(ctx, gobin, dir)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("vetting %s failed: %v", name, err))
+			}
+			ps = append(ps, vps...)
+		}
+		if c.Runs(Types) {
+			tps, failed, err := s.typeCheck(ctx, dir)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("type-checking %s failed: %v", name, err))
+			}
+			for _, f := range failed {
+				warnings = append(warnings, fmt.Sprintf("in %s, %s", name, f))
+			}
+			ps = append(ps, tps...)
+		}
+	}
+	return ps, warnings, nil
+}
@@ -0,0 +1,78 @@
+package fixhub
+
+import (
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestSandbox(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "proj", map[string][]byte{
+		"go.mod": []byte("module example.com/proj\n\ngo 1.21\n"),
+		"a.go": []byte(`package proj
+
+import "fmt"
+
+func F() {
+	fmt.Printf("%d\n", "x")
+}
+`),
+		"b/b.go": []byte(`package b
+
+import "example.com/proj"
+
+var N int = proj.F()
+`),
+	})
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	if _, err := c.goBinary(); err != nil {
+		t.Skipf("no go command: %v", err)
+	}
+	c.ScratchDir = t.TempDir()
+	c.Sandbox = true
+	c.Enabled = []ProblemType{Vet, Types}
+
+	res, err := c.Run("master")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, w := range res.Warnings {
+		t.Errorf("warning: %s", w)
+	}
+	got := make(map[ProblemType][]Problem)
+	for _, p := range res.Problems {
+		got[p.Type] = append(got[p.Type], p)
+	}
+	if ps := got[Vet]; len(ps) != 1 || ps[0].File != "a.go" || ps[0].Line != 6 || ps[0].Code != "printf" {
+		t.Errorf("vet problems = %v, want a printf problem at a.go:6", ps)
+	}
+	// Type errors need the other package, which per-file checks can't see.
+	if ps := got[Types]; len(ps) != 1 || ps[0].File != "b/b.go" || ps[0].Line != 5 {
+		t.Errorf("type problems = %v, want one at b/b.go:5", ps)
+	}
+}
+
+func TestSplitPosition(t *testing.T) {
+	tests := []struct {
+		pos       string
+		file      string
+		line, col int
+	}{
+		{"a.go:3:14", "a.go", 3, 14},
+		{"a.go:3", "a.go", 3, 0},
+		{"a.go", "a.go", 0, 0},
+		{"C:/x/a.go:3:14", "C:/x/a.go", 3, 14},
+		{"", "", 0, 0},
+	}
+	for _, tt := range tests {
+		file, line, col := splitPosition(tt.pos)
+		if file != tt.file || line != tt.line || col != tt.col {
+			t.Errorf("splitPosition(%q) = %q, %d, %d; want %q, %d, %d", tt.pos, file, line, col, tt.file, tt.line, tt.col)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package fixhub
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/github"
+)
+
+// StatusContext is the context of the commit statuses set by SetCommitStatus.
+const StatusContext = "fixhub"
+
+// SetCommitStatus sets the "fixhub" commit status of a commit, for teams that
+// use commit statuses rather than checks. If res is nil, the status is pending;
+// otherwise it is a success if res has no problems and a failure if it has some,
+// and its description says how many. The status links to targetURL,
+// such as a fixhubd page of the problems, if it is not empty.
+func (c *Client) SetCommitStatus(sha1 string, res *CheckResult, targetURL string) error {
+	st := &github.RepoStatus{Context: github.String(StatusContext)}
+	switch {
+	case res == nil:
+		st.State, st.Description = github.String("pending"), github.String("Checking for problems")
+	case len(res.Problems) == 0:
+		st.State, st.Description = github.String("success"), github.String("No problems found")
+	case len(res.Problems) == 1:
+		st.State, st.Description = github.String("failure"), github.String("1 problem found")
+	default:
+		st.State, st.Description = github.String("failure"), github.String(fmt.Sprintf("%d problems found", len(res.Problems)))
+	}
+	if targetURL != "" {
+		st.TargetURL = github.String(targetURL)
+	}
+	if _, _, err := c.gc.Repositories.CreateStatus(c.owner, c.repo, sha1, st); err != nil {
+		return fmt.Errorf("setting status of %s: %v", sha1, err)
+	}
+	return nil
+}
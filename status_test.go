@@ -0,0 +1,37 @@
+package fixhub
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestSetCommitStatus(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	sha := srv.AddRepo("faker", "proj", map[string][]byte{"a.go": []byte("package a\n")})
+	c := NewClientWithHTTPClient("faker", "proj", nil)
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	const url = "https://fixhub.example/github.com/faker/proj@" // no SHA, for brevity
+	if err := c.SetCommitStatus(sha, nil, ""); err != nil {
+		t.Fatalf("SetCommitStatus(pending): %v", err)
+	}
+	if err := c.SetCommitStatus(sha, &CheckResult{SHA: sha, Problems: make(Problems, 3)}, url); err != nil {
+		t.Fatalf("SetCommitStatus(failure): %v", err)
+	}
+	if err := c.SetCommitStatus(sha, &CheckResult{SHA: sha}, url); err != nil {
+		t.Fatalf("SetCommitStatus(success): %v", err)
+	}
+	want := []fixhubtest.Status{
+		{State: "pending", Description: "Checking for problems", Context: "fixhub"},
+		{State: "failure", Description: "3 problems found", Context: "fixhub", TargetURL: url},
+		{State: "success", Description: "No problems found", Context: "fixhub", TargetURL: url},
+	}
+	if got := srv.Statuses("faker", "proj", sha); !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses set:\n got %+v\nwant %+v", got, want)
+	}
+}
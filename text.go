@@ -0,0 +1,51 @@
+package fixhub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Format implements fmt.Formatter. The %v and %s verbs write a problem
+// the way golint and vet do, as
+//
+//	file:line: text
+//
+// or file:line:col: text if the column is known,
+// so that editors and other tools that understand their output can understand fixhub's.
+// The + flag (%+v) appends the kind of check and its rule code, if any, in brackets:
+//
+//	p.go:3:6: exported type T should have comment or be unexported [lint/comments]
+func (p Problem) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		if p.Col > 0 {
+			fmt.Fprintf(f, "%s:%d:%d: %s", p.File, p.Line, p.Col, p.Text)
+		} else {
+			fmt.Fprintf(f, "%s:%d: %s", p.File, p.Line, p.Text)
+		}
+		if f.Flag('+') {
+			if p.Code != "" {
+				fmt.Fprintf(f, " [%s/%s]", p.Type, p.Code)
+			} else {
+				fmt.Fprintf(f, " [%s]", p.Type)
+			}
+		}
+	default:
+		fmt.Fprintf(f, "%%!%c(fixhub.Problem=%v)", verb, p)
+	}
+}
+
+// WriteTo writes the problems to w, one per line, in the form described by Problem.Format.
+// It implements io.WriterTo.
+func (ps Problems) WriteTo(w io.Writer) (n int64, err error) {
+	bw := bufio.NewWriter(w)
+	for _, p := range ps {
+		m, err := fmt.Fprintf(bw, "%v\n", p)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
@@ -0,0 +1,52 @@
+package fixhub
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		p          Problem
+		plain, all string
+	}{
+		{
+			Problem{File: "a/b.go", Line: 3, Type: Lint, Code: "comments", Text: "exported type T should have comment or be unexported"},
+			"a/b.go:3: exported type T should have comment or be unexported",
+			"a/b.go:3: exported type T should have comment or be unexported [lint/comments]",
+		},
+		{
+			Problem{File: "d.go", Line: 7, Col: 2, Type: Vet, Text: "unreachable code"},
+			"d.go:7:2: unreachable code",
+			"d.go:7:2: unreachable code [vet]",
+		},
+		{
+			Problem{File: "c.go", Type: Gofmt, Text: "This file needs formatting with gofmt."},
+			"c.go:0: This file needs formatting with gofmt.",
+			"c.go:0: This file needs formatting with gofmt. [gofmt]",
+		},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf("%v", tt.p); got != tt.plain {
+			t.Errorf("%%v gave %q, want %q", got, tt.plain)
+		}
+		if got := tt.p.String(); got != tt.plain {
+			t.Errorf("String() = %q, want %q", got, tt.plain)
+		}
+		if got := fmt.Sprintf("%+v", tt.p); got != tt.all {
+			t.Errorf("%%+v gave %q, want %q", got, tt.all)
+		}
+	}
+
+	var buf bytes.Buffer
+	ps := Problems{tests[0].p, tests[1].p}
+	n, err := ps.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	want := tests[0].plain + "\n" + tests[1].plain + "\n"
+	if buf.String() != want || n != int64(len(want)) {
+		t.Errorf("WriteTo wrote %d bytes %q, want %d bytes %q", n, buf.String(), len(want), want)
+	}
+}
@@ -0,0 +1,70 @@
+package fixhub
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+
+	"code.google.com/p/goauth2/oauth"
+)
+
+// UserAgent is the User-Agent header of fixhub's HTTP requests.
+const UserAgent = "fixhub"
+
+// DefaultTransport is the transport beneath the clients made by NewClient.
+// Sharing it lets connections to GitHub be reused across clients.
+// Programs may replace it before making clients, such as with a transport
+// from NewTransport that goes through a corporate proxy.
+var DefaultTransport http.RoundTripper = NewTransport(nil, nil)
+
+// NewTransport returns a transport suited to talking to GitHub. It keeps
+// connections alive, enough for a client's concurrent fetches, and uses HTTP/2
+// where it can. If proxy is nil, the proxy is taken from the environment
+// ($HTTPS_PROXY and $NO_PROXY); otherwise proxy chooses it for each request.
+// If tlsConfig is non-nil, it configures TLS, such as to trust a private CA.
+func NewTransport(proxy func(*http.Request) (*url.URL, error), tlsConfig *tls.Config) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ForceAttemptHTTP2 = true
+	t.MaxIdleConnsPerHost = 16
+	if proxy != nil {
+		t.Proxy = proxy
+	}
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig
+	}
+	return t
+}
+
+// NewHTTPClient returns an HTTP client that makes requests through base,
+// or DefaultTransport if base is nil, identifying itself as UserAgent.
+// If accessToken is not empty, requests are authenticated with it.
+// Such a client may be given to NewClientWithHTTPClient.
+func NewHTTPClient(base http.RoundTripper, accessToken string) *http.Client {
+	if base == nil {
+		base = DefaultTransport
+	}
+	var rt http.RoundTripper = userAgentTransport{base}
+	if accessToken != "" {
+		rt = &oauth.Transport{
+			Token: &oauth.Token{
+				AccessToken: accessToken,
+			},
+			Transport: rt,
+		}
+	}
+	return &http.Client{Transport: rt}
+}
+
+// userAgentTransport sets the User-Agent header of requests that lack one.
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") != "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", UserAgent)
+	return t.base.RoundTrip(req)
+}
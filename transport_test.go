@@ -0,0 +1,36 @@
+package fixhub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	var ua, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ua, auth = r.UserAgent(), r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		token    string
+		wantAuth string
+	}{
+		{"", ""},
+		{"sekrit", "Bearer sekrit"},
+	}
+	for _, tt := range tests {
+		resp, err := NewHTTPClient(srv.Client().Transport, tt.token).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if ua != UserAgent {
+			t.Errorf("token %q: User-Agent = %q, want %q", tt.token, ua, UserAgent)
+		}
+		if auth != tt.wantAuth {
+			t.Errorf("token %q: Authorization = %q, want %q", tt.token, auth, tt.wantAuth)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package fixhub
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// checkUnused reports exported package-level identifiers that are not
+// referenced anywhere else in the repository. Methods are not considered,
+// since they may be needed to satisfy interfaces.
+//
+// Declarations in main packages and in test files are ignored
+// unless the configuration says otherwise.
+func checkUnused(fset *token.FileSet, pkgs []*repoPackage, cfg *Config) Problems {
+	type decl struct {
+		kind string // "func", "type", "var" or "const"
+		id   *ast.Ident
+		file string
+	}
+	declared := make(map[string]map[string]decl) // import path -> name -> declaration
+	for _, rp := range pkgs {
+		if rp.name == "main" && !cfg.Unused.Main {
+			continue
+		}
+		if strings.HasSuffix(rp.name, "_test") {
+			continue // can't be referenced
+		}
+		m := make(map[string]decl)
+		for filename, f := range rp.files {
+			if strings.HasSuffix(filename, "_test.go") && !cfg.Unused.Tests {
+				continue
+			}
+			add := func(kind string, id *ast.Ident) {
+				if id.IsExported() {
+					m[id.Name] = decl{kind, id, filename}
+				}
+			}
+			for _, d := range f.Decls {
+				switch d := d.(type) {
+				case *ast.FuncDecl:
+					if d.Recv == nil && !(rp.name == "main" && d.Name.Name == "main") {
+						add("func", d.Name)
+					}
+				case *ast.GenDecl:
+					for _, spec := range d.Specs {
+						switch spec := spec.(type) {
+						case *ast.TypeSpec:
+							add("type", spec.Name)
+						case *ast.ValueSpec:
+							for _, name := range spec.Names {
+								add(strings.ToLower(d.Tok.String()), name)
+							}
+						}
+					}
+				}
+			}
+		}
+		if len(m) > 0 {
+			declared[rp.path] = m
+		}
+	}
+
+	used := func(path, name string) {
+		if m := declared[path]; m != nil {
+			delete(m, name)
+		}
+	}
+	pkgNames := make(map[string]string) // import path -> package name
+	for _, rp := range pkgs {
+		if !strings.HasSuffix(rp.name, "_test") {
+			pkgNames[rp.path] = rp.name
+		}
+	}
+	nameOf := func(path string) string { return pkgNames[path] }
+
+	for _, rp := range pkgs {
+		// External test packages refer to the package under test by import path,
+		// which is handled along with all the other qualified references.
+		for _, f := range rp.files {
+			imported := importNames(f, nameOf)
+			ast.Inspect(f, func(n ast.Node) bool {
+				if pkg, name, ok := qualifiedRef(n); ok {
+					if path, ok := imported[pkg.Name]; ok {
+						used(path, name.Name)
+						return false
+					}
+				}
+				// Any other use of the name within the package counts,
+				// as long as it isn't the declaration itself.
+				if id, ok := n.(*ast.Ident); ok {
+					if d, ok := declared[rp.path][id.Name]; ok && d.id != id {
+						used(rp.path, id.Name)
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	var ps Problems
+	for _, m := range declared {
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			d := m[name]
+			ps = append(ps, Problem{
+				File: d.file,
+				Type: Unused,
+				Text: fmt.Sprintf("exported %s %s is not used anywhere in the repository; consider unexporting or removing it", d.kind, name),
+			}.at(fset, d.id.Pos(), d.id.End()))
+		}
+	}
+	return ps
+}
@@ -0,0 +1,57 @@
+package fixhub
+
+import (
+	"go/token"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCheckUnused(t *testing.T) {
+	packages := map[string]map[string][]byte{
+		"lib lib": {
+			"lib/lib.go": []byte(`package lib
+
+func Used() {}
+
+func UsedInPackage() {}
+
+func Unused() { UsedInPackage() }
+
+type T struct{}
+
+func (T) Method() {}
+
+const C, D = 1, 2
+`),
+			"lib/lib_test.go": []byte(`package lib
+
+func Helper() {}
+`),
+		},
+		"cmd/x main": {
+			"cmd/x/main.go": []byte(`package main
+
+import "example.com/repo/lib"
+
+func Exported() {}
+
+func main() { lib.Used(); _ = lib.C; _ = lib.T{} }
+`),
+		},
+	}
+	fset := token.NewFileSet()
+	ps := checkUnused(fset, parsePackages(fset, "example.com/repo", packages), new(Config))
+	var got []string
+	for _, p := range ps {
+		got = append(got, p.Text)
+	}
+	sort.Strings(got)
+	want := []string{
+		"exported const D is not used anywhere in the repository; consider unexporting or removing it",
+		"exported func Unused is not used anywhere in the repository; consider unexporting or removing it",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkUnused problems:\n%q\nwant:\n%q", got, want)
+	}
+}
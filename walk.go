@@ -0,0 +1,77 @@
+package fixhub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A TreeEntry is a file in a repository, as visited by WalkTree.
+type TreeEntry struct {
+	Path string // relative to the top of the repository
+	SHA  string // blob SHA-1 ID, for GetBlob
+	Size int
+}
+
+// A TreeFilter reports whether WalkTree should visit an entry.
+type TreeFilter func(TreeEntry) bool
+
+// GoFiles is a TreeFilter that selects Go source files.
+func GoFiles(ent TreeEntry) bool { return strings.HasSuffix(ent.Path, ".go") }
+
+// NotGenerated is a TreeFilter that rejects files known to be generated,
+// such as protocol buffer code, which is not worth checking.
+func NotGenerated(ent TreeEntry) bool { return !generated(ent.Path) }
+
+// MaxSize returns a TreeFilter that rejects files bigger than n bytes.
+func MaxSize(n int) TreeFilter {
+	return func(ent TreeEntry) bool { return ent.Size <= n }
+}
+
+// generated reports whether the file at path is known to be generated.
+func generated(path string) bool {
+	return strings.HasSuffix(path, ".pb.go")
+}
+
+// WalkTree calls fn for each file in the named revision that passes all the filters,
+// in the order GitHub lists them. Only files under Client.Dir are visited,
+// and submodules and symlinks are never visited. If fn returns an error, WalkTree stops
+// and returns that error.
+//
+// Large trees are walked one directory at a time, as Run does; any parts
+// of the tree that could not be listed are reported to Client.Logger.
+func (c *Client) WalkTree(rev string, fn func(TreeEntry) error, filters ...TreeFilter) error {
+	ref, err := c.ResolveRef(rev)
+	if err != nil {
+		return fmt.Errorf("resolving %q: %v", rev, err)
+	}
+	tree, warnings, err := c.fullTree(ref)
+	if err != nil {
+		return fmt.Errorf("fetching tree %q (%s): %v", rev, ref, err)
+	}
+	for _, w := range warnings {
+		c.warn(w)
+	}
+	dir := strings.Trim(c.Dir, "/")
+entries:
+	for _, ent := range tree.Entries {
+		if ent.SHA == nil || ent.Path == nil || ent.Size == nil || (ent.Type != nil && *ent.Type != "blob") {
+			continue
+		}
+		if ent.Mode != nil && *ent.Mode == symlinkMode {
+			continue
+		}
+		if dir != "" && !strings.HasPrefix(*ent.Path, dir+"/") {
+			continue
+		}
+		te := TreeEntry{Path: *ent.Path, SHA: *ent.SHA, Size: *ent.Size}
+		for _, f := range filters {
+			if !f(te) {
+				continue entries
+			}
+		}
+		if err := fn(te); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package fixhub
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dsymonds/fixhub/fixhubtest"
+)
+
+func TestWalkTree(t *testing.T) {
+	srv := fixhubtest.NewServer()
+	defer srv.Close()
+	srv.AddRepo("faker", "walk", map[string][]byte{
+		"README":         []byte("hello\n"),
+		"a.go":           []byte("package a\n"),
+		"a.pb.go":        []byte("package a\n"),
+		"big/big.go":     []byte("package big\n\n// This file is bigger than the others.\n"),
+		"sub/dir/sub.go": []byte("package dir\n"),
+	})
+	c, err := NewClient("faker", "walk", "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.SetBaseURL(srv.BaseURL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+
+	walk := func(filters ...TreeFilter) []string {
+		var paths []string
+		err := c.WalkTree("master", func(ent TreeEntry) error {
+			paths = append(paths, ent.Path)
+			return nil
+		}, filters...)
+		if err != nil {
+			t.Fatalf("WalkTree: %v", err)
+		}
+		return paths
+	}
+	tests := []struct {
+		dir     string
+		filters []TreeFilter
+		want    []string
+	}{
+		{"", nil, []string{"README", "a.go", "a.pb.go", "big/big.go", "sub/dir/sub.go"}},
+		{"", []TreeFilter{GoFiles, NotGenerated}, []string{"a.go", "big/big.go", "sub/dir/sub.go"}},
+		{"", []TreeFilter{GoFiles, MaxSize(20)}, []string{"a.go", "a.pb.go", "sub/dir/sub.go"}},
+		{"sub", []TreeFilter{GoFiles}, []string{"sub/dir/sub.go"}},
+	}
+	for _, tt := range tests {
+		c.Dir = tt.dir
+		if got := walk(tt.filters...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("walking %q with %d filters visited %q, want %q", tt.dir, len(tt.filters), got, tt.want)
+		}
+	}
+
+	c.Dir = ""
+	stop := errors.New("stop")
+	n := 0
+	err = c.WalkTree("master", func(TreeEntry) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("WalkTree with failing callback = %v after %d calls, want %v after 1", err, n, stop)
+	}
+}